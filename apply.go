@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// applyCommand copies the rows of a plan written by devseeder plan -out into dev.
+// It reads no FKs and follows none: only the planned rows are read, by ID, from
+// prod or the configured source, so it needs no wider access than that. The
+// tables of the config are ignored in favour of the plan's.
+// Usage: devseeder apply [flags] plan.json
+func applyCommand(args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	progress := fs.String("progress", "auto", "live per-table progress bars on stderr: auto (when stderr is a terminal), on, off, or tui for a full-screen dashboard")
+	result := fs.String("result", "", "write a JSON result document (status, per-table counts, warnings, duration, exit code) to this file when the run ends (- for stdout)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fatalf(exitConfig, "Usage: devseeder apply [flags] plan.json\n")
+	}
+	ui, err := progressMode(*progress)
+	if err != nil {
+		fatalf(exitConfig, "%v\n", err)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fatalf(exitConfig, "Error opening plan: %v\n", err)
+	}
+	pf, err := devseeder.ReadPlanFile(f)
+	f.Close()
+	if err != nil {
+		fatalf(exitConfig, "Error: %v\n", err)
+	}
+
+	cfg := flags.load()
+	cfg.resultPath = *result
+	confirmTarget(cfg)
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+	log.Printf("Auditing executed statements to %s", audit.Path())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing := startTracing(ctx, cfg)
+	defer shutdownTracing()
+
+	closeSource := openSource(ctx, cfg, audit)
+	defer closeSource()
+
+	if err := ensureDevDatabase(ctx, cfg, audit); err != nil {
+		fatalf(exitCode(err), "Error: %v\n", err)
+	}
+
+	if cfg.LogFormat == LogFormatJSON {
+		ui = progressOff
+	}
+	if err := runSync(ctx, cfg, audit, ui, devseeder.WithPlanFile(pf)); err != nil {
+		shutdownTracing()
+		exitOnSyncError(ctx, err)
+	}
+}
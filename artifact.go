@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// manifestSuffix is appended to an artifact's path to name its manifest
+const manifestSuffix = ".manifest.json"
+
+// artifactManifest is written next to an SQL dump artifact, so whoever loads it
+// with devseeder load can check it is the file the dump produced, unaltered,
+// and which anonymize rules its data went through.
+type artifactManifest struct {
+	Format            string         `json:"format"`
+	SHA256            string         `json:"sha256"` // of the artifact as written (compressed, encrypted)
+	Size              int64          `json:"size"`
+	CreatedAt         time.Time      `json:"created_at"`
+	Profile           string         `json:"profile,omitempty"`
+	AnonymizationHash string         `json:"anonymization_hash"` // see anonymizationHash
+	Tables            map[string]int `json:"tables"`             // rows dumped per table
+}
+
+// fileDigest returns the hex SHA-256 and size of the file at path
+func fileDigest(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	sum := sha256.New()
+	n, err := io.Copy(sum, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), n, nil
+}
+
+// writeArtifactManifest writes the manifest of the artifact at path
+func writeArtifactManifest(cfg *Config, path, format string, plan *devseeder.CopyPlan) error {
+	digest, size, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+	m := artifactManifest{Format: format, SHA256: digest, Size: size, CreatedAt: time.Now().UTC(),
+		Profile: cfg.profile, AnonymizationHash: anonymizationHash(cfg.Anonymize), Tables: make(map[string]int)}
+	for _, table := range plan.Order {
+		m.Tables[table] = plan.Rows(table)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+manifestSuffix, append(data, '\n'), 0o644)
+}
+
+// verifyArtifact checks the artifact at path against its manifest
+func verifyArtifact(path string) (artifactManifest, error) {
+	var m artifactManifest
+	data, err := os.ReadFile(path + manifestSuffix)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("reading %s%s: %w", path, manifestSuffix, err)
+	}
+	digest, size, err := fileDigest(path)
+	if err != nil {
+		return m, err
+	}
+	if size != m.Size || digest != m.SHA256 {
+		return m, fmt.Errorf("%s does not match its manifest (sha256 %s, %d bytes; expected %s, %d bytes): it is incomplete or was altered",
+			path, digest, size, m.SHA256, m.Size)
+	}
+	return m, nil
+}
+
+// loadCommand applies an SQL dump artifact made by devseeder dump to dev. It never
+// connects to prod, so developers can seed from an artifact a privileged job
+// produced without holding prod credentials at all.
+// Usage: devseeder load [flags] [-skip-verify] seed.sql.zst
+func loadCommand(args []string) {
+	fs := flag.NewFlagSet("load", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	skipVerify := fs.Bool("skip-verify", false, "load the artifact even without a manifest to verify it against")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fatalf(exitConfig, "Usage: devseeder load [flags] artifact\n")
+	}
+	path := fs.Arg(0)
+
+	m, err := verifyArtifact(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && *skipVerify:
+		log.Printf("Warning: %s has no manifest; loading it unverified", path)
+	case errors.Is(err, os.ErrNotExist):
+		fatalf(exitConfig, "Error: %s has no %s manifest to verify it against; pass -skip-verify to load it anyway\n", path, manifestSuffix)
+	case err != nil:
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+
+	cfg := flags.load()
+	if m.SHA256 != "" {
+		switch m.Format {
+		case "sql", "mysqldump":
+		default:
+			fatalf(exitConfig, "Error: %s is a %s artifact; only sql and mysqldump dumps can be loaded\n", path, m.Format)
+		}
+		if m.AnonymizationHash != anonymizationHash(cfg.Anonymize) {
+			log.Printf("Warning: %s was anonymized with different rules than the current config", path)
+		}
+	}
+	confirmTarget(cfg)
+
+	identities, err := cfg.Encrypt.ageIdentities()
+	if err != nil {
+		fatalf(exitConfig, "Error: %v\n", err)
+	}
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	in, err := devseeder.OpenInput(path, identities)
+	if err != nil {
+		fatalf(exitFailure, "Error opening artifact: %v\n", err)
+	}
+	defer in.Close()
+
+	devDB, err := OpenDevDatabase(cfg)
+	if err != nil {
+		fatalf(exitConnection, "Error opening dev database: %v\n", err)
+	}
+	defer devDB.Close()
+
+	seeder := devseeder.New(nil, devDB, devseeder.WithAuditLog(audit), devseeder.WithLockTimeout(cfg.LockTimeout),
+		devseeder.WithLogger(engineLogger(cfg)))
+	count, err := seeder.LoadScript(ctx, in)
+	if err != nil {
+		fatalf(exitFailure, "Error loading %s: %v\n", path, err)
+	}
+	log.Printf("Loaded %d statements from %s", count, path)
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+	"github.com/segmentio/kafka-go"
+)
+
+// cdcIdleTimeout is how long a catch-up waits for another event before deciding
+// it has reached the end of the stream
+const cdcIdleTimeout = 10 * time.Second
+
+// CDCSourceConfig reads prod's data from a Debezium change stream in Kafka instead
+// of connecting to prod.
+type CDCSourceConfig struct {
+	Brokers []string `yaml:"brokers"`
+	// Debezium's topic.prefix; topics are named <topic_prefix>.<database>.<table>
+	TopicPrefix string `yaml:"topic_prefix"`
+	// Name of the captured prod database, as it appears in the topic names
+	Database string `yaml:"database"`
+	// Consumer group whose committed offsets match the scratch schema's contents
+	// (default devseeder-<scratch schema>)
+	GroupID string `yaml:"group_id"`
+	// Schema on the dev server holding the reconstructed tables (default devseeder_cdc).
+	// It is kept between runs, so each run only applies the events since the last.
+	ScratchDB string `yaml:"scratch_db"`
+	// Keep only rows whose tenant_column holds one of tenants (tables without the
+	// column are kept whole)
+	TenantColumn string   `yaml:"tenant_column"`
+	Tenants      []string `yaml:"tenants"`
+	// Seconds without a new event after which the stream is considered caught up
+	IdleTimeout int `yaml:"idle_timeout"`
+}
+
+func (c CDCSourceConfig) enabled() bool { return len(c.Brokers) > 0 }
+
+func (c CDCSourceConfig) scratchDB() string { return firstNonEmpty(c.ScratchDB, "devseeder_cdc") }
+
+// PrepareCDCSource makes sure the scratch schema for the change stream exists on
+// the dev server, with every dev table created from dev's own DDL (the stream
+// carries no usable DDL, and dev's schema matches prod's), and applies the
+// events published since the last run. It returns the DSN of the scratch
+// schema, which then stands in for prod.
+func PrepareCDCSource(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) (string, error) {
+	c := cfg.SourceCDC
+	if c.TopicPrefix == "" || c.Database == "" {
+		return "", fmt.Errorf("source_cdc needs topic_prefix and database")
+	}
+	dsnCfg, err := mysql.ParseDSN(cfg.DevDSN)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse dev DSN: %w", err)
+	}
+	scratch := c.scratchDB()
+	if scratch == dsnCfg.DBName {
+		return "", fmt.Errorf("source_cdc.scratch_db must differ from the dev database")
+	}
+
+	devDB, err := openAuditedDB("dev", cfg.DevDSN, cfg.DevTLS, cfg.forceUTF8MB4(), audit)
+	if err != nil {
+		return "", err
+	}
+	defer devDB.Close()
+	tables, err := listBaseTables(ctx, devDB)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := devDB.ExecContext(ctx, "CREATE DATABASE IF NOT EXISTS "+devseeder.QuoteIdent(scratch)+" CHARACTER SET utf8mb4"); err != nil {
+		return "", fmt.Errorf("cannot create scratch schema %s: %w", scratch, err)
+	}
+	dsnCfg.DBName = scratch
+	scratchDSN := dsnCfg.FormatDSN()
+	db, err := openAuditedDB("source", scratchDSN, cfg.DevTLS, cfg.forceUTF8MB4(), audit)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	// Tables reference each other, and events arrive in any table order
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return "", err
+	}
+
+	existing, err := listBaseTables(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tables {
+		if slices.Contains(existing, t) {
+			continue
+		}
+		var name, ddl string
+		if err := devDB.QueryRowContext(ctx, "SHOW CREATE TABLE "+devseeder.QuoteIdent(t)).Scan(&name, &ddl); err != nil {
+			return "", fmt.Errorf("SHOW CREATE TABLE %s: %w", t, err)
+		}
+		if _, err := db.ExecContext(ctx, ddl); err != nil {
+			return "", fmt.Errorf("creating %s in scratch schema: %w", t, err)
+		}
+	}
+
+	if err := catchUpCDC(ctx, c, db, tables); err != nil {
+		return "", err
+	}
+	return scratchDSN, nil
+}
+
+// listBaseTables returns the base tables of db's default schema
+func listBaseTables(ctx context.Context, db *devseeder.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// catchUpCDC applies the change events of the given tables to db until none has
+// arrived for the idle timeout, committing the consumer group's offsets as it goes
+func catchUpCDC(ctx context.Context, c CDCSourceConfig, db *devseeder.DB, tables []string) error {
+	topics := make([]string, len(tables))
+	byTopic := make(map[string]string, len(tables))
+	for i, t := range tables {
+		topics[i] = c.TopicPrefix + "." + c.Database + "." + t
+		byTopic[topics[i]] = t
+	}
+	idle := cdcIdleTimeout
+	if c.IdleTimeout > 0 {
+		idle = time.Duration(c.IdleTimeout) * time.Second
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     c.Brokers,
+		GroupID:     firstNonEmpty(c.GroupID, "devseeder-"+c.scratchDB()),
+		GroupTopics: topics,
+		StartOffset: kafka.FirstOffset,
+	})
+	defer reader.Close()
+
+	log.Printf("Applying change events from %d topics (%s.%s.*) to scratch schema %s",
+		len(topics), c.TopicPrefix, c.Database, c.scratchDB())
+	applied, skipped := 0, 0
+	columns := make(map[string][]string)
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, idle)
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading change events: %w", err)
+		}
+
+		table := byTopic[msg.Topic]
+		if columns[table] == nil {
+			if columns[table], err = tableColumnNames(ctx, db, table); err != nil {
+				return err
+			}
+		}
+		ok, err := applyChangeEvent(ctx, db, c, table, columns[table], msg.Value)
+		if err != nil {
+			return fmt.Errorf("%s partition %d offset %d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+		}
+		if ok {
+			applied++
+		} else {
+			skipped++
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil {
+			return fmt.Errorf("committing offsets: %w", err)
+		}
+	}
+	log.Printf("Change stream caught up: applied %d events, skipped %d (other tenants or tombstones)", applied, skipped)
+	return nil
+}
+
+func tableColumnNames(ctx context.Context, db *devseeder.DB, table string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT column_name FROM information_schema.columns
+	WHERE table_schema = DATABASE() AND table_name = ? AND generation_expression = '' ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// debeziumField describes one field of a Debezium row struct, as sent with
+// schemas.enable=true
+type debeziumField struct {
+	Field      string            `json:"field"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Parameters map[string]string `json:"parameters"`
+	Fields     []debeziumField   `json:"fields"`
+}
+
+// debeziumEvent is a change event, with or without its schema envelope
+type debeziumEvent struct {
+	Schema *struct {
+		Fields []debeziumField `json:"fields"`
+	} `json:"schema"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type debeziumPayload struct {
+	Op     string                 `json:"op"`
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+}
+
+// applyChangeEvent upserts or deletes the row of one Debezium event in table. It
+// reports false for events that were skipped: tombstones and other tenants' rows.
+func applyChangeEvent(ctx context.Context, db *devseeder.DB, c CDCSourceConfig, table string, columns []string, value []byte) (bool, error) {
+	if len(value) == 0 {
+		return false, nil // tombstone following a delete
+	}
+	var ev debeziumEvent
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+	if err := dec.Decode(&ev); err != nil {
+		return false, fmt.Errorf("decoding event: %w", err)
+	}
+	payload := []byte(ev.Payload)
+	if ev.Schema == nil && ev.Payload == nil {
+		payload = value // schemas.enable=false: the event is the payload
+	}
+	var p debeziumPayload
+	dec = json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&p); err != nil {
+		return false, fmt.Errorf("decoding event payload: %w", err)
+	}
+
+	// The row struct's fields, to decode logical types (dates, decimals, bytes)
+	fields := make(map[string]debeziumField)
+	if ev.Schema != nil {
+		for _, f := range ev.Schema.Fields {
+			if f.Field == "after" || f.Field == "before" {
+				for _, col := range f.Fields {
+					fields[col.Field] = col
+				}
+			}
+		}
+	}
+
+	switch p.Op {
+	case "d":
+		found, err := devseeder.DeleteRow(ctx, db, table, p.Before, func(col string, v interface{}) interface{} {
+			return debeziumValue(v, fields[col])
+		})
+		if err == nil && !found {
+			err = fmt.Errorf("delete event without the row's id")
+		}
+		return err == nil, err
+	case "c", "u", "r":
+	default:
+		return false, nil // e.g. truncate or message events
+	}
+	if !tenantAllowed(c, p.After) {
+		// The row may have moved to another tenant: drop it
+		_, err := devseeder.DeleteRow(ctx, db, table, p.After, func(col string, v interface{}) interface{} {
+			return debeziumValue(v, fields[col])
+		})
+		return false, err
+	}
+
+	var cols, updates []string
+	var args []interface{}
+	for _, col := range columns {
+		v, ok := p.After[col]
+		if !ok {
+			continue
+		}
+		cols = append(cols, devseeder.QuoteIdent(col))
+		updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", devseeder.QuoteIdent(col), devseeder.QuoteIdent(col)))
+		args = append(args, debeziumValue(v, fields[col]))
+	}
+	if len(cols) == 0 {
+		return false, nil
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+		devseeder.QuoteIdent(table), strings.Join(cols, ","), strings.TrimSuffix(strings.Repeat("?,", len(cols)), ","),
+		strings.Join(updates, ", ")), args...)
+	return err == nil, err
+}
+
+// tenantAllowed reports whether row belongs to one of the configured tenants
+func tenantAllowed(c CDCSourceConfig, row map[string]interface{}) bool {
+	if c.TenantColumn == "" || len(c.Tenants) == 0 {
+		return true
+	}
+	v, ok := row[c.TenantColumn]
+	if !ok {
+		return true
+	}
+	return v != nil && slices.Contains(c.Tenants, fmt.Sprint(v))
+}
+
+// debeziumValue converts a JSON value of a Debezium row into what the driver
+// sends, decoding the logical types Debezium's MySQL connector emits
+func debeziumValue(v interface{}, f debeziumField) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case json.Number:
+		n, err := val.Int64()
+		if err != nil {
+			return val.String()
+		}
+		switch f.Name {
+		case "io.debezium.time.Date", "org.apache.kafka.connect.data.Date":
+			return time.Unix(n*86400, 0).UTC().Format(time.DateOnly)
+		case "io.debezium.time.Timestamp", "org.apache.kafka.connect.data.Timestamp":
+			return time.UnixMilli(n).UTC()
+		case "io.debezium.time.MicroTimestamp":
+			return time.UnixMicro(n).UTC()
+		case "io.debezium.time.NanoTimestamp":
+			return time.Unix(0, n).UTC()
+		case "io.debezium.time.MicroTime":
+			return formatClock(time.Duration(n) * time.Microsecond)
+		case "io.debezium.time.Time", "org.apache.kafka.connect.data.Time":
+			return formatClock(time.Duration(n) * time.Millisecond)
+		}
+		return n
+	case string:
+		switch {
+		case f.Name == "io.debezium.time.ZonedTimestamp":
+			if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+				return t.UTC()
+			}
+		case f.Name == "org.apache.kafka.connect.data.Decimal":
+			if b, err := base64.StdEncoding.DecodeString(val); err == nil {
+				return decodeDecimal(b, f.Parameters["scale"])
+			}
+		case f.Type == "bytes":
+			if b, err := base64.StdEncoding.DecodeString(val); err == nil {
+				return b
+			}
+		}
+		return val
+	case bool:
+		return val
+	default:
+		// structs such as geometry are kept as their JSON text
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
+
+// decodeDecimal renders a Kafka Connect Decimal (big-endian two's complement
+// unscaled value) as exact decimal text
+func decodeDecimal(b []byte, scale string) string {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b))*8))
+	}
+	var s int
+	fmt.Sscan(scale, &s)
+	digits := new(big.Int).Abs(n).String()
+	if s > 0 {
+		if len(digits) <= s {
+			digits = strings.Repeat("0", s-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-s] + "." + digits[len(digits)-s:]
+	}
+	if n.Sign() < 0 {
+		digits = "-" + digits
+	}
+	return digits
+}
+
+// formatClock renders a TIME value
+func formatClock(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	us := (d % time.Second) / time.Microsecond
+	return fmt.Sprintf("%s%02d:%02d:%02d.%06d", sign, h, m, s, us)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"cloud.google.com/go/cloudsqlconn"
+	"github.com/go-sql-driver/mysql"
+)
+
+// cloudSQLNet is the network name registered with the mysql driver for
+// connections dialed through the Cloud SQL Go connector.
+const cloudSQLNet = "cloudsql"
+
+// CloudSQLConfig describes a Cloud SQL instance reached through the Go connector
+// instead of a plain TCP address.
+type CloudSQLConfig struct {
+	// Instance connection name, e.g. "my-project:europe-west1:prod-db"
+	Instance string `yaml:"instance"`
+	// Use IAM database authentication instead of a password
+	IAMAuth bool `yaml:"iam_auth"`
+	// Connect over the instance's private IP instead of its public one
+	PrivateIP bool `yaml:"private_ip"`
+	// Service account key file; Application Default Credentials are used when empty
+	CredentialsFile string `yaml:"credentials_file"`
+}
+
+// buildCloudSQLDSN builds a DSN that connects through the Cloud SQL connector.
+func buildCloudSQLDSN(user, pass, instance, dbName string) string {
+	c := mysql.NewConfig()
+	c.User = user
+	c.Passwd = pass
+	c.Net = cloudSQLNet
+	c.Addr = instance
+	c.DBName = dbName
+	return c.FormatDSN()
+}
+
+// applyCloudSQL starts a Cloud SQL dialer, registers it with the mysql driver and
+// returns the DSN rewritten to connect through it. The returned func closes the dialer.
+// When no instance is configured the DSN is returned unchanged.
+func applyCloudSQL(dsn string, c CloudSQLConfig) (string, func() error, error) {
+	noop := func() error { return nil }
+	if c.Instance == "" {
+		return dsn, noop, nil
+	}
+
+	var opts []cloudsqlconn.Option
+	if c.IAMAuth {
+		opts = append(opts, cloudsqlconn.WithIAMAuthN())
+	}
+	if c.CredentialsFile != "" {
+		opts = append(opts, cloudsqlconn.WithCredentialsFile(c.CredentialsFile))
+	}
+	if c.PrivateIP {
+		opts = append(opts, cloudsqlconn.WithDefaultDialOptions(cloudsqlconn.WithPrivateIP()))
+	}
+
+	dsnCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", noop, fmt.Errorf("cannot parse DSN: %w", err)
+	}
+	// The connector already encrypts the connection
+	if dsnCfg.TLSConfig != "" && dsnCfg.TLSConfig != "false" {
+		return "", noop, fmt.Errorf("TLS options cannot be combined with the Cloud SQL connector")
+	}
+
+	dialer, err := cloudsqlconn.NewDialer(context.Background(), opts...)
+	if err != nil {
+		return "", noop, fmt.Errorf("cannot create Cloud SQL dialer: %w", err)
+	}
+	mysql.RegisterDialContext(cloudSQLNet, func(ctx context.Context, addr string) (net.Conn, error) {
+		return dialer.Dial(ctx, addr)
+	})
+
+	dsnCfg.Net = cloudSQLNet
+	dsnCfg.Addr = c.Instance
+	return dsnCfg.FormatDSN(), dialer.Close, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// composeReadyTimeout is how long a compose service that is still starting gets
+// to accept connections
+const composeReadyTimeout = time.Minute
+
+// ComposeConfig selects the Docker Compose project of -target compose:<service>.
+type ComposeConfig struct {
+	// Compose file(s), as with docker compose -f; the usual lookup when empty
+	Files []string `yaml:"files"`
+	// Project name, as with docker compose -p; derived by compose when empty
+	Project string `yaml:"project"`
+	// Port the MySQL server listens on inside the container (default 3306)
+	Port int `yaml:"port"`
+}
+
+// composeArgs returns the docker arguments running a compose subcommand
+func (c ComposeConfig) composeArgs(sub ...string) []string {
+	args := []string{"compose"}
+	for _, f := range c.Files {
+		args = append(args, "-f", f)
+	}
+	if c.Project != "" {
+		args = append(args, "-p", c.Project)
+	}
+	return append(args, sub...)
+}
+
+// ResolveComposeService returns a DSN for the MySQL server of a running compose
+// service: the host address its port is published on, and the credentials and
+// database the official mysql/mariadb images are configured with in the
+// service environment. A MYSQL_USER is preferred over root. fallbackDB is used
+// when the environment names no database.
+func ResolveComposeService(ctx context.Context, c ComposeConfig, service, fallbackDB string) (string, error) {
+	out, err := exec.CommandContext(ctx, "docker", c.composeArgs("ps", "-q", service)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("docker compose ps failed: %w", commandError(err))
+	}
+	container := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	if container == "" {
+		return "", fmt.Errorf("compose service %s is not running (start it with docker compose up -d %s)", service, service)
+	}
+
+	port := c.Port
+	if port == 0 {
+		port = 3306
+	}
+	out, err = exec.CommandContext(ctx, "docker", c.composeArgs("port", service, fmt.Sprint(port))...).Output()
+	if err != nil {
+		return "", fmt.Errorf("port %d of compose service %s is not published: %w", port, service, commandError(err))
+	}
+	host, hostPort, err := net.SplitHostPort(strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]))
+	if err != nil {
+		return "", fmt.Errorf("unexpected docker compose port output %q", out)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	out, err = exec.CommandContext(ctx, "docker", "inspect", "--format", "{{json .Config.Env}}", container).Output()
+	if err != nil {
+		return "", fmt.Errorf("docker inspect failed: %w", commandError(err))
+	}
+	var envList []string
+	if err := json.Unmarshal(out, &envList); err != nil {
+		return "", fmt.Errorf("reading the environment of %s: %w", service, err)
+	}
+	env := make(map[string]string, len(envList))
+	for _, kv := range envList {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	lookup := func(name string) string {
+		return firstNonEmpty(env["MYSQL_"+name], env["MARIADB_"+name])
+	}
+
+	dsnCfg := mysql.NewConfig()
+	dsnCfg.Net = "tcp"
+	dsnCfg.Addr = net.JoinHostPort(host, hostPort)
+	dsnCfg.DBName = firstNonEmpty(lookup("DATABASE"), fallbackDB)
+	switch {
+	case lookup("USER") != "" && lookup("PASSWORD") != "":
+		dsnCfg.User, dsnCfg.Passwd = lookup("USER"), lookup("PASSWORD")
+	case lookup("ROOT_PASSWORD") != "":
+		dsnCfg.User, dsnCfg.Passwd = "root", lookup("ROOT_PASSWORD")
+	case lookup("ALLOW_EMPTY_PASSWORD") != "" || lookup("ALLOW_EMPTY_ROOT_PASSWORD") != "":
+		dsnCfg.User = "root"
+	case lookup("ROOT_PASSWORD_FILE") != "" || lookup("PASSWORD_FILE") != "":
+		return "", fmt.Errorf("compose service %s reads its password from a secret file; set dev_dsn instead", service)
+	default:
+		return "", fmt.Errorf("no MySQL credentials in the environment of compose service %s", service)
+	}
+	if dsnCfg.DBName == "" {
+		return "", fmt.Errorf("compose service %s sets no MYSQL_DATABASE and no database to fall back on", service)
+	}
+
+	dsn := dsnCfg.FormatDSN()
+	log.Printf("Seeding compose service %s as %s@%s/%s", service, dsnCfg.User, dsnCfg.Addr, dsnCfg.DBName)
+	if err := waitForMySQL(ctx, dsn, composeReadyTimeout); err != nil {
+		return "", fmt.Errorf("compose service %s does not accept connections: %w", service, err)
+	}
+	return dsn, nil
+}
+
+// commandError adds the stderr of a failed command to its error
+func commandError(err error) error {
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(ee.Stderr)))
+	}
+	return err
+}
+
+// composeFallbackDatabase is the database seeded when the compose service does
+// not name one: dev_dsn's, then prod's
+func composeFallbackDatabase(cfg *Config) string {
+	for _, dsn := range []string{cfg.DevDSN, cfg.ProdDSN} {
+		if c, err := mysql.ParseDSN(dsn); err == nil && c.DBName != "" {
+			return c.DBName
+		}
+	}
+	return ""
+}
@@ -4,22 +4,211 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration loaded from config.yaml
 type Config struct {
-	ProdDSN         string         `yaml:"prod_dsn"`
-	DevDSN          string         `yaml:"dev_dsn"`
-	Tables          map[string]int `yaml:"tables"`
-	RootTable       string         `yaml:"root_table"`
-	RootLimit       int            `yaml:"root_limit"`
-	DisableFKChecks bool           `yaml:"disable_fk_checks"`
-	ResetTables     bool           `yaml:"reset_tables"`
+	ProdDSN      string         `yaml:"prod_dsn"`
+	DevDSN       string         `yaml:"dev_dsn"`
+	ProdTLS      TLSConfig      `yaml:"prod_tls"`
+	DevTLS       TLSConfig      `yaml:"dev_tls"`
+	ProdCloudSQL CloudSQLConfig `yaml:"prod_cloudsql"`
+
+	// Read from a mysqldump file instead of prod; it is loaded into a scratch schema on the dev server
+	SourceDump      string `yaml:"source_dump"`
+	SourceScratchDB string `yaml:"source_scratch_db"`
+
+	// Or rebuild prod's tables from a Debezium change stream in Kafka
+	SourceCDC CDCSourceConfig `yaml:"source_cdc"`
+
+	// Optionally pull DSN passwords from Vault at runtime
+	Vault             VaultConfig    `yaml:"vault"`
+	ProdPasswordVault VaultSecretRef `yaml:"prod_password_vault"`
+	DevPasswordVault  VaultSecretRef `yaml:"dev_password_vault"`
+
+	// OS keychain accounts holding the DSN passwords (set when saving a profile)
+	ProdPasswordKeychain string `yaml:"prod_password_keychain,omitempty"`
+	DevPasswordKeychain  string `yaml:"dev_password_keychain,omitempty"`
+
+	Tables          tableLimits `yaml:"tables"`
+	RootTable       string      `yaml:"root_table"`
+	RootLimit       int         `yaml:"root_limit"`
+	DisableFKChecks bool        `yaml:"disable_fk_checks"`
+	FKChecks        string      `yaml:"fk_checks"`
+	ResetTables     bool        `yaml:"reset_tables"`
+	BackupTables    bool        `yaml:"backup_tables"`
+	SkipPreflight   bool        `yaml:"skip_preflight"`
+
+	// Copy tables with at most this many rows in full when planned rows reference
+	// them (0 = off)
+	ReferenceTableRows int `yaml:"reference_table_rows"`
+
+	// Which rows of a table are seeded: an SQL ORDER BY list per table, e.g.
+	// "priority DESC, created_at DESC" (default: the lowest IDs)
+	OrderBy map[string]string `yaml:"order_by"`
+
+	// Fail the sync before writing anything if the plan holds more rows (0 = no limit)
+	MaxRows int `yaml:"max_rows"`
+
+	// Most parent IDs one FK may pull into the subset: { childTable : { fkColumn : max } }
+	EdgeLimits map[string]map[string]int `yaml:"edge_limits"`
+
+	// Named subsets that -job selects; without -job every job runs along with Tables
+	Jobs map[string]JobConfig `yaml:"jobs"`
+
+	// Keep FK and column metadata in the user cache directory between runs, keyed
+	// by a hash of the schema so changes are picked up automatically
+	MetadataCache bool `yaml:"metadata_cache"`
+
+	// Reuse the computed closure between runs with the same parameters
+	PlanCache PlanCacheConfig `yaml:"plan_cache"`
+
+	// Further schemas on the prod server to traverse, with the FKs between them;
+	// their tables are named schema.table
+	Schemas []string `yaml:"schemas"`
+
+	// Create prod tables missing from dev (from prod's DDL) before seeding
+	CreateMissingTables bool `yaml:"create_missing_tables"`
+
+	// Create the dev database without asking when the dev server does not have
+	// it yet (see ensureDevDatabase)
+	CreateDatabase bool `yaml:"create_database"`
+
+	// Disposable container settings for `devseeder sync -target docker`
+	Docker DockerConfig `yaml:"docker"`
+
+	// Compose project of `devseeder sync -target compose:<service>`
+	Compose ComposeConfig `yaml:"compose"`
+
+	// Per-developer database settings for `devseeder sync -target personal`
+	Personal PersonalConfig `yaml:"personal"`
+
+	// Run against dev before seeding: a command and/or a directory of SQL files
+	Migrate MigrateConfig `yaml:"migrate"`
+
+	// Dev tables named differently from prod's: { prodTable : devTable }
+	TableMap map[string]string `yaml:"table_map"`
+	// Dev columns named differently from prod's: { prodTable : { prodColumn : devColumn } }
+	ColumnMap map[string]map[string]string `yaml:"column_map"`
+	// SQL expressions written instead of prod's values, or for columns prod lacks:
+	// { prodTable : { devColumn : expression } }
+	ColumnValues map[string]map[string]string `yaml:"column_values"`
+
+	// Restrict seed selection on partitioned prod tables: { table : [partitions] }
+	Partitions map[string][]string `yaml:"partitions"`
+
+	// Insert only columns present in both prod and dev, relying on dev defaults for the rest
+	ColumnIntersection bool `yaml:"column_intersection"`
+
+	// Recreate prod views in dev after the data copy
+	CopyViews bool `yaml:"copy_views"`
+
+	// Drop dev triggers on copied tables during the load and recreate them afterwards
+	DisableTriggers bool `yaml:"disable_triggers"`
+	// Create prod triggers / stored procedures and functions missing from dev
+	CopyTriggers bool `yaml:"copy_triggers"`
+	CopyRoutines bool `yaml:"copy_routines"`
+
+	// Seconds to wait for another run on the same dev database to finish (0 = refuse)
+	LockTimeout int `yaml:"lock_timeout"`
+
+	// Re-open a dropped prod or dev connection up to this many times in a row and
+	// resume where it stopped (0 = fail the run)
+	ReconnectAttempts int `yaml:"reconnect_attempts"`
+
+	// Always talk utf8mb4 on both connections, whatever the DSNs ask for (default true)
+	ForceUTF8MB4 *bool `yaml:"force_utf8mb4"`
+
+	// After copying, delete dev rows of the copied tables that were deleted in prod
+	Prune bool `yaml:"prune"`
+
+	// Duplicate the seeded rows into a larger load-test dataset
+	Multiply MultiplyConfig `yaml:"multiply"`
+
+	// How rows are written to dev: insert (default; existing rows fail the sync),
+	// ignore (keep the rows dev has, e.g. local changes, and only add missing ones)
+	// or append (only add rows with IDs outside the range each dev table uses)
+	WriteStrategy string `yaml:"write_strategy"`
+
+	// Per table, what to do with planned rows whose id dev already has: skip,
+	// overwrite, remap or fail (other tables follow write_strategy)
+	Conflicts map[string]string `yaml:"conflicts"`
+
+	// How to write prod's 0000-00-00 dates: "" (as is), relax, null or sentinel
+	ZeroDates string `yaml:"zero_dates"`
+	// The date written in place of zero dates with zero_dates: sentinel
+	ZeroDateSentinel string `yaml:"zero_date_sentinel"`
+
+	// Encrypt dump artifacts with age before they leave this machine
+	Encrypt EncryptConfig `yaml:"encrypt"`
+
+	// Upload dump artifacts to object storage (s3:// or gs://) after a dump run
+	Upload UploadConfig `yaml:"upload"`
+
+	// POST a JSON report (status, per-table counts, duration, error) when a sync finishes
+	Webhook WebhookConfig `yaml:"webhook"`
+
+	// Export OpenTelemetry spans of each run (FK discovery, BFS, per-table fetch/insert)
+	Tracing TracingConfig `yaml:"tracing"`
+
+	// Where devseeder dataset keeps tagged dataset versions, e.g. a shared drive
+	// (default: the user config directory)
+	DatasetsDir string `yaml:"datasets_dir"`
+
+	// Directory of checked-in seed files (<table>.yml fixtures, then *.sql) applied
+	// to dev after the prod subset
+	FixturesDir string `yaml:"fixtures_dir"`
+
+	// Checks run on dev after the copy
+	Verify VerifyConfig `yaml:"verify"`
+
+	// Statements run on dev around the whole sync and each table, and commands
+	// run before and after each run
+	Hooks HooksConfig `yaml:"hooks"`
+
+	// Log output: text (default) or json, one structured event per line
+	LogFormat string `yaml:"log_format"`
+
+	// Replication settings of `devseeder sync -follow`
+	Binlog BinlogConfig `yaml:"binlog"`
+
+	// Refresh targets on a schedule with devseeder daemon
+	Daemon DaemonConfig `yaml:"daemon"`
+
+	// TLS and client credentials of `devseeder serve`
+	Serve ServeConfig `yaml:"serve"`
+
+	// Also log statements taking at least this many milliseconds, with their table,
+	// to a devseeder-slow-<time>.log file in AuditDir (0 = off)
+	SlowQueryMs int `yaml:"slow_query_ms"`
+
+	// Directory where the per-run audit log of executed statements is written
+	AuditDir string `yaml:"audit_dir"`
+
+	// Directory for the undo script deleting every inserted row; no script when empty
+	UndoDir string `yaml:"undo_dir"`
+
+	// Record every inserted row in the _devseeder_state table on dev, for devseeder clean
+	TrackState bool `yaml:"track_state"`
 
 	// Optionally define anonymization rules, logs, etc.
 	Anonymize map[string]string `yaml:"anonymize"`
+
+	// Transform policies compiled to WebAssembly, applied in order to every row
+	// copied, exported or followed
+	WASMPolicies []WASMPolicyConfig `yaml:"wasm_policies"`
+
+	// Name of the saved profile this config came from, recorded in run history
+	profile string
+	// Where sync -result writes the result document of each run
+	resultPath string
 }
 
 // LoadConfig reads a YAML file and unmarshals into Config
@@ -35,25 +224,238 @@ func LoadConfig(path string) (*Config, error) {
 	return &cfg, nil
 }
 
-// OpenDatabases opens connections to the prod and dev MySQL databases
+// OpenDatabases opens connections to the prod and dev MySQL databases.
+// If prod is reached through the Cloud SQL connector, the dialer must already
+// be registered (see applyCloudSQL).
 func OpenDatabases(cfg *Config) (*sql.DB, *sql.DB, error) {
-	prodDB, err := sql.Open("mysql", cfg.ProdDSN)
+	prodDB, err := OpenProdDatabase(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	devDB, err := OpenDevDatabase(cfg)
+	if err != nil {
+		prodDB.Close()
+		return nil, nil, err
+	}
+	return prodDB, devDB, nil
+}
+
+// OpenProdDatabase opens only the prod (source) database, for commands that never write to dev.
+func OpenProdDatabase(cfg *Config) (*sql.DB, error) {
+	return openDB("prod", cfg.ProdDSN, cfg.ProdTLS, cfg.forceUTF8MB4())
+}
+
+// OpenDevDatabase opens only the dev (target) database, for commands that never touch prod.
+func OpenDevDatabase(cfg *Config) (*sql.DB, error) {
+	return openDB("dev", cfg.DevDSN, cfg.DevTLS, cfg.forceUTF8MB4())
+}
+
+// openDB opens and pings one connection; name is "prod" or "dev"
+func openDB(name, dsn string, tlsCfg TLSConfig, utf8mb4 bool) (*sql.DB, error) {
+	dsn, err := applyTLS(dsn, "devseeder-"+name, tlsCfg)
 	if err != nil {
-		return nil, nil, fmt.Errorf("prodDB connect error: %w", err)
+		return nil, fmt.Errorf("%sDB TLS error: %w", name, err)
+	}
+	if utf8mb4 {
+		if dsn, err = forceUTF8MB4(dsn); err != nil {
+			return nil, fmt.Errorf("%sDB charset error: %w", name, err)
+		}
+	}
+	if dsn, err = utcSession(dsn); err != nil {
+		return nil, fmt.Errorf("%sDB time zone error: %w", name, err)
 	}
 
-	devDB, err := sql.Open("mysql", cfg.DevDSN)
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		return nil, nil, fmt.Errorf("devDB connect error: %w", err)
+		return nil, fmt.Errorf("%sDB connect error: %w", name, err)
 	}
 
-	// Ping to ensure databases are up
-	if err := prodDB.Ping(); err != nil {
-		return nil, nil, fmt.Errorf("prodDB ping error: %w", err)
+	// Ping to ensure the database is up
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%sDB ping error: %w", name, err)
 	}
-	if err := devDB.Ping(); err != nil {
-		return nil, nil, fmt.Errorf("devDB ping error: %w", err)
+
+	return db, nil
+}
+
+// openAuditedDB is openDB for connections the CLI uses itself (migrations, dump
+// loading): every statement run through the result is written to audit.
+func openAuditedDB(name, dsn string, tlsCfg TLSConfig, utf8mb4 bool, audit *devseeder.AuditLog) (*devseeder.DB, error) {
+	db, err := openDB(name, dsn, tlsCfg, utf8mb4)
+	if err != nil {
+		return nil, err
 	}
+	return devseeder.NewDB(db, name, audit), nil
+}
 
-	return prodDB, devDB, nil
+// forceUTF8MB4 rewrites a DSN so the connection uses utf8mb4 regardless of any
+// charset or collation it asked for.
+func forceUTF8MB4(dsn string) (string, error) {
+	// The driver only exposes the charset param through the DSN string, so drop it there
+	if base, query, ok := strings.Cut(dsn, "?"); ok {
+		var params []string
+		for _, p := range strings.Split(query, "&") {
+			if !strings.HasPrefix(p, "charset=") {
+				params = append(params, p)
+			}
+		}
+		dsn = base
+		if len(params) > 0 {
+			dsn += "?" + strings.Join(params, "&")
+		}
+	}
+
+	dsnCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse DSN: %w", err)
+	}
+	if !strings.HasPrefix(dsnCfg.Collation, "utf8mb4") {
+		dsnCfg.Collation = "utf8mb4_unicode_ci"
+	}
+	return dsnCfg.FormatDSN(), nil
+}
+
+// forceUTF8MB4 reports whether connections are switched to utf8mb4, which they
+// are unless force_utf8mb4 is explicitly false
+func (c *Config) forceUTF8MB4() bool {
+	return c.ForceUTF8MB4 == nil || *c.ForceUTF8MB4
+}
+
+// utcSession rewrites a DSN so the session time zone is UTC and DATETIME and
+// TIMESTAMP values are scanned as UTC time.Time. With both connections alike,
+// TIMESTAMPs copy unshifted even when the servers' time_zone settings differ.
+func utcSession(dsn string) (string, error) {
+	dsnCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse DSN: %w", err)
+	}
+	dsnCfg.ParseTime = true
+	dsnCfg.Loc = time.UTC
+	if dsnCfg.Params == nil {
+		dsnCfg.Params = map[string]string{}
+	}
+	dsnCfg.Params["time_zone"] = "'+00:00'"
+	return dsnCfg.FormatDSN(), nil
+}
+
+// tableLimits maps tables to their row limits; 0 or "all" copies the whole table
+type tableLimits map[string]int
+
+func (t *tableLimits) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]string
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	*t = make(tableLimits, len(raw))
+	for table, limit := range raw {
+		n, err := parseTableLimit(limit)
+		if err != nil {
+			return fmt.Errorf("tables: %s: %w", table, err)
+		}
+		(*t)[table] = n
+	}
+	return nil
+}
+
+// parseTableLimit reads a row limit: a number of rows, or 0 or "all" for every row
+func parseTableLimit(s string) (int, error) {
+	if strings.EqualFold(strings.TrimSpace(s), "all") {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid row limit %q (expected a number of rows, or 0 or all for every row)", s)
+	}
+	return n, nil
+}
+
+// fkChecks returns the dev foreign key check mode: fk_checks if set, else off
+// with disable_fk_checks and deferred without, as WithFKChecks defaults to
+func (c *Config) fkChecks() string {
+	switch {
+	case c.FKChecks != "":
+		return c.FKChecks
+	case c.DisableFKChecks:
+		return devseeder.FKChecksOff
+	}
+	return devseeder.FKChecksDeferred
+}
+
+// zeroDates validates zero_dates and parses zero_date_sentinel
+func (c *Config) zeroDates() (string, time.Time, error) {
+	switch c.ZeroDates {
+	case devseeder.ZeroDatesKeep, devseeder.ZeroDatesRelax, devseeder.ZeroDatesNull:
+		return c.ZeroDates, time.Time{}, nil
+	case devseeder.ZeroDatesSentinel:
+		sentinel := c.ZeroDateSentinel
+		if sentinel == "" {
+			sentinel = "1970-01-01 00:00:00"
+		}
+		for _, layout := range []string{time.DateTime, time.DateOnly} {
+			if t, err := time.Parse(layout, sentinel); err == nil {
+				return c.ZeroDates, t, nil
+			}
+		}
+		return "", time.Time{}, fmt.Errorf("zero_date_sentinel: cannot parse %q (expected YYYY-MM-DD [hh:mm:ss])", sentinel)
+	}
+	return "", time.Time{}, fmt.Errorf("zero_dates: unknown mode %q (expected relax, null or sentinel)", c.ZeroDates)
+}
+
+// VerifyConfig selects the post-sync checks. Each is empty (off), "warn" or "fail".
+type VerifyConfig struct {
+	// Every planned row arrived in dev
+	RowCounts string `yaml:"row_counts"`
+	// No copied row references a parent row missing from dev
+	ForeignKeys string `yaml:"foreign_keys"`
+	// Copied rows hash the same in prod and dev
+	Checksums string `yaml:"checksums"`
+	// Character columns of the copied rows are byte-identical in prod and dev
+	Text string `yaml:"text"`
+}
+
+func (v VerifyConfig) verification() (devseeder.Verification, error) {
+	for name, mode := range map[string]string{"row_counts": v.RowCounts, "foreign_keys": v.ForeignKeys, "checksums": v.Checksums, "text": v.Text} {
+		switch mode {
+		case devseeder.VerifyOff, devseeder.VerifyWarn, devseeder.VerifyFail:
+		default:
+			return devseeder.Verification{}, fmt.Errorf("verify.%s: unknown mode %q (expected %s or %s)", name, mode, devseeder.VerifyWarn, devseeder.VerifyFail)
+		}
+	}
+	return devseeder.Verification{RowCounts: v.RowCounts, ForeignKeys: v.ForeignKeys, Checksums: v.Checksums, Text: v.Text}, nil
+}
+
+// PlanCacheConfig keeps computed plans in the user cache directory (see
+// devseeder.WithPlanCache)
+type PlanCacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Plan again once the cached plan is this old (0: only when the tables,
+	// partitions or prod schema change)
+	MaxAgeMinutes int `yaml:"max_age_minutes"`
+}
+
+// MultiplyConfig turns the seeded subset into a load-test dataset (see
+// devseeder.Multiplication).
+type MultiplyConfig struct {
+	Factor  int   `yaml:"factor"`
+	Perturb bool  `yaml:"perturb"`
+	Seed    int64 `yaml:"seed"`
+}
+
+// HooksConfig holds what runs around a sync.
+type HooksConfig struct {
+	SQL      SQLHooksConfig     `yaml:"sql"`
+	Commands CommandHooksConfig `yaml:"commands"`
+}
+
+// SQLHooksConfig lists statements run on dev; the table hooks are keyed by table.
+type SQLHooksConfig struct {
+	BeforeRun   []string            `yaml:"before_run"`
+	AfterRun    []string            `yaml:"after_run"`
+	BeforeTable map[string][]string `yaml:"before_table"`
+	AfterTable  map[string][]string `yaml:"after_table"`
+}
+
+func (h SQLHooksConfig) sqlHooks() devseeder.SQLHooks {
+	return devseeder.SQLHooks{BeforeRun: h.BeforeRun, AfterRun: h.AfterRun, BeforeTable: h.BeforeTable, AfterTable: h.AfterTable}
 }
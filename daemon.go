@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+	"github.com/robfig/cron/v3"
+)
+
+// DaemonConfig schedules unattended refreshes (devseeder daemon).
+type DaemonConfig struct {
+	// Standard 5-field cron expression, e.g. "0 3 * * *" for every night at 03:00.
+	// Prefix with CRON_TZ=Europe/Berlin to read it in another time zone than the local one.
+	Schedule string `yaml:"schedule"`
+	// Saved profiles to refresh one after the other on every run; empty refreshes
+	// the config the daemon was started with
+	Targets []string `yaml:"targets"`
+}
+
+// daemonCommand refreshes the configured targets on a cron schedule until
+// interrupted. Runs never overlap: a run still going when the next is due
+// makes the daemon skip that one. Every run is recorded in the run history.
+// Usage: devseeder daemon [flags] [-now]
+func daemonCommand(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	now := fs.Bool("now", false, "also refresh once right away instead of waiting for the first scheduled time")
+	fs.Parse(args)
+
+	cfg := flags.load()
+	if cfg.Daemon.Schedule == "" {
+		fatalf(exitFailure, "daemon.schedule is not set\n")
+	}
+	schedule, err := cron.ParseStandard(cfg.Daemon.Schedule)
+	if err != nil {
+		fatalf(exitFailure, "Invalid daemon.schedule %q: %v\n", cfg.Daemon.Schedule, err)
+	}
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing := startTracing(ctx, cfg)
+	defer shutdownTracing()
+
+	if *now {
+		refreshTargets(ctx, cfg, audit)
+	}
+	for {
+		next := schedule.Next(time.Now())
+		log.Printf("Next refresh at %s", next.Format(time.RFC1123))
+		select {
+		case <-ctx.Done():
+			log.Printf("Daemon stopped")
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		refreshTargets(ctx, cfg, audit)
+		if missed := schedule.Next(next); missed.Before(time.Now()) {
+			log.Printf("Warning: the refresh due at %s was skipped, the previous one was still running", missed.Format(time.RFC1123))
+		}
+	}
+}
+
+// refreshTargets syncs every daemon target in turn. A failing target is logged
+// and does not stop the others or the daemon.
+func refreshTargets(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) {
+	targets := cfg.Daemon.Targets
+	if len(targets) == 0 {
+		targets = []string{""}
+	}
+	for _, name := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		label := name
+		if label == "" {
+			label = "default target"
+		}
+		log.Printf("Refreshing %s", label)
+		start := time.Now()
+		if err := refreshTarget(ctx, cfg, name, audit); err != nil {
+			log.Printf("Warning: refreshing %s failed: %v", label, err)
+			continue
+		}
+		log.Printf("Refreshed %s in %s", label, time.Since(start).Round(time.Second))
+	}
+}
+
+// refreshTarget runs one sync, of the daemon's own config or of a saved
+// profile. Profiles are reloaded every time so rotated credentials are picked up.
+func refreshTarget(ctx context.Context, base *Config, profile string, audit *devseeder.AuditLog) error {
+	cfg := *base
+	if profile != "" {
+		loaded, err := LoadProfile(profile)
+		if err != nil {
+			return fmt.Errorf("loading profile: %w", err)
+		}
+		if err := resolveKeychainPasswords(loaded); err != nil {
+			return fmt.Errorf("reading credentials from keychain: %w", err)
+		}
+		if err := resolveVaultPasswords(loaded); err != nil {
+			return fmt.Errorf("fetching credentials from Vault: %w", err)
+		}
+		if err := loaded.selectJobs(nil); err != nil {
+			return err
+		}
+		cfg = *loaded
+		cfg.profile = profile
+	}
+
+	// Nobody is there to confirm a target that looks like production
+	warnings, err := targetWarnings(cfg.ProdDSN, cfg.DevDSN)
+	if err != nil {
+		return fmt.Errorf("checking target database: %w", err)
+	}
+	if len(warnings) > 0 {
+		return fmt.Errorf("target looks like production, refusing to write to it: %v", warnings)
+	}
+
+	release, err := prepareSource(ctx, &cfg, audit)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return run(ctx, &cfg, audit)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// datasetInfo is written as dataset.json next to a dataset's snapshot manifest:
+// what produced the data, for whoever applies it later.
+type datasetInfo struct {
+	Version           string    `json:"version"`
+	Profile           string    `json:"profile,omitempty"`
+	SeededAt          time.Time `json:"seeded_at"`          // start of the sync run that produced the data
+	AnonymizationHash string    `json:"anonymization_hash"` // see anonymizationHash
+}
+
+// datasetsDir returns where dataset versions are kept: datasets_dir, or
+// ~/.config/devseeder/datasets on Linux
+func datasetsDir(cfg *Config) (string, error) {
+	if cfg.DatasetsDir != "" {
+		return cfg.DatasetsDir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "devseeder", "datasets"), nil
+}
+
+// anonymizationHash fingerprints the anonymize rules, so a dataset records which
+// rules its data went through
+func anonymizationHash(rules map[string]string) string {
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	sum := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(sum, "%s=%s\n", k, rules[k])
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// datasetCommand tags the seeded dev database as a named dataset version, or
+// re-applies one, so the same data can be reproduced at any time.
+// Usage: devseeder dataset tag|apply [flags] <version>, or devseeder dataset list [flags]
+func datasetCommand(args []string) {
+	if len(args) == 0 {
+		fatalf(exitFailure, "Usage: devseeder dataset tag|apply|list [flags] [version]\n")
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("dataset "+action, flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	fs.Parse(args)
+	version := fs.Arg(0)
+	switch action {
+	case "list":
+	case "tag", "apply":
+		if fs.NArg() != 1 {
+			fatalf(exitFailure, "Usage: devseeder dataset %s [flags] <version>\n", action)
+		}
+	default:
+		fatalf(exitFailure, "Unknown dataset action %q (expected tag, apply or list)\n", action)
+	}
+
+	cfg := flags.load()
+	root, err := datasetsDir(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+	if action == "list" {
+		if err := listDatasets(root); err != nil {
+			fatalf(exitFailure, "Error listing datasets: %v\n", err)
+		}
+		return
+	}
+	dir, err := snapshotPath(root, version)
+	if err != nil {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+	if action == "apply" {
+		confirmTarget(cfg)
+	}
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	devDB, err := OpenDevDatabase(cfg)
+	if err != nil {
+		fatalf(exitConnection, "Error opening dev database: %v\n", err)
+	}
+	defer devDB.Close()
+
+	seeder := devseeder.New(nil, devDB, devseeder.WithAuditLog(audit), devseeder.WithLockTimeout(cfg.LockTimeout),
+		devseeder.WithLogger(engineLogger(cfg)))
+	if action == "tag" {
+		err = tagDataset(ctx, cfg, seeder, dir, version)
+	} else {
+		err = applyDataset(ctx, cfg, seeder, dir)
+	}
+	if err != nil {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+}
+
+// tagDataset saves dev as dataset version into dir. Versions are never
+// overwritten, and only the data of a successful sync can be tagged.
+func tagDataset(ctx context.Context, cfg *Config, seeder *devseeder.Seeder, dir, version string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("dataset %s already exists in %s; versions cannot be changed", version, dir)
+	}
+	run, err := lastSync(cfg.profile)
+	if err != nil {
+		return err
+	}
+	if run.Status != "success" {
+		return fmt.Errorf("the last sync (%s) did not succeed (%s); tag a completed run",
+			run.StartedAt.Local().Format(time.DateTime), run.Status)
+	}
+
+	m, err := seeder.TagDataset(ctx, dir, version)
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	info := datasetInfo{Version: version, Profile: cfg.profile, SeededAt: run.StartedAt, AnonymizationHash: anonymizationHash(cfg.Anonymize)}
+	b, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dataset.json"), append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	rows := 0
+	for _, n := range m.Tables {
+		rows += n
+	}
+	log.Printf("Dataset %s: %d rows in %d tables saved to %s", version, rows, len(m.Tables), dir)
+	return nil
+}
+
+// applyDataset re-applies the dataset in dir, warning when the anonymize rules
+// have changed since it was tagged
+func applyDataset(ctx context.Context, cfg *Config, seeder *devseeder.Seeder, dir string) error {
+	info, err := readDatasetInfo(dir)
+	if err != nil {
+		return err
+	}
+	if info.AnonymizationHash != anonymizationHash(cfg.Anonymize) {
+		log.Printf("Warning: dataset %s was anonymized with different rules than the current config", info.Version)
+	}
+	return seeder.ApplyDataset(ctx, dir)
+}
+
+// lastSync returns the most recent recorded run of the given profile
+func lastSync(profile string) (RunReport, error) {
+	reports, err := loadHistory()
+	if err != nil {
+		return RunReport{}, err
+	}
+	for i := len(reports) - 1; i >= 0; i-- {
+		if reports[i].Profile == profile {
+			return reports[i], nil
+		}
+	}
+	return RunReport{}, errors.New("no sync recorded in the run history; seed dev before tagging a dataset")
+}
+
+func readDatasetInfo(dir string) (*datasetInfo, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "dataset.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no dataset in %s", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var info datasetInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, "dataset.json"), err)
+	}
+	return &info, nil
+}
+
+// listDatasets prints the dataset versions in root, oldest first
+func listDatasets(root string) error {
+	entries, err := os.ReadDir(root)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	type dataset struct {
+		info *datasetInfo
+		m    *devseeder.SnapshotManifest
+	}
+	var datasets []dataset
+	for _, e := range entries {
+		dir := filepath.Join(root, e.Name())
+		info, err := readDatasetInfo(dir)
+		if err != nil {
+			continue
+		}
+		m, err := devseeder.ReadSnapshotManifest(dir)
+		if err != nil {
+			continue
+		}
+		datasets = append(datasets, dataset{info, m})
+	}
+	if len(datasets) == 0 {
+		log.Printf("No datasets in %s", root)
+		return nil
+	}
+	slices.SortFunc(datasets, func(a, b dataset) int { return a.m.CreatedAt.Compare(b.m.CreatedAt) })
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tTAGGED\tSEEDED\tDATABASE\tTABLES\tROWS\tANONYMIZATION")
+	for _, d := range datasets {
+		rows := 0
+		for _, n := range d.m.Tables {
+			rows += n
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", d.info.Version,
+			d.m.CreatedAt.Local().Format("2006-01-02 15:04"), d.info.SeededAt.Local().Format("2006-01-02 15:04"),
+			d.m.Database, len(d.m.Tables), rows, d.info.AnonymizationHash[:12])
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+	"golang.org/x/term"
+)
+
+// charsetName matches character set and collation names, which CREATE DATABASE
+// cannot take as placeholders
+var charsetName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ensureDevDatabase creates the dev database when the dev server does not have
+// it yet, with prod's default character set and collation, and has the sync
+// create every table, so a brand-new MySQL server can be seeded as it is. It
+// asks first unless create_database is set. Any other failure to reach dev is
+// left for the sync to report.
+func ensureDevDatabase(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) error {
+	devDB, err := OpenDevDatabase(cfg)
+	if err == nil {
+		devDB.Close()
+		return nil
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) || myErr.Number != errBadDB {
+		return nil
+	}
+	dsnCfg, err := mysql.ParseDSN(cfg.DevDSN)
+	if err != nil {
+		return fmt.Errorf("cannot parse dev DSN: %w", err)
+	}
+	database := dsnCfg.DBName
+
+	charset, collation, err := prodDefaultCharset(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("reading prod's default character set: %w", err)
+	}
+	if !cfg.CreateDatabase {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return configError{fmt.Errorf("dev database %s does not exist; create it or set create_database", database)}
+		}
+		if !promptForBool(fmt.Sprintf("Dev database %s does not exist on %s. Create it (%s, %s) with every table?",
+			database, dsnCfg.Addr, charset, collation), true) {
+			return configError{fmt.Errorf("dev database %s does not exist", database)}
+		}
+	}
+
+	dsnCfg.DBName = ""
+	server, err := openAuditedDB("dev", dsnCfg.FormatDSN(), cfg.DevTLS, cfg.forceUTF8MB4(), audit)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	if _, err := server.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET %s COLLATE %s",
+		devseeder.QuoteIdent(database), charset, collation)); err != nil {
+		return fmt.Errorf("creating database %s: %w", database, err)
+	}
+	log.Printf("Created database %s (%s, %s); the sync creates its tables from prod's", database, charset, collation)
+	cfg.CreateMissingTables = true
+	return nil
+}
+
+// prodDefaultCharset returns the default character set and collation of the
+// prod database
+func prodDefaultCharset(ctx context.Context, cfg *Config) (string, string, error) {
+	prodDB, err := OpenProdDatabase(cfg)
+	if err != nil {
+		return "", "", err
+	}
+	defer prodDB.Close()
+	var charset, collation string
+	err = prodDB.QueryRowContext(ctx, "SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME "+
+		"FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = DATABASE()").Scan(&charset, &collation)
+	if err != nil {
+		return "", "", err
+	}
+	if !charsetName.MatchString(charset) || !charsetName.MatchString(collation) {
+		return "", "", fmt.Errorf("unexpected character set %q or collation %q", charset, collation)
+	}
+	return charset, collation, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// dockerReadyTimeout is how long a fresh MySQL container gets to initialize
+const dockerReadyTimeout = 3 * time.Minute
+
+// DockerConfig describes the disposable MySQL container used by -target docker.
+type DockerConfig struct {
+	// Image to run, e.g. "mysql:8.0" or "mariadb:11"
+	Image string `yaml:"image"`
+	// Container name; a timestamped one is generated when empty
+	Name string `yaml:"name"`
+	// Host port bound on 127.0.0.1; a free one is picked when 0
+	Port int `yaml:"port"`
+	// Schema created in the container; defaults to the prod database name
+	Database string `yaml:"database"`
+}
+
+// StartDockerMySQL starts a MySQL container, waits until it accepts connections
+// and returns a DSN for its (empty) schema and the container name.
+// The container is started with --rm, so `docker stop` removes it.
+func StartDockerMySQL(ctx context.Context, c DockerConfig, prodDSN string) (string, string, error) {
+	image := firstNonEmpty(c.Image, "mysql:8.0")
+	name := firstNonEmpty(c.Name, "devseeder-"+time.Now().Format("20060102-150405"))
+	database := c.Database
+	if database == "" {
+		if prodCfg, err := mysql.ParseDSN(prodDSN); err == nil {
+			database = prodCfg.DBName
+		}
+	}
+	database = firstNonEmpty(database, "devseeder")
+
+	secret := make([]byte, 12)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	password := hex.EncodeToString(secret)
+
+	port := ""
+	if c.Port != 0 {
+		port = strconv.Itoa(c.Port)
+	}
+	log.Printf("Starting %s container %s", image, name)
+	run := exec.CommandContext(ctx, "docker", "run", "-d", "--rm",
+		"--name", name,
+		"-e", "MYSQL_ROOT_PASSWORD="+password,
+		"-e", "MYSQL_DATABASE="+database,
+		"-p", "127.0.0.1:"+port+":3306",
+		image,
+	)
+	run.Stderr = os.Stderr
+	if err := run.Run(); err != nil {
+		return "", "", fmt.Errorf("docker run failed: %w", err)
+	}
+
+	// Ask docker which host port it picked
+	out, err := exec.CommandContext(ctx, "docker", "port", name, "3306/tcp").Output()
+	if err != nil {
+		return "", name, fmt.Errorf("docker port failed: %w", err)
+	}
+	addr := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+
+	dsnCfg := mysql.NewConfig()
+	dsnCfg.User = "root"
+	dsnCfg.Passwd = password
+	dsnCfg.Net = "tcp"
+	dsnCfg.Addr = addr
+	dsnCfg.DBName = database
+	dsn := dsnCfg.FormatDSN()
+
+	log.Printf("Waiting for MySQL in %s to accept connections on %s", name, addr)
+	if err := waitForMySQL(ctx, dsn, dockerReadyTimeout); err != nil {
+		return "", name, fmt.Errorf("container %s did not become ready: %w", name, err)
+	}
+	return dsn, name, nil
+}
+
+// waitForMySQL pings dsn until it answers or timeout elapses. The official images
+// initialize with networking off, so TCP connections only succeed once the real server is up.
+func waitForMySQL(ctx context.Context, dsn string, timeout time.Duration) error {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
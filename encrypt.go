@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// EncryptConfig selects how dump artifacts are encrypted with age
+// (https://age-encryption.org). Decrypt with: age -d -i key.txt seed.sql.age
+type EncryptConfig struct {
+	// age public keys (age1...) to encrypt to
+	Recipients []string `yaml:"recipients"`
+	// File with one age public key per line, as written by age-keygen -y
+	RecipientsFile string `yaml:"recipients_file"`
+	// File holding a passphrase, for symmetric encryption instead of recipients
+	PassphraseFile string `yaml:"passphrase_file"`
+	// File with age private keys (AGE-SECRET-KEY-...), as written by age-keygen,
+	// to decrypt artifacts with devseeder load
+	IdentityFile string `yaml:"identity_file"`
+}
+
+// enabled reports whether any encryption is configured.
+func (e EncryptConfig) enabled() bool {
+	return len(e.Recipients) > 0 || e.RecipientsFile != "" || e.PassphraseFile != ""
+}
+
+// ageRecipients parses the configured keys or passphrase into age recipients.
+func (e EncryptConfig) ageRecipients() ([]age.Recipient, error) {
+	if e.PassphraseFile != "" {
+		if len(e.Recipients) > 0 || e.RecipientsFile != "" {
+			return nil, fmt.Errorf("encrypt: passphrase_file cannot be combined with recipients")
+		}
+		pass, err := readTrimmedFile(e.PassphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: reading passphrase: %w", err)
+		}
+		if pass == "" {
+			return nil, fmt.Errorf("encrypt: passphrase file %s is empty", e.PassphraseFile)
+		}
+		r, err := age.NewScryptRecipient(pass)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Recipient{r}, nil
+	}
+
+	var recipients []age.Recipient
+	if e.RecipientsFile != "" {
+		f, err := os.Open(e.RecipientsFile)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: %w", err)
+		}
+		defer f.Close()
+		parsed, err := age.ParseRecipients(f)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: parsing %s: %w", e.RecipientsFile, err)
+		}
+		recipients = append(recipients, parsed...)
+	}
+	for _, key := range e.Recipients {
+		r, err := age.ParseX25519Recipient(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: invalid recipient %q: %w", key, err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+// ageIdentities parses the configured private keys or passphrase into age
+// identities, for reading encrypted artifacts.
+func (e EncryptConfig) ageIdentities() ([]age.Identity, error) {
+	var identities []age.Identity
+	if e.PassphraseFile != "" {
+		pass, err := readTrimmedFile(e.PassphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: reading passphrase: %w", err)
+		}
+		id, err := age.NewScryptIdentity(pass)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, id)
+	}
+	if e.IdentityFile != "" {
+		f, err := os.Open(e.IdentityFile)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: %w", err)
+		}
+		defer f.Close()
+		parsed, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: parsing %s: %w", e.IdentityFile, err)
+		}
+		identities = append(identities, parsed...)
+	}
+	return identities, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// confirmEstimate plans the run, prints how big it is and how long it should
+// take, and asks before going ahead.
+func confirmEstimate(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) {
+	prodDB, err := OpenProdDatabase(cfg)
+	if err != nil {
+		fatalf(exitConnection, "Error opening prod database: %v\n", err)
+	}
+	defer prodDB.Close()
+
+	seeder := devseeder.New(prodDB, nil, seederOptions(cfg, audit)...)
+	plan, err := seeder.Plan(ctx)
+	if err != nil {
+		fatalf(exitFailure, "Error planning the run: %v\n", err)
+	}
+	est, err := seeder.Estimate(ctx, plan)
+	if err != nil {
+		fatalf(exitFailure, "Error estimating the run: %v\n", err)
+	}
+	printEstimate(os.Stdout, est)
+
+	if !promptForBool("Start the sync?", true) {
+		fatalf(exitFailure, "Aborted")
+	}
+}
+
+// printEstimate prints the size of each planned table and, going by the
+// throughput of previous runs, how long the copy should take.
+func printEstimate(w io.Writer, est *devseeder.Estimate) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "TABLE\tROWS\tSIZE\t")
+	for _, t := range est.Tables {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t\n", t.Table, t.Rows, humanBytes(t.Bytes))
+	}
+	fmt.Fprintf(tw, "total\t%d\t%s\t\n", est.Rows, humanBytes(est.Bytes))
+	tw.Flush()
+
+	history, err := loadHistory()
+	if err != nil {
+		log.Printf("Warning: cannot read run history: %v\n", err)
+	}
+	if rate, ok := historicalThroughput(history); ok {
+		eta := time.Duration(float64(est.Rows) / rate * float64(time.Second))
+		fmt.Fprintf(w, "Estimated time: %s (previous runs copied %.0f rows/s)\n", eta.Round(time.Second), rate)
+	} else {
+		fmt.Fprintln(w, "Estimated time: unknown until a sync has completed on this machine")
+	}
+}
+
+// humanBytes formats n as B, KiB, MiB, ...
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
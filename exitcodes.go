@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// Exit statuses, so wrappers and pipelines can tell failures apart
+const (
+	exitFailure      = 1   // any other failure
+	exitConfig       = 2   // invalid config, flags or option combinations
+	exitConnection   = 3   // prod or dev cannot be reached or refuses the login
+	exitPlanTooLarge = 4   // the plan exceeds max_rows
+	exitCopyFailed   = 5   // the copy itself failed
+	exitInterrupted  = 130 // interrupted by Ctrl-C or SIGTERM
+)
+
+// MySQL errors refusing the login or the database
+const (
+	errDBAccessDenied = 1044
+	errAccessDenied   = 1045
+	errBadDB          = 1049
+)
+
+// configError marks a failure caused by the configuration rather than the run
+type configError struct{ err error }
+
+func (e configError) Error() string { return e.err.Error() }
+func (e configError) Unwrap() error { return e.err }
+
+// connectionError marks a failure to open or keep a database connection
+type connectionError struct{ err error }
+
+func (e connectionError) Error() string { return e.err.Error() }
+func (e connectionError) Unwrap() error { return e.err }
+
+// exitCleanups are the pending atExit funcs, most recent last
+var exitCleanups struct {
+	sync.Mutex
+	fns []*func()
+}
+
+// atExit makes fatalf run fn before the process exits, until the returned func
+// is called: that runs fn itself (once) and is meant to be deferred. os.Exit
+// skips deferred calls, which would leave e.g. a scratch schema behind.
+func atExit(fn func()) func() {
+	var once sync.Once
+	run := func() { once.Do(fn) }
+	exitCleanups.Lock()
+	exitCleanups.fns = append(exitCleanups.fns, &run)
+	exitCleanups.Unlock()
+	return func() {
+		exitCleanups.Lock()
+		exitCleanups.fns = slices.DeleteFunc(exitCleanups.fns, func(f *func()) bool { return f == &run })
+		exitCleanups.Unlock()
+		run()
+	}
+}
+
+// fatalf logs like log.Fatalf, runs the pending atExit funcs and exits with code
+func fatalf(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	exitCleanups.Lock()
+	fns := exitCleanups.fns
+	exitCleanups.fns = nil
+	exitCleanups.Unlock()
+	for i := len(fns) - 1; i >= 0; i-- {
+		(*fns[i])()
+	}
+	os.Exit(code)
+}
+
+// exitCode returns the exit status of a run that failed with err
+func exitCode(err error) int {
+	var myErr *mysql.MySQLError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, devseeder.ErrPlanTooLarge):
+		return exitPlanTooLarge
+	case errors.As(err, new(configError)), errors.Is(err, devseeder.ErrInvalidOptions):
+		return exitConfig
+	case errors.As(err, new(connectionError)), errors.Is(err, mysql.ErrInvalidConn), errors.Is(err, driver.ErrBadConn),
+		errors.As(err, &netErr):
+		return exitConnection
+	case errors.As(err, &myErr) && (myErr.Number == errAccessDenied || myErr.Number == errDBAccessDenied || myErr.Number == errBadDB):
+		return exitConnection
+	}
+	return exitCopyFailed
+}
+
+// runExitCode returns the exit status of a run that ended with err (nil on
+// success) while ctx was its context
+func runExitCode(ctx context.Context, err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case ctx.Err() != nil || errors.Is(err, context.Canceled):
+		return exitInterrupted
+	}
+	return exitCode(err)
+}
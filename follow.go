@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// defaultBinlogServerID is the server_id sync -follow connects to prod with when
+// binlog.server_id is not set
+const defaultBinlogServerID = 0x5eed
+
+// BinlogConfig holds the settings of `devseeder sync -follow`.
+type BinlogConfig struct {
+	// server_id of the replication connection; must not clash with a real replica
+	ServerID uint32 `yaml:"server_id"`
+	// mysql (default) or mariadb
+	Flavor string `yaml:"flavor"`
+}
+
+// binlogSource returns how to connect to prod as a replica, from the prod DSN.
+// It must be called before the source is prepared, as a dump source or the Cloud
+// SQL connector leave no binary log to read.
+func binlogSource(cfg *Config) (devseeder.BinlogSource, error) {
+	if cfg.SourceDump != "" || cfg.SourceCDC.enabled() || cfg.ProdCloudSQL.Instance != "" {
+		return devseeder.BinlogSource{}, fmt.Errorf("-follow needs a prod server reached over TCP, not a dump, a CDC stream or the Cloud SQL connector")
+	}
+	dsnCfg, err := mysql.ParseDSN(cfg.ProdDSN)
+	if err != nil {
+		return devseeder.BinlogSource{}, fmt.Errorf("cannot parse prod DSN: %w", err)
+	}
+	if dsnCfg.Net != "tcp" {
+		return devseeder.BinlogSource{}, fmt.Errorf("-follow needs a prod DSN using tcp, not %s", dsnCfg.Net)
+	}
+	tlsCfg, err := cfg.ProdTLS.tlsConfig()
+	if err != nil {
+		return devseeder.BinlogSource{}, err
+	}
+	if tlsCfg != nil && tlsCfg.ServerName == "" {
+		tlsCfg.ServerName, _, _ = net.SplitHostPort(dsnCfg.Addr)
+	}
+	serverID := cfg.Binlog.ServerID
+	if serverID == 0 {
+		serverID = defaultBinlogServerID
+	}
+	return devseeder.BinlogSource{
+		Addr:     dsnCfg.Addr,
+		User:     dsnCfg.User,
+		Password: dsnCfg.Passwd,
+		TLS:      tlsCfg,
+		ServerID: serverID,
+		Flavor:   cfg.Binlog.Flavor,
+	}, nil
+}
+
+// binlogStart returns prod's binlog position before the initial seed
+func binlogStart(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) (devseeder.BinlogPosition, error) {
+	prodDB, err := OpenProdDatabase(cfg)
+	if err != nil {
+		return devseeder.BinlogPosition{}, fmt.Errorf("opening prod database: %w", err)
+	}
+	defer prodDB.Close()
+	return devseeder.CurrentBinlogPosition(ctx, devseeder.NewDB(prodDB, "prod", audit))
+}
+
+// followBinlog keeps the seeded rows in step with prod from the given position
+// until ctx is cancelled.
+func followBinlog(ctx context.Context, cfg *Config, audit *devseeder.AuditLog, src devseeder.BinlogSource, from devseeder.BinlogPosition) error {
+	prodDB, devDB, err := OpenDatabases(cfg)
+	if err != nil {
+		return fmt.Errorf("opening databases: %w", err)
+	}
+	defer prodDB.Close()
+	defer devDB.Close()
+
+	policies, releasePolicies, err := loadWASMPolicies(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer releasePolicies()
+
+	opts := append(seederOptions(cfg, audit), policies, devseeder.WithLockTimeout(cfg.LockTimeout))
+	return devseeder.New(prodDB, devDB, opts...).FollowBinlog(ctx, src, from)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// graphCommand writes the prod FK graph as Graphviz DOT or SVG, optionally only
+// the tables the configured subset would pull in.
+// Usage: devseeder graph [flags] [-closure] [-format dot|svg] [-o graph.svg]
+func graphCommand(args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	outPath := fs.String("o", "-", "file to write the graph to (- for stdout)")
+	format := fs.String("format", "dot", "output format: dot, or svg (rendered with Graphviz's dot, which must be on PATH)")
+	closure := fs.Bool("closure", false, "only draw the tables the configured subset copies, with their row counts")
+	fs.Parse(args)
+	if *format != "dot" && *format != "svg" {
+		fatalf(exitFailure, "Unknown graph format %q (expected dot or svg)\n", *format)
+	}
+
+	cfg := flags.load()
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	closeSource := openSource(ctx, cfg, audit)
+	defer closeSource()
+
+	if err := graph(ctx, cfg, audit, *format, *outPath, *closure); err != nil {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+}
+
+func graph(ctx context.Context, cfg *Config, audit *devseeder.AuditLog, format, outPath string, closure bool) error {
+	prodDB, err := OpenProdDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("opening prod database: %w", err)
+	}
+	defer prodDB.Close()
+
+	var dot bytes.Buffer
+	if err := devseeder.New(prodDB, nil, seederOptions(cfg, audit)...).Graph(ctx, &dot, closure); err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if outPath != "-" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if format == "svg" {
+		cmd := exec.CommandContext(ctx, "dot", "-Tsvg")
+		cmd.Stdin = &dot
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("rendering SVG with Graphviz dot: %w", err)
+		}
+	} else if _, err := dot.WriteTo(out); err != nil {
+		return err
+	}
+
+	if f, ok := out.(*os.File); ok && f != os.Stdout {
+		if err := f.Close(); err != nil {
+			return err
+		}
+		log.Printf("Graph written to %s", outPath)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyPath returns where finished runs are recorded, one RunReport per line,
+// e.g. ~/.config/devseeder/history.jsonl on Linux
+func historyPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "devseeder", "history.jsonl"), nil
+}
+
+// appendHistory records a finished run.
+func appendHistory(report RunReport) error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	line, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadHistory returns the recorded runs, oldest first. No history is not an error.
+func loadHistory() ([]RunReport, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var reports []RunReport
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var r RunReport
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		reports = append(reports, r)
+	}
+	return reports, sc.Err()
+}
+
+// historicalThroughput returns the rows per second of the last few successful
+// runs taken together, or false if there are none to go by.
+func historicalThroughput(reports []RunReport) (float64, bool) {
+	const window = 10
+	rows, seconds, runs := 0, 0.0, 0
+	for i := len(reports) - 1; i >= 0 && runs < window; i-- {
+		r := reports[i]
+		if r.Status != "success" || r.DurationSeconds <= 0 {
+			continue
+		}
+		for _, n := range r.Tables {
+			rows += n
+		}
+		seconds += r.DurationSeconds
+		runs++
+	}
+	if rows == 0 || seconds == 0 {
+		return 0, false
+	}
+	return float64(rows) / seconds, true
+}
+
+// historyCommand prints the recorded runs, newest last, so growth of the seed
+// over time stands out.
+// Usage: devseeder history [-n 20] [-table orders]
+func historyCommand(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := fs.Int("n", 20, "how many of the latest runs to show (0 for all)")
+	table := fs.String("table", "", "show the rows and copy time of this table only")
+	fs.Parse(args)
+
+	reports, err := loadHistory()
+	if err != nil {
+		fatalf(exitFailure, "Error reading run history: %v\n", err)
+	}
+	if len(reports) == 0 {
+		path, _ := historyPath()
+		log.Printf("No runs recorded yet in %s", path)
+		return
+	}
+	if *limit > 0 && len(reports) > *limit {
+		reports = reports[len(reports)-*limit:]
+	}
+	printHistory(os.Stdout, reports, *table)
+}
+
+// printHistory prints one line per run with its size and how much that changed
+// since the previous successful run.
+func printHistory(w io.Writer, reports []RunReport, table string) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if table == "" {
+		fmt.Fprintln(tw, "STARTED\tSTATUS\tTABLES\tROWS\tCHANGE\tDURATION\tERROR")
+	} else {
+		fmt.Fprintf(tw, "STARTED\tSTATUS\t%s ROWS\tCHANGE\tDURATION\tERROR\n", strings.ToUpper(table))
+	}
+	prevRows := -1
+	for _, r := range reports {
+		rows, duration := 0, r.DurationSeconds
+		if table == "" {
+			for _, n := range r.Tables {
+				rows += n
+			}
+		} else {
+			rows, duration = r.Tables[table], r.TableSeconds[table]
+		}
+
+		change := ""
+		if r.Status == "success" {
+			if prevRows > 0 {
+				change = fmt.Sprintf("%+.0f%%", float64(rows-prevRows)/float64(prevRows)*100)
+			}
+			prevRows = rows
+		}
+		errText := r.Error
+		if len(errText) > 60 {
+			errText = errText[:57] + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t", r.StartedAt.Local().Format("2006-01-02 15:04"), r.Status)
+		if table == "" {
+			fmt.Fprintf(tw, "%d\t", len(r.Tables))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", rows, change,
+			time.Duration(duration*float64(time.Second)).Round(time.Second), errText)
+	}
+	tw.Flush()
+}
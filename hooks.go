@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// CommandHooksConfig lists shell commands run at the boundaries of each sync run,
+// e.g. `docker compose up -d db` before it, a cache flush or smoke tests after it.
+type CommandHooksConfig struct {
+	// Run before the databases are opened; a failing command fails the run
+	BeforeRun []string `yaml:"before_run"`
+	// Run once the sync succeeded; a failing command fails the run
+	AfterRun []string `yaml:"after_run"`
+	// Run when the sync (or an after_run command) failed; failures are only logged
+	OnFailure []string `yaml:"on_failure"`
+}
+
+// runCommandHooks runs cmds in order with sh -c, stopping at the first failure.
+// Each gets the run's metadata as DEVSEEDER_* variables (see hookEnv).
+func runCommandHooks(ctx context.Context, stage string, cmds []string, env []string) error {
+	for i, c := range cmds {
+		log.Printf("Running %s command %d/%d: %s", stage, i+1, len(cmds), c)
+		cmd := exec.CommandContext(ctx, "sh", "-c", c)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), env...)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s command %d (%s) failed: %w", stage, i+1, c, err)
+		}
+	}
+	return nil
+}
+
+// hookEnv returns the variables describing the run to a hook command. report is
+// nil before the run, when only the configuration is known.
+func hookEnv(cfg *Config, stage string, report *RunReport) []string {
+	env := []string{
+		"DEVSEEDER_HOOK=" + stage,
+		"DEVSEEDER_PROFILE=" + cfg.profile,
+		"DEVSEEDER_DEV_DSN=" + cfg.DevDSN,
+	}
+	if report == nil {
+		return env
+	}
+	rows := 0
+	for _, n := range report.Tables {
+		rows += n
+	}
+	env = append(env,
+		"DEVSEEDER_STATUS="+report.Status,
+		"DEVSEEDER_ERROR="+report.Error,
+		"DEVSEEDER_STARTED_AT="+report.StartedAt.UTC().Format(time.RFC3339),
+		fmt.Sprintf("DEVSEEDER_DURATION_SECONDS=%.3f", report.DurationSeconds),
+		fmt.Sprintf("DEVSEEDER_TABLES=%d", len(report.Tables)),
+		fmt.Sprintf("DEVSEEDER_ROWS=%d", rows),
+	)
+	// The whole report, with per-table counts, as the webhook receives it
+	if b, err := json.Marshal(report); err == nil {
+		env = append(env, "DEVSEEDER_REPORT="+string(b))
+	}
+	return env
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// JobConfig is a named subset of the config (see Config.Jobs)
+type JobConfig struct {
+	// Tables to seed and their row limits, like the top-level tables
+	Tables tableLimits `yaml:"tables"`
+	// Seed selection restricted to these partitions, like the top-level partitions
+	Partitions map[string][]string `yaml:"partitions"`
+}
+
+// selectJobs sets the tables to copy from cfg.Jobs: only the named jobs' tables,
+// or with no names every job's on top of the top-level tables. The jobs then run
+// as one sync sharing the connections, the FK metadata and the plan, so the rows
+// they have in common, like shared parent rows, are copied once. A table in
+// several jobs gets the highest limit, 0 (the whole table) being the highest,
+// and the union of their partitions, unless one of them lists it without any:
+// then it is read from every partition.
+func (cfg *Config) selectJobs(names []string) error {
+	if len(names) == 0 && len(cfg.Jobs) == 0 {
+		return nil
+	}
+	tables := make(map[string]int)
+	partitions := make(map[string][]string)
+	unpartitioned := make(map[string]bool) // listed somewhere without partitions
+	if len(names) == 0 {
+		for t, limit := range cfg.Tables {
+			tables[t] = limit
+			if len(cfg.Partitions[t]) == 0 {
+				unpartitioned[t] = true
+			}
+		}
+		for t, p := range cfg.Partitions {
+			partitions[t] = p
+		}
+		for name := range cfg.Jobs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		job, ok := cfg.Jobs[name]
+		if !ok {
+			known := make([]string, 0, len(cfg.Jobs))
+			for n := range cfg.Jobs {
+				known = append(known, n)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown job %q (configured: %s)", name, strings.Join(known, ", "))
+		}
+		for t, limit := range job.Tables {
+			if prev, ok := tables[t]; !ok || prev != 0 && (limit == 0 || limit > prev) {
+				tables[t] = limit
+			}
+			if len(job.Partitions[t]) == 0 {
+				unpartitioned[t] = true
+			}
+		}
+		for t, ps := range job.Partitions {
+			for _, p := range ps {
+				if !slices.Contains(partitions[t], p) {
+					partitions[t] = append(partitions[t], p)
+				}
+			}
+		}
+	}
+	for t := range unpartitioned {
+		delete(partitions, t)
+	}
+	log.Printf("Running jobs: %s", strings.Join(names, ", "))
+	cfg.Tables, cfg.Partitions = tables, partitions
+	return nil
+}
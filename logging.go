@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// Log formats accepted by -log-format and Config.LogFormat
+const (
+	LogFormatText = "text" // plain log lines (default)
+	LogFormatJSON = "json" // one JSON object per event on stderr
+)
+
+// setupLogging switches the standard logger to format. With json, every log line
+// becomes a JSON record with time, level and msg; errors carry an "error" field.
+func setupLogging(format string) error {
+	switch format {
+	case "", LogFormatText:
+		return nil
+	case LogFormatJSON:
+		logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+		slog.SetDefault(logger)
+		// Replace the writer slog.SetDefault installed, to pick levels from the text
+		log.SetFlags(0)
+		log.SetOutput(jsonLogWriter{logger})
+		return nil
+	}
+	return fmt.Errorf("unknown log format %q (expected %s or %s)", format, LogFormatText, LogFormatJSON)
+}
+
+// engineLogger returns the Logger the Seeder should use for cfg: a structured one
+// with json logs, so key events keep their table, rows and duration as fields.
+func engineLogger(cfg *Config) devseeder.Logger {
+	if cfg.LogFormat == LogFormatJSON {
+		return devseeder.NewSlogLogger(slog.Default())
+	}
+	return log.Default()
+}
+
+// jsonLogWriter turns lines written by the standard logger into slog records.
+// "Warning: ..." lines are logged at warn level and "Error...: <err>" lines at
+// error level with the cause in an "error" field.
+type jsonLogWriter struct {
+	logger *slog.Logger
+}
+
+func (w jsonLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	level := slog.LevelInfo
+	var attrs []any
+	if rest, ok := strings.CutPrefix(msg, "Warning: "); ok {
+		level, msg = slog.LevelWarn, rest
+	} else if strings.HasPrefix(msg, "Error") {
+		level = slog.LevelError
+		if head, cause, ok := strings.Cut(msg, ": "); ok {
+			msg = head
+			attrs = append(attrs, "error", cause)
+		}
+	}
+	w.logger.Log(context.Background(), level, msg, attrs...)
+	return len(p), nil
+}
@@ -1,39 +1,883 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"go/token"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
 )
 
 func main() {
+	// The first argument selects a command; plain "devseeder [flags]" runs a sync.
+	args := os.Args[1:]
+	command := "sync"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "sync":
+		syncCommand(args)
+	case "restore":
+		restoreCommand(args)
+	case "undo":
+		undoCommand(args)
+	case "clean":
+		cleanCommand(args)
+	case "snapshot":
+		snapshotCommand(args)
+	case "dataset":
+		datasetCommand(args)
+	case "dump":
+		dumpCommand(args)
+	case "serve":
+		serveCommand(args)
+	case "graph":
+		graphCommand(args)
+	case "plan":
+		planCommand(args)
+	case "apply":
+		applyCommand(args)
+	case "load":
+		loadCommand(args)
+	case "history":
+		historyCommand(args)
+	case "daemon":
+		daemonCommand(args)
+	default:
+		fatalf(exitFailure, "Unknown command %q (expected sync, plan, apply, dump, load, snapshot, restore, dataset, undo, clean, serve, daemon, graph or history)\n", command)
+	}
+}
+
+// configFlags are the flags every command uses to locate its configuration
+type configFlags struct {
+	configPath *string
+	profile    *string
+	logFormat  *string
+	jobs       *string
+}
+
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	return &configFlags{
+		configPath: fs.String("config", "", "path to a config.yaml; prompts interactively when empty"),
+		profile:    fs.String("profile", "", "name of a saved profile to load instead of prompting"),
+		logFormat:  fs.String("log-format", "", "log output: text, or json for one structured event per line (overrides log_format)"),
+		jobs:       fs.String("job", "", "comma-separated jobs of the config to run instead of the top-level tables and every job"),
+	}
+}
+
+// load reads the config from a file, a profile, or interactive prompts, and
+// resolves any passwords kept in the OS keychain or Vault.
+func (f *configFlags) load() *Config {
+	// Switch formats first, so loading the config already logs in it
+	if err := setupLogging(*f.logFormat); err != nil {
+		fatalf(exitConfig, "%v\n", err)
+	}
 
-	cfg := interactiveConfig()
+	var cfg *Config
+	var err error
+	switch {
+	case *f.configPath != "":
+		if cfg, err = LoadConfig(*f.configPath); err != nil {
+			fatalf(exitConfig, "Error loading config: %v\n", err)
+		}
+	case *f.profile != "":
+		if cfg, err = LoadProfile(*f.profile); err != nil {
+			fatalf(exitConfig, "Error loading profile %q: %v\n", *f.profile, err)
+		}
+		cfg.profile = *f.profile
+	default:
+		cfg = interactiveConfig()
+		if name := promptForValue("Save as Profile (empty to skip)", ""); name != "" {
+			if err := SaveProfile(name, cfg); err != nil {
+				log.Printf("Warning: cannot save profile %q: %v\n", name, err)
+			} else {
+				log.Printf("Saved profile %q; passwords are stored in the OS keychain", name)
+			}
+		}
+	}
+
+	if *f.logFormat != "" {
+		cfg.LogFormat = *f.logFormat
+	} else if err := setupLogging(cfg.LogFormat); err != nil {
+		fatalf(exitConfig, "%v\n", err)
+	}
+
+	if err := resolveKeychainPasswords(cfg); err != nil {
+		fatalf(exitFailure, "Error reading credentials from keychain: %v\n", err)
+	}
+
+	if err := resolveVaultPasswords(cfg); err != nil {
+		fatalf(exitFailure, "Error fetching credentials from Vault: %v\n", err)
+	}
+
+	var jobs []string
+	if *f.jobs != "" {
+		jobs = strings.Split(*f.jobs, ",")
+	}
+	if err := cfg.selectJobs(jobs); err != nil {
+		fatalf(exitConfig, "Error: %v\n", err)
+	}
+	return cfg
+}
+
+// syncCommand copies the configured subset from prod to dev, or to a fresh MySQL
+// container with -target docker, to the developer's own database with -target personal,
+// or to the MySQL service of a running compose project with -target compose:<service>.
+func syncCommand(args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	target := fs.String("target", "", "where to seed: empty for dev_dsn, docker to start a disposable MySQL container, personal for a dev_<user> database on the dev_dsn server, or compose:<service> for a service of the running compose project")
+	estimate := fs.Bool("estimate", false, "plan first, print the expected size and duration, and ask before copying")
+	replan := fs.Bool("replan", false, "compute the subset afresh and replace the cached plan instead of reusing it (plan_cache)")
+	review := fs.Bool("review", false, "step through the planned tables and approve, skip or change the limit of each before any data moves")
+	progress := fs.String("progress", "auto", "live per-table progress bars on stderr: auto (when stderr is a terminal), on, off, or tui for a full-screen dashboard")
+	watch := fs.Duration("watch", 0, "keep running and re-sync incrementally at this interval (e.g. 1h), only adding rows dev does not have yet")
+	follow := fs.Bool("follow", false, "after seeding, keep following prod's binlog and apply changes to the seeded rows until interrupted")
+	multiply := fs.Int("multiply", 0, "end up with this many copies of the seeded dataset, with fresh IDs and rewritten FKs, for load testing (overrides multiply.factor)")
+	zeroDates := fs.String("zero-dates", "", "how to write 0000-00-00 dates a strict dev rejects: relax (dev sql_mode), null or sentinel (overrides zero_dates)")
+	result := fs.String("result", "", "write a JSON result document (status, per-table counts, warnings, duration, exit code) to this file when the sync ends (- for stdout)")
+	fs.Parse(args)
+	service, isCompose := strings.CutPrefix(*target, "compose:")
+	if *target != "" && *target != "docker" && *target != "personal" && (!isCompose || service == "") {
+		fatalf(exitConfig, "Unknown target %q (expected docker, personal or compose:<service>)\n", *target)
+	}
+	if *follow && *watch > 0 {
+		fatalf(exitConfig, "-follow and -watch cannot be combined\n")
+	}
+	if *review && *watch > 0 {
+		fatalf(exitConfig, "-review and -watch cannot be combined\n")
+	}
+	ui, err := progressMode(*progress)
+	if err != nil {
+		fatalf(exitConfig, "%v\n", err)
+	}
+	if *review {
+		// The prompts need the terminal to themselves until the copy starts
+		if ui == progressTUI {
+			fatalf(exitConfig, "-review cannot be combined with -progress tui\n")
+		}
+		ui = progressOff
+	}
+
+	cfg := flags.load()
+	if *zeroDates != "" {
+		cfg.ZeroDates = *zeroDates
+	}
+	if *multiply > 0 {
+		cfg.Multiply.Factor = *multiply
+	}
+	if *replan {
+		cfg.PlanCache.MaxAgeMinutes = -1
+	}
+	cfg.resultPath = *result
+	if cfg.Multiply.Factor > 1 && (*watch > 0 || cfg.Prune) {
+		fatalf(exitConfig, "Multiplied datasets cannot be synced incrementally or pruned; drop -watch and prune\n")
+	}
+	if *target != "docker" && !isCompose {
+		confirmTarget(cfg)
+	}
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+	log.Printf("Auditing executed statements to %s", audit.Path())
+
+	// Cancel in-flight work on Ctrl-C / SIGTERM instead of dying mid-insert.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var container string
+	if *target == "docker" {
+		// The container starts empty: seed into it with the prod schema
+		dsn, name, err := StartDockerMySQL(ctx, cfg.Docker, cfg.ProdDSN)
+		if err != nil {
+			fatalf(exitFailure, "Error starting MySQL container: %v\n", err)
+		}
+		container = name
+		cfg.DevDSN = dsn
+		cfg.DevTLS = TLSConfig{}
+		cfg.CreateMissingTables = true
+	}
+	if *target == "personal" {
+		dsn, err := ProvisionPersonalDatabase(ctx, cfg, audit)
+		if err != nil {
+			fatalf(exitFailure, "Error provisioning personal database: %v\n", err)
+		}
+		cfg.DevDSN = dsn
+		cfg.CreateMissingTables = true
+	}
+	if isCompose {
+		dsn, err := ResolveComposeService(ctx, cfg.Compose, service, composeFallbackDatabase(cfg))
+		if err != nil {
+			fatalf(exitFailure, "Error resolving compose service: %v\n", err)
+		}
+		cfg.DevDSN = dsn
+		cfg.DevTLS = TLSConfig{}
+		cfg.CreateMissingTables = true
+	}
+
+	var binlog devseeder.BinlogSource
+	if *follow {
+		if binlog, err = binlogSource(cfg); err != nil {
+			fatalf(exitFailure, "Error: %v\n", err)
+		}
+	}
+
+	shutdownTracing := startTracing(ctx, cfg)
+	defer shutdownTracing()
+
+	closeSource := openSource(ctx, cfg, audit)
+	defer closeSource()
+
+	if *target != "docker" {
+		if err := ensureDevDatabase(ctx, cfg, audit); err != nil {
+			fatalf(exitCode(err), "Error: %v\n", err)
+		}
+	}
+
+	var binlogFrom devseeder.BinlogPosition
+	if *follow {
+		if binlogFrom, err = binlogStart(ctx, cfg, audit); err != nil {
+			fatalf(exitFailure, "Error reading prod binlog position: %v\n", err)
+		}
+	}
+
+	if *estimate {
+		confirmEstimate(ctx, cfg, audit)
+	}
+
+	if cfg.LogFormat == LogFormatJSON {
+		ui = progressOff
+	}
+	var extra []devseeder.Option
+	if *review {
+		extra = append(extra, devseeder.WithPlanReview(interactiveReview()))
+	}
+	if err := runSync(ctx, cfg, audit, ui, extra...); err != nil {
+		shutdownTracing()
+		exitOnSyncError(ctx, err)
+	}
+
+	if container != "" {
+		log.Printf("MySQL container %s is seeded; stop (and remove) it with: docker stop %s", container, container)
+		fmt.Println(cfg.DevDSN)
+	}
+	if *target == "personal" {
+		log.Printf("Your database is seeded; connect with the DSN below")
+		fmt.Println(cfg.DevDSN)
+	}
+	if *watch > 0 {
+		watchSync(ctx, cfg, audit, ui, *watch)
+	}
+	if *follow {
+		if err := followBinlog(ctx, cfg, audit, binlog, binlogFrom); err != nil {
+			fatalf(exitFailure, "Error following prod binlog: %v\n", err)
+		}
+	}
+}
+
+// openAuditLog opens the run's audit log, also logging slow statements when configured.
+func openAuditLog(cfg *Config) (*devseeder.AuditLog, error) {
+	audit, err := devseeder.OpenAuditLog(cfg.AuditDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SlowQueryMs > 0 {
+		audit.LogSlowQueries(time.Duration(cfg.SlowQueryMs) * time.Millisecond)
+	}
+	return audit, nil
+}
+
+// closeAuditLog closes audit, pointing out any slow statements it recorded.
+func closeAuditLog(audit *devseeder.AuditLog) {
+	if n, path := audit.SlowQueries(); n > 0 {
+		log.Printf("%d statements took longer than slow_query_ms; see %s", n, path)
+	}
+	audit.Close()
+}
+
+// startTracing installs the configured trace exporter; the returned func flushes it
+// and may be called more than once. A fatal exit flushes it too.
+func startTracing(ctx context.Context, cfg *Config) func() {
+	shutdown, err := setupTracing(ctx, cfg.Tracing)
+	if err != nil {
+		fatalf(exitFailure, "Error setting up tracing: %v\n", err)
+	}
+	return atExit(shutdown)
+}
+
+// openSource points cfg.ProdDSN at the data source: a scratch schema loaded from
+// cfg.SourceDump or rebuilt from cfg.SourceCDC, or prod itself (through the Cloud SQL connector when configured).
+// The returned func releases the source; a fatal exit releases it too.
+func openSource(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) func() {
+	release, err := prepareSource(ctx, cfg, audit)
+	if err != nil {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+	return atExit(release)
+}
+
+// prepareSource is openSource for callers that outlive a failed source, like the daemon.
+func prepareSource(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) (func(), error) {
+	if cfg.SourceDump != "" {
+		dsn, drop, err := LoadDumpSource(ctx, cfg, audit)
+		if err != nil {
+			return nil, fmt.Errorf("loading source dump: %w", err)
+		}
+		cfg.ProdDSN = dsn
+		cfg.ProdTLS = cfg.DevTLS
+		return drop, nil
+	}
+	if cfg.SourceCDC.enabled() {
+		dsn, err := PrepareCDCSource(ctx, cfg, audit)
+		if err != nil {
+			return nil, fmt.Errorf("reading CDC source: %w", err)
+		}
+		cfg.ProdDSN = dsn
+		cfg.ProdTLS = cfg.DevTLS
+		return func() {}, nil
+	}
+
+	prodDSN, closeCloudSQL, err := applyCloudSQL(cfg.ProdDSN, cfg.ProdCloudSQL)
+	if err != nil {
+		return nil, fmt.Errorf("setting up Cloud SQL connector: %w", err)
+	}
+	cfg.ProdDSN = prodDSN
+	return func() { closeCloudSQL() }, nil
+}
+
+// run opens both databases and performs the sync; extra options are added to the ones
+// derived from cfg. Session settings on dev are restored before it returns, even on
+// error or cancellation.
+func run(ctx context.Context, cfg *Config, audit *devseeder.AuditLog, extra ...devseeder.Option) (err error) {
+	// Every run is recorded locally (see devseeder history) and optionally posted to a webhook
+	rec := newRunRecorder()
+	extra = append(extra, devseeder.WithProgress(rec.progress), devseeder.WithLogger(rec.logger(engineLogger(cfg))))
+	defer func() {
+		report := rec.report(ctx, err)
+		report.Profile = cfg.profile
+		if err == nil && len(cfg.Hooks.Commands.AfterRun) > 0 {
+			if err = runCommandHooks(ctx, "after_run", cfg.Hooks.Commands.AfterRun, hookEnv(cfg, "after_run", &report)); err != nil {
+				report = rec.report(ctx, err)
+				report.Profile = cfg.profile
+			}
+		}
+		if err != nil && len(cfg.Hooks.Commands.OnFailure) > 0 {
+			// Still run after a cancellation, e.g. to tear down what before_run started
+			hookCtx := context.WithoutCancel(ctx)
+			if herr := runCommandHooks(hookCtx, "on_failure", cfg.Hooks.Commands.OnFailure, hookEnv(cfg, "on_failure", &report)); herr != nil {
+				log.Printf("Warning: %v\n", herr)
+			}
+		}
+		if herr := appendHistory(report); herr != nil {
+			log.Printf("Warning: cannot record run history: %v\n", herr)
+		}
+		if cfg.resultPath != "" {
+			if rerr := writeResult(cfg.resultPath, report); rerr != nil {
+				log.Printf("Warning: cannot write result document: %v\n", rerr)
+			}
+		}
+		if cfg.Webhook.URL == "" {
+			return
+		}
+		if werr := postWebhook(cfg.Webhook, report); werr != nil {
+			log.Printf("Warning: cannot notify webhook: %v\n", werr)
+		}
+	}()
+
+	// e.g. start the database container or stop the app using it
+	if err := runCommandHooks(ctx, "before_run", cfg.Hooks.Commands.BeforeRun, hookEnv(cfg, "before_run", nil)); err != nil {
+		return err
+	}
 
 	prodDB, devDB, err := OpenDatabases(cfg)
 	if err != nil {
-		log.Fatalf("Error opening databases: %v\n", err)
+		return connectionError{fmt.Errorf("opening databases: %w", err)}
 	}
 
 	// Close connections once all operations are completed.
 	defer prodDB.Close()
 	defer devDB.Close()
 
-	// By setting foreign_key_checks to 0, we can disable foreign key constraints during data synchronization.
-	// This allows us to perform operations that would otherwise violate foreign key constraints.
-	if _, err := devDB.Exec("SET foreign_key_checks = 0"); err != nil {
-		log.Printf("Warning: cannot disable foreign_key_checks: %v\n", err)
+	policies, releasePolicies, err := loadWASMPolicies(ctx, cfg)
+	if err != nil {
+		return configError{err}
 	}
+	defer releasePolicies()
 
-	// Fetch all foreign keys from the production database.
-	allFks, err := FetchAllForeignKeys(prodDB) // from fks.go
+	opts := seederOptions(cfg, audit)
+	opts = append(opts,
+		policies,
+		devseeder.WithLockTimeout(cfg.LockTimeout),
+		// Bring the dev schema up to date before seeding it
+		devseeder.WithBeforeSync(func(ctx context.Context) error {
+			if err := RunMigrations(ctx, cfg.Migrate, cfg, audit); err != nil {
+				return fmt.Errorf("pre-sync migrations: %w", err)
+			}
+			return nil
+		}),
+	)
+	if cfg.ResetTables {
+		opts = append(opts, devseeder.WithResetTables(cfg.BackupTables))
+	}
+	if cfg.UndoDir != "" {
+		undo := devseeder.NewUndoLog()
+		opts = append(opts, devseeder.WithUndoLog(undo))
+		// Write whatever was inserted, even if the sync failed halfway
+		defer func() {
+			path, err := undo.WriteScript(cfg.UndoDir)
+			if err != nil {
+				log.Printf("Warning: cannot write undo script: %v\n", err)
+				return
+			}
+			log.Printf("Undo script written to %s; revert with: devseeder undo %s", path, path)
+		}()
+	}
+	if cfg.TrackState {
+		opts = append(opts, devseeder.WithStateTracking())
+	}
+	if cfg.ColumnIntersection {
+		opts = append(opts, devseeder.WithColumnIntersection())
+	}
+	if cfg.Prune {
+		opts = append(opts, devseeder.WithPrune())
+	}
+	if cfg.DisableTriggers {
+		opts = append(opts, devseeder.WithDisabledTriggers(cfg.AuditDir))
+	}
+	if cfg.SkipPreflight {
+		opts = append(opts, devseeder.WithoutPreflight())
+	}
+	if cfg.CreateMissingTables {
+		opts = append(opts, devseeder.WithCreateMissingTables())
+	}
+	if cfg.CopyViews {
+		opts = append(opts, devseeder.WithCopyViews())
+	}
+	if cfg.CopyRoutines {
+		opts = append(opts, devseeder.WithCopyRoutines())
+	}
+	if cfg.CopyTriggers {
+		opts = append(opts, devseeder.WithCopyTriggers())
+	}
+	verification, err := cfg.Verify.verification()
 	if err != nil {
-		log.Fatalf("Error fetching all FKs: %v\n", err)
+		return configError{err}
+	}
+	opts = append(opts, devseeder.WithVerification(verification), devseeder.WithSQLHooks(cfg.Hooks.SQL.sqlHooks()))
+	switch cfg.WriteStrategy {
+	case "", "insert":
+	case devseeder.WriteIgnore, devseeder.WriteAppend:
+		opts = append(opts, devseeder.WithWriteStrategy(cfg.WriteStrategy))
+	default:
+		return configError{fmt.Errorf("write_strategy: unknown strategy %q (expected insert, ignore or append)", cfg.WriteStrategy)}
+	}
+	if len(cfg.Conflicts) > 0 {
+		opts = append(opts, devseeder.WithConflictStrategies(cfg.Conflicts))
+	}
+	if cfg.Multiply.Factor > 1 {
+		opts = append(opts, devseeder.WithMultiplication(devseeder.Multiplication{
+			Factor: cfg.Multiply.Factor, Perturb: cfg.Multiply.Perturb, Seed: cfg.Multiply.Seed}))
+	}
+	if cfg.FixturesDir != "" {
+		opts = append(opts, devseeder.WithFixtures(cfg.FixturesDir))
 	}
+	zeroDates, sentinel, err := cfg.zeroDates()
+	if err != nil {
+		return configError{err}
+	}
+	opts = append(opts, devseeder.WithZeroDates(zeroDates, sentinel), devseeder.WithFKChecks(cfg.fkChecks()))
+
+	return devseeder.New(prodDB, devDB, append(opts, extra...)...).Sync(ctx)
+}
+
+// runSync runs one sync with the chosen progress display
+func runSync(ctx context.Context, cfg *Config, audit *devseeder.AuditLog, ui string, extra ...devseeder.Option) error {
+	switch ui {
+	case progressBars:
+		display := newProgressDisplay(os.Stderr)
+		log.SetOutput(display)
+		defer log.SetOutput(os.Stderr)
+		return run(ctx, cfg, audit, append(extra, devseeder.WithProgress(display.update))...)
+	case progressTUI:
+		return runWithDashboard(ctx, cfg, audit, extra...)
+	default:
+		return run(ctx, cfg, audit, extra...)
+	}
+}
+
+// exitOnSyncError ends the process for a failed sync, with a status telling
+// what failed (see exitCode), or 130 if it was interrupted.
+func exitOnSyncError(ctx context.Context, err error) {
+	code := runExitCode(ctx, err)
+	if code == exitInterrupted {
+		log.Printf("Interrupted: %v\n", err)
+		log.Printf("Tables copied before the interruption are complete; the remaining tables were not touched")
+		os.Exit(code)
+	}
+	fatalf(code, "Error: %v\n", err)
+}
+
+// watchSync re-runs the sync every interval until interrupted. Later runs are
+// incremental and never reset tables: they only add the planned rows dev does
+// not have yet. A failed run is logged and the next one tried on schedule.
+func watchSync(ctx context.Context, cfg *Config, audit *devseeder.AuditLog, ui string, interval time.Duration) {
+	incremental := *cfg
+	incremental.ResetTables = false
+	for {
+		log.Printf("Watching: next sync at %s", time.Now().Add(interval).Format(time.TimeOnly))
+		select {
+		case <-ctx.Done():
+			log.Printf("Stopped watching")
+			return
+		case <-time.After(interval):
+		}
+		if incremental.SourceCDC.enabled() {
+			// Apply the change events published since the last run first
+			if _, err := PrepareCDCSource(ctx, &incremental, audit); err != nil {
+				if ctx.Err() != nil {
+					log.Printf("Stopped watching: %v", err)
+					return
+				}
+				log.Printf("Warning: cannot read CDC source, retrying in %s: %v", interval, err)
+				continue
+			}
+		}
+		if err := runSync(ctx, &incremental, audit, ui, devseeder.WithIncremental()); err != nil {
+			if ctx.Err() != nil {
+				log.Printf("Stopped watching: %v", err)
+				return
+			}
+			log.Printf("Warning: sync failed, retrying in %s: %v", interval, err)
+		}
+	}
+}
+
+// seederOptions returns the Seeder options every command shares.
+func seederOptions(cfg *Config, audit *devseeder.AuditLog) []devseeder.Option {
+	opts := []devseeder.Option{
+		devseeder.WithTables(cfg.Tables),
+		devseeder.WithSchemas(cfg.Schemas...),
+		devseeder.WithPartitions(cfg.Partitions),
+		devseeder.WithOrderBy(cfg.OrderBy),
+		devseeder.WithTableMap(cfg.TableMap),
+		devseeder.WithColumnMap(cfg.ColumnMap),
+		devseeder.WithColumnValues(cfg.ColumnValues),
+		devseeder.WithReferenceTables(cfg.ReferenceTableRows),
+		devseeder.WithReconnect(cfg.ReconnectAttempts),
+		devseeder.WithMaxRows(cfg.MaxRows),
+		devseeder.WithEdgeLimits(cfg.EdgeLimits),
+		devseeder.WithAuditLog(audit),
+		devseeder.WithLogger(engineLogger(cfg)),
+	}
+	if cfg.MetadataCache {
+		if dir, err := cacheDir("metadata"); err != nil {
+			log.Printf("Warning: %v\n", err)
+		} else {
+			opts = append(opts, devseeder.WithMetadataCache(dir))
+		}
+	}
+	if cfg.PlanCache.Enabled {
+		if dir, err := cacheDir("plans"); err != nil {
+			log.Printf("Warning: %v\n", err)
+		} else {
+			opts = append(opts, devseeder.WithPlanCache(dir, time.Duration(cfg.PlanCache.MaxAgeMinutes)*time.Minute))
+		}
+	}
+	return opts
+}
+
+// cacheDir returns the directory of one kind of cached data, e.g.
+// ~/.cache/devseeder/metadata on Linux
+func cacheDir(kind string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "devseeder", kind), nil
+}
+
+// dumpCommand writes the computed subset to an SQL file instead of inserting it into dev.
+// Usage: devseeder dump [flags] [-format sql|mysqldump|ndjson|fixtures|parquet] [-compress auto|none|gzip|zstd] [-encrypt-to age1...] [-upload s3://bucket/prefix/] -o seed.sql
+func dumpCommand(args []string) {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	outPath := fs.String("o", "seed.sql", "file to write the SQL dump to (- for stdout); a directory for ndjson, fixtures and parquet; seed.go by default for go")
+	format := fs.String("format", "sql", "output format: sql, mysqldump (mysqldump-compatible SQL), ndjson (one <table>.ndjson per table), fixtures (go-testfixtures YAML), parquet (one <table>.parquet per table) or go (Go structs with the rows and a Seed function)")
+	goPackage := fs.String("package", "fixtures", "package name of the generated Go file with -format go")
+	compress := fs.String("compress", devseeder.CompressAuto, "compress the output: auto (from the .gz/.zst extension), none, gzip or zstd")
+	encryptTo := fs.String("encrypt-to", "", "comma-separated age recipients (age1...) to encrypt the output to, in addition to encrypt.recipients")
+	uploadURI := fs.String("upload", "", "upload the artifact to this s3:// or gs:// URI afterwards (overrides upload.uri)")
+	fs.Parse(args)
+	switch *format {
+	case "sql", "mysqldump", "ndjson", "fixtures", "parquet", "go":
+	default:
+		fatalf(exitFailure, "Unknown dump format %q (expected sql, mysqldump, ndjson, fixtures, parquet or go)\n", *format)
+	}
+	if *format == "go" {
+		if !token.IsIdentifier(*goPackage) {
+			fatalf(exitFailure, "Invalid Go package name %q\n", *goPackage)
+		}
+		outSet := false
+		fs.Visit(func(f *flag.Flag) { outSet = outSet || f.Name == "o" })
+		if !outSet {
+			*outPath = "seed.go"
+		}
+	}
+
+	cfg := flags.load()
+	if *uploadURI != "" {
+		cfg.Upload.URI = *uploadURI
+	}
+	if *encryptTo != "" {
+		cfg.Encrypt.Recipients = append(cfg.Encrypt.Recipients, strings.Split(*encryptTo, ",")...)
+	}
+	if cfg.Upload.URI != "" && *outPath == "-" {
+		fatalf(exitFailure, "Cannot upload a dump written to stdout\n")
+	}
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing := startTracing(ctx, cfg)
+	defer shutdownTracing()
+
+	closeSource := openSource(ctx, cfg, audit)
+	defer closeSource()
+
+	if err := dump(ctx, cfg, audit, *format, *compress, *outPath, *goPackage); err != nil {
+		shutdownTracing()
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+	if cfg.Upload.URI != "" {
+		if err := UploadArtifact(ctx, cfg.Upload, *outPath); err != nil {
+			fatalf(exitFailure, "Error uploading dump: %v\n", err)
+		}
+		if *format == "sql" || *format == "mysqldump" {
+			if err := UploadArtifact(ctx, cfg.Upload, *outPath+manifestSuffix); err != nil {
+				fatalf(exitFailure, "Error uploading manifest: %v\n", err)
+			}
+		}
+	}
+}
+
+// dump computes the subset from prod and writes it to outPath in the given format,
+// compressed and encrypted as requested
+func dump(ctx context.Context, cfg *Config, audit *devseeder.AuditLog, format, compress, outPath, goPackage string) error {
+	var artifact devseeder.ArtifactOptions
+	var err error
+	if artifact.Compression, err = devseeder.ResolveCompression(compress, outPath); err != nil {
+		return err
+	}
+	if cfg.Encrypt.enabled() {
+		if artifact.Recipients, err = cfg.Encrypt.ageRecipients(); err != nil {
+			return err
+		}
+	}
+	if format == "fixtures" && artifact.Ext() != "" {
+		return fmt.Errorf("fixtures cannot be compressed or encrypted: go-testfixtures reads plain YAML files")
+	}
+	if format == "go" && artifact.Ext() != "" {
+		return fmt.Errorf("Go fixtures cannot be compressed or encrypted: they are compiled into tests")
+	}
+	if format == "parquet" && artifact.Ext() != "" {
+		return fmt.Errorf("parquet files cannot be compressed or encrypted: they are already Snappy-compressed internally")
+	}
+
+	prodDB, err := OpenProdDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("opening prod database: %w", err)
+	}
+	defer prodDB.Close()
+
+	policies, releasePolicies, err := loadWASMPolicies(ctx, cfg)
+	if err != nil {
+		return configError{err}
+	}
+	defer releasePolicies()
 
-	SyncPartialData(prodDB, devDB, allFks, cfg.Tables, cfg.ResetTables)
+	seeder := devseeder.New(prodDB, nil, append(seederOptions(cfg, audit), policies)...)
+	plan, err := seeder.Plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "ndjson":
+		if err := seeder.ExportNDJSON(ctx, plan, outPath, artifact); err != nil {
+			return err
+		}
+		log.Printf("NDJSON files written to %s", outPath)
+		return nil
+	case "parquet":
+		if err := seeder.ExportParquet(ctx, plan, outPath); err != nil {
+			return err
+		}
+		log.Printf("Parquet files written to %s", outPath)
+		return nil
+	case "fixtures":
+		if err := seeder.ExportFixtures(ctx, plan, outPath); err != nil {
+			return err
+		}
+		log.Printf("Fixtures written to %s", outPath)
+		return nil
+	}
+
+	out, err := devseeder.CreateOutput(outPath, artifact)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if format == "go" {
+		err = seeder.ExportGoFixtures(ctx, plan, out, goPackage)
+	} else {
+		err = seeder.Dump(ctx, plan, out, format == "mysqldump")
+	}
+	if err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if outPath != "-" {
+		if format != "go" {
+			if err := writeArtifactManifest(cfg, outPath, format, plan); err != nil {
+				return fmt.Errorf("writing manifest: %w", err)
+			}
+		}
+		log.Printf("Dump written to %s", outPath)
+	}
+	return nil
+}
+
+// restoreCommand puts dev tables back from a snapshot taken with devseeder snapshot,
+// or else from the backups taken by a previous sync.
+// Usage: devseeder restore [flags] [snapshot name | timestamp]; the latest backup is
+// used without an argument.
+func restoreCommand(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	fs.Parse(args)
+
+	cfg := flags.load()
+	confirmTarget(cfg)
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	devDB, err := OpenDevDatabase(cfg)
+	if err != nil {
+		fatalf(exitConnection, "Error opening dev database: %v\n", err)
+	}
+	defer devDB.Close()
+
+	seeder := devseeder.New(nil, devDB, devseeder.WithAuditLog(audit), devseeder.WithLockTimeout(cfg.LockTimeout),
+		devseeder.WithLogger(engineLogger(cfg)))
+	if name := fs.Arg(0); name != "" && snapshotExists(name) {
+		dir, _ := snapshotDir(name)
+		if err := seeder.RestoreSnapshot(ctx, dir); err != nil {
+			fatalf(exitFailure, "Error restoring snapshot: %v\n", err)
+		}
+		return
+	}
+	if err := seeder.Restore(ctx, fs.Arg(0)); err != nil {
+		fatalf(exitFailure, "Error restoring backup: %v\n", err)
+	}
+}
+
+// undoCommand deletes the rows recorded in an undo script from dev.
+// Usage: devseeder undo [flags] <script>
+func undoCommand(args []string) {
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fatalf(exitFailure, "Usage: devseeder undo [flags] <script>\n")
+	}
+
+	cfg := flags.load()
+	confirmTarget(cfg)
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	devDB, err := OpenDevDatabase(cfg)
+	if err != nil {
+		fatalf(exitConnection, "Error opening dev database: %v\n", err)
+	}
+	defer devDB.Close()
+
+	seeder := devseeder.New(nil, devDB, devseeder.WithAuditLog(audit), devseeder.WithLockTimeout(cfg.LockTimeout),
+		devseeder.WithLogger(engineLogger(cfg)))
+	if err := seeder.Undo(ctx, fs.Arg(0)); err != nil {
+		fatalf(exitFailure, "Error applying undo script: %v\n", err)
+	}
+}
+
+// cleanCommand deletes the rows that syncs with track_state recorded from dev.
+// Usage: devseeder clean [flags] [-tables users,orders]
+func cleanCommand(args []string) {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	tables := fs.String("tables", "", "comma-separated dev tables to clean (default: every table with seeded rows)")
+	fs.Parse(args)
+
+	cfg := flags.load()
+	confirmTarget(cfg)
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
 
-	if _, err := devDB.Exec("SET foreign_key_checks = 1"); err != nil {
-		log.Printf("Warning: cannot re-enable foreign_key_checks: %v\n", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	devDB, err := OpenDevDatabase(cfg)
+	if err != nil {
+		fatalf(exitConnection, "Error opening dev database: %v\n", err)
+	}
+	defer devDB.Close()
+
+	var only []string
+	if *tables != "" {
+		only = strings.Split(*tables, ",")
+	}
+	seeder := devseeder.New(nil, devDB, devseeder.WithAuditLog(audit), devseeder.WithLockTimeout(cfg.LockTimeout),
+		devseeder.WithLogger(engineLogger(cfg)), devseeder.WithSchemas(cfg.Schemas...))
+	if err := seeder.Clean(ctx, only); err != nil {
+		fatalf(exitFailure, "Error cleaning seeded rows: %v\n", err)
 	}
 }
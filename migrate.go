@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// MigrateConfig describes how to bring the dev schema up to date before seeding.
+// Command runs first (e.g. "goose up"), then every *.sql file in SQLDir in name order.
+type MigrateConfig struct {
+	Command string `yaml:"command"`
+	SQLDir  string `yaml:"sql_dir"`
+}
+
+// RunMigrations prepares the dev schema before any data is copied.
+// The command gets the dev DSN in $DEVSEEDER_DEV_DSN.
+func RunMigrations(ctx context.Context, m MigrateConfig, cfg *Config, audit *devseeder.AuditLog) error {
+	if m.Command != "" {
+		log.Printf("Running migration command: %s", m.Command)
+		cmd := exec.CommandContext(ctx, "sh", "-c", m.Command)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), "DEVSEEDER_DEV_DSN="+cfg.DevDSN)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("migration command failed: %w", err)
+		}
+	}
+
+	if m.SQLDir == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(m.SQLDir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		return nil
+	}
+
+	// Migration files usually hold several statements, so use a separate
+	// connection that allows multi-statement execution.
+	dsnCfg, err := mysql.ParseDSN(cfg.DevDSN)
+	if err != nil {
+		return fmt.Errorf("cannot parse dev DSN: %w", err)
+	}
+	dsnCfg.MultiStatements = true
+	db, err := openAuditedDB("dev", dsnCfg.FormatDSN(), cfg.DevTLS, cfg.forceUTF8MB4(), audit)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, f := range files {
+		script, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applying migration %s", f)
+		if _, err := db.ExecContext(ctx, string(script)); err != nil {
+			return fmt.Errorf("migration %s failed: %w", f, err)
+		}
+	}
+	return nil
+}
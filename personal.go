@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/user"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// PersonalConfig describes the per-developer database of -target personal.
+type PersonalConfig struct {
+	// Prefix of the database name (default "dev_")
+	Prefix string `yaml:"prefix"`
+	// Name used after the prefix; defaults to the OS user name
+	User string `yaml:"user"`
+}
+
+var unsafeDatabaseChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// personalDatabaseName returns e.g. dev_jane for OS user "Jane" or "CORP\jane"
+func personalDatabaseName(c PersonalConfig) (string, error) {
+	name := c.User
+	if name == "" {
+		u, err := user.Current()
+		if err != nil {
+			return "", fmt.Errorf("cannot determine the OS user: %w", err)
+		}
+		name = u.Username
+		if i := strings.LastIndexAny(name, `\/`); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+	name = strings.Trim(unsafeDatabaseChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("no usable user name for the personal database; set personal.user")
+	}
+	return firstNonEmpty(c.Prefix, "dev_") + name, nil
+}
+
+// ProvisionPersonalDatabase creates the developer's own database on the dev
+// server (CREATE DATABASE IF NOT EXISTS dev_<user>) and returns the dev DSN
+// pointed at it. The schema is created by the sync itself.
+func ProvisionPersonalDatabase(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) (string, error) {
+	database, err := personalDatabaseName(cfg.Personal)
+	if err != nil {
+		return "", err
+	}
+	dsnCfg, err := mysql.ParseDSN(cfg.DevDSN)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse dev DSN: %w", err)
+	}
+	dsnCfg.DBName = ""
+	server, err := openAuditedDB("dev", dsnCfg.FormatDSN(), cfg.DevTLS, cfg.forceUTF8MB4(), audit)
+	if err != nil {
+		return "", err
+	}
+	defer server.Close()
+
+	res, err := server.ExecContext(ctx, "CREATE DATABASE IF NOT EXISTS "+devseeder.QuoteIdent(database)+
+		" CHARACTER SET utf8mb4")
+	if err != nil {
+		return "", fmt.Errorf("creating database %s: %w", database, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("Created database %s", database)
+	} else {
+		log.Printf("Database %s already exists; seeding into it", database)
+	}
+
+	dsnCfg.DBName = database
+	return dsnCfg.FormatDSN(), nil
+}
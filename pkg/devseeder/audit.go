@@ -0,0 +1,211 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditLog records every statement DevSeeder executes, on either connection,
+// to a per-run file so it can be reviewed after the fact.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+
+	slowThreshold time.Duration
+	slowFile      *os.File // created on the first slow statement
+	slowCount     int
+}
+
+// OpenAuditLog creates a new audit file for this run inside dir.
+// The file name contains the run's start time, e.g. devseeder-audit-20250101-150405.log
+func OpenAuditLog(dir string) (*AuditLog, error) {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create audit directory: %w", err)
+	}
+	name := fmt.Sprintf("devseeder-audit-%s.log", time.Now().Format("20060102-150405"))
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open audit log: %w", err)
+	}
+	return &AuditLog{file: f}, nil
+}
+
+// Path returns the location of the audit file.
+func (a *AuditLog) Path() string {
+	if a == nil {
+		return ""
+	}
+	return a.file.Name()
+}
+
+// LogSlowQueries also writes every statement taking threshold or longer to a
+// devseeder-slow-<time>.log file next to the audit file, with the table it reads
+// or writes, so missing indexes (e.g. on FK columns) can be spotted.
+func (a *AuditLog) LogSlowQueries(threshold time.Duration) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.slowThreshold = threshold
+}
+
+// SlowQueries returns how many slow statements were recorded and where.
+func (a *AuditLog) SlowQueries() (int, string) {
+	if a == nil {
+		return 0, ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.slowFile == nil {
+		return 0, ""
+	}
+	return a.slowCount, a.slowFile.Name()
+}
+
+// Record appends one statement to the audit file.
+// A nil *AuditLog is valid and records nothing.
+func (a *AuditLog) Record(conn, query string, params int, dur time.Duration, err error) {
+	if a == nil {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error: " + err.Error()
+	}
+	query = strings.Join(strings.Fields(query), " ")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fmt.Fprintf(a.file, "%s\tconn=%s\tparams=%d\tduration=%s\t%s\t%s\n",
+		time.Now().Format(time.RFC3339Nano),
+		conn,
+		params,
+		dur,
+		status,
+		query,
+	)
+	if a.slowThreshold > 0 && dur >= a.slowThreshold {
+		a.recordSlow(conn, query, params, dur)
+	}
+}
+
+// statementTable matches the first table a statement reads or writes
+var statementTable = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|JOIN|TABLE)\s+` + "`((?:[^`]|``)+)`")
+
+// recordSlow writes one statement to the slow query file; a.mu must be held.
+func (a *AuditLog) recordSlow(conn, query string, params int, dur time.Duration) {
+	if a.slowFile == nil {
+		name := strings.Replace(filepath.Base(a.file.Name()), "devseeder-audit-", "devseeder-slow-", 1)
+		f, err := os.OpenFile(filepath.Join(filepath.Dir(a.file.Name()), name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return
+		}
+		a.slowFile = f
+	}
+	table := "-"
+	if m := statementTable.FindStringSubmatch(query); m != nil {
+		table = strings.ReplaceAll(m[1], "``", "`")
+	}
+	a.slowCount++
+	fmt.Fprintf(a.slowFile, "%s\tconn=%s\ttable=%s\tparams=%d\tduration=%s\t%s\n",
+		time.Now().Format(time.RFC3339Nano), conn, table, params, dur, query)
+}
+
+// Close flushes and closes the audit file.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.slowFile != nil {
+		a.slowFile.Close()
+	}
+	return a.file.Close()
+}
+
+// DB wraps a *sql.DB so every statement run through it ends up in the audit log.
+// Name identifies the connection ("prod" or "dev") in the log.
+type DB struct {
+	*sql.DB
+	Name    string
+	audit   *AuditLog
+	columns *columnCache
+	schemas []string // further schemas traversed besides the connection's (see WithSchemas)
+	// if set, FKs and columns are kept on disk between runs (see WithMetadataCache)
+	metadata *metadataCache
+	session  *session // reconnection settings (see WithReconnect)
+}
+
+// NewDB wraps db so its statements are recorded in audit under name.
+// audit may be nil.
+func NewDB(db *sql.DB, name string, audit *AuditLog) *DB {
+	return &DB{DB: db, Name: name, audit: audit, columns: &columnCache{}, session: &session{}}
+}
+
+// Exec runs a statement and records it in the audit log.
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext runs a statement and records it in the audit log.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.execResuming(ctx, query, query, args...)
+}
+
+// execResuming runs query, or resumeQuery when the connection dropped and the
+// statement is run again: query may have taken effect before the drop.
+func (db *DB) execResuming(ctx context.Context, query, resumeQuery string, args ...interface{}) (res sql.Result, err error) {
+	q := query
+	err = db.retry(ctx, func() error {
+		start := time.Now()
+		res, err = db.DB.ExecContext(ctx, q, args...)
+		db.audit.Record(db.Name, q, len(args), time.Since(start), err)
+		q = resumeQuery
+		return err
+	})
+	return res, err
+}
+
+// Query runs a query and records it in the audit log.
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext runs a query and records it in the audit log.
+// The duration covers the round-trip until the first result is available.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	err = db.retry(ctx, func() error {
+		start := time.Now()
+		rows, err = db.DB.QueryContext(ctx, query, args...)
+		db.audit.Record(db.Name, query, len(args), time.Since(start), err)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow runs a single-row query and records it in the audit log.
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext runs a single-row query and records it in the audit log.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) (row *sql.Row) {
+	db.retry(ctx, func() error {
+		start := time.Now()
+		row = db.DB.QueryRowContext(ctx, query, args...)
+		db.audit.Record(db.Name, query, len(args), time.Since(start), row.Err())
+		return row.Err()
+	})
+	return row
+}
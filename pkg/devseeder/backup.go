@@ -0,0 +1,108 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// backupInfix separates the original table name from the backup timestamp,
+// e.g. companies_backup_20250101150405
+const backupInfix = "_backup_"
+
+// newBackupSuffix returns the suffix used for all backups taken during one run.
+func newBackupSuffix() string {
+	return backupInfix + time.Now().Format("20060102150405")
+}
+
+// backupTable copies a dev table to <table><suffix> before it gets truncated.
+// The copy keeps the original table in place so FKs pointing at it are untouched.
+func backupTable(ctx context.Context, db *DB, table, suffix string) error {
+	backup := table + suffix
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s LIKE %s", quoteTable(backup), quoteTable(table))); err != nil {
+		return err
+	}
+	return copyTableRows(ctx, db, table, backup)
+}
+
+// copyTableRows copies all rows from one table to another with identical structure.
+// Columns are listed explicitly so invisible columns are included and generated ones skipped.
+func copyTableRows(ctx context.Context, db *DB, from, to string) error {
+	columns, err := copyableColumns(ctx, db, from)
+	if err != nil {
+		return err
+	}
+	cols := quoteIdents(columns)
+	_, err = db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", quoteTable(to), cols, cols, quoteTable(from)))
+	return err
+}
+
+// listBackups returns { originalTable : backupTable } for every backup taken with
+// the given timestamp. An empty timestamp selects the most recent backup run.
+func listBackups(ctx context.Context, db *DB, stamp string) (map[string]string, string, error) {
+	rows, err := db.QueryContext(ctx, `
+	SELECT table_name
+	FROM information_schema.tables
+	WHERE table_schema = DATABASE() AND table_name LIKE ?`,
+		"%"+backupInfix+"%",
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	byStamp := make(map[string]map[string]string)
+	latest := ""
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, "", err
+		}
+		i := strings.LastIndex(name, backupInfix)
+		original, ts := name[:i], name[i+len(backupInfix):]
+		if byStamp[ts] == nil {
+			byStamp[ts] = make(map[string]string)
+		}
+		byStamp[ts][original] = name
+		if ts > latest {
+			latest = ts
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if stamp == "" {
+		stamp = latest
+	}
+	backups := byStamp[stamp]
+	if len(backups) == 0 {
+		return nil, stamp, fmt.Errorf("no backup tables found for timestamp %q", stamp)
+	}
+	return backups, stamp, nil
+}
+
+// RestoreBackups puts the contents of the backup tables taken at stamp back into
+// their original tables and drops the backups afterwards.
+func RestoreBackups(ctx context.Context, db *DB, stamp string) error {
+	backups, stamp, err := listBackups(ctx, db, stamp)
+	if err != nil {
+		return err
+	}
+	logf(ctx, "Restoring %d tables from backup %s", len(backups), stamp)
+
+	for table, backup := range backups {
+		if _, err := db.ExecContext(ctx, "TRUNCATE TABLE "+quoteTable(table)); err != nil {
+			return fmt.Errorf("truncate error on %s: %w", table, err)
+		}
+		if err := copyTableRows(ctx, db, backup, table); err != nil {
+			return fmt.Errorf("restore error on %s: %w", table, err)
+		}
+		if _, err := db.ExecContext(ctx, "DROP TABLE "+quoteTable(backup)); err != nil {
+			return fmt.Errorf("cannot drop backup %s: %w", backup, err)
+		}
+		logf(ctx, "Restored table %s from %s", table, backup)
+	}
+	return nil
+}
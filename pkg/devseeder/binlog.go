@@ -0,0 +1,391 @@
+package devseeder
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	golog "github.com/siddontang/go-log/log"
+)
+
+// binlogReportInterval is how often a summary of the changes applied is logged
+const binlogReportInterval = time.Minute
+
+// BinlogPosition is a position in prod's binary log.
+type BinlogPosition struct {
+	File string
+	Pos  uint32
+}
+
+func (p BinlogPosition) String() string { return fmt.Sprintf("%s:%d", p.File, p.Pos) }
+
+// BinlogSource says how to connect to prod as a replica.
+type BinlogSource struct {
+	Addr     string // host:port
+	User     string
+	Password string
+	TLS      *tls.Config // nil for plain TCP
+
+	// ServerID identifies the connection to prod; it must differ from every
+	// real replica's server_id
+	ServerID uint32
+	// Flavor is mysql (default) or mariadb
+	Flavor string
+}
+
+// CurrentBinlogPosition returns prod's current binary log position. Take it
+// before a sync so following from it misses no change made during the copy.
+func CurrentBinlogPosition(ctx context.Context, db *DB) (BinlogPosition, error) {
+	rows, err := db.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		// MySQL 8.4 renamed it
+		var err2 error
+		if rows, err2 = db.QueryContext(ctx, "SHOW BINARY LOG STATUS"); err2 != nil {
+			return BinlogPosition{}, err
+		}
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return BinlogPosition{}, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return BinlogPosition{}, err
+		}
+		return BinlogPosition{}, errors.New("prod has binary logging disabled (log_bin=OFF)")
+	}
+	vals := make([]sql.RawBytes, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return BinlogPosition{}, err
+	}
+	pos, err := strconv.ParseUint(string(vals[1]), 10, 32)
+	if err != nil {
+		return BinlogPosition{}, fmt.Errorf("unexpected binlog position %q", vals[1])
+	}
+	return BinlogPosition{File: string(vals[0]), Pos: uint32(pos)}, rows.Err()
+}
+
+// followedTable is a table whose seeded rows are kept in step with prod
+type followedTable struct {
+	devTable string
+	ids      map[int64]bool
+	renames  map[string]string // { prodColumn : devColumn }
+	values   map[string]string // dev columns set by ColumnValues, left alone
+}
+
+// FollowBinlog tails prod's binary log from `from` and applies every change to
+// a row dev holds of the tables the configured subset copies: updates are
+// written to dev and deletes carried out, so the subset stays current without
+// full refreshes. Rows inserted in prod are not pulled in. It needs prod's
+// binlog_format=ROW with binlog_row_image=FULL, and REPLICATION SLAVE and
+// REPLICATION CLIENT privileges. It returns when ctx is cancelled.
+func (s *Seeder) FollowBinlog(ctx context.Context, src BinlogSource, from BinlogPosition) error {
+	if s.prod == nil {
+		return fmt.Errorf("following the binlog needs a prod database")
+	}
+	// Updated rows go through the same transforms as the seeded ones
+	ctx = contextWithTransforms(ctx, s.opts.Transforms)
+	return s.withDevSession(ctx, func(ctx context.Context) error {
+		var format, image, schema string
+		err := s.prod.QueryRowContext(ctx, "SELECT @@GLOBAL.binlog_format, @@GLOBAL.binlog_row_image, DATABASE()").Scan(&format, &image, &schema)
+		if err != nil {
+			return fmt.Errorf("reading binlog settings: %w", err)
+		}
+		if !strings.EqualFold(format, "ROW") || !strings.EqualFold(image, "FULL") {
+			return fmt.Errorf("prod logs binlog_format=%s, binlog_row_image=%s; following needs ROW and FULL", format, image)
+		}
+
+		allFks, err := FetchAllForeignKeys(ctx, s.prod)
+		if err != nil {
+			return fmt.Errorf("fetching all FKs: %w", err)
+		}
+		allFks, tables, devTableNames, err := normalizeTableNames(ctx, s.prod, s.dev, allFks, s.opts.Tables, s.opts.TableMap)
+		if err != nil {
+			return fmt.Errorf("normalizing table names: %w", err)
+		}
+		reachable := reachableTables(allFks, tables)
+		columnMap, err := normalizeColumnMap("column_map", s.opts.ColumnMap, reachable)
+		if err != nil {
+			return err
+		}
+		columnValues, err := normalizeColumnMap("column_values", s.opts.ColumnValues, reachable)
+		if err != nil {
+			return err
+		}
+		followed := make(map[string]*followedTable)
+		for _, table := range reachable {
+			devTable := SyncOptions{DevTableNames: devTableNames}.devTable(table)
+			ids, err := allIDs(ctx, s.dev, devTable)
+			if err != nil {
+				return fmt.Errorf("reading IDs of dev table %s: %w", devTable, err)
+			}
+			followed[table] = &followedTable{devTable: devTable, ids: ids, renames: columnMap[table], values: columnValues[table]}
+		}
+		return followBinlog(ctx, s.prod, s.dev, src, from, schema, followed)
+	})
+}
+
+func followBinlog(ctx context.Context, prodDB, devDB *DB, src BinlogSource, from BinlogPosition, schema string, followed map[string]*followedTable) error {
+	host, port, err := net.SplitHostPort(src.Addr)
+	if err != nil {
+		return fmt.Errorf("binlog address %q: %w", src.Addr, err)
+	}
+	portNum, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("binlog address %q: bad port", src.Addr)
+	}
+	flavor := src.Flavor
+	if flavor == "" {
+		flavor = gomysql.MySQLFlavor
+	}
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID:                src.ServerID,
+		Flavor:                  flavor,
+		Host:                    host,
+		Port:                    uint16(portNum),
+		User:                    src.User,
+		Password:                src.Password,
+		TLSConfig:               src.TLS,
+		ParseTime:               true,
+		TimestampStringLocation: time.UTC,
+		HeartbeatPeriod:         30 * time.Second,
+		// progress is logged here instead
+		Logger: golog.NewDefault(&golog.NullHandler{}),
+	})
+	defer syncer.Close()
+
+	streamer, err := syncer.StartSync(gomysql.Position{Name: from.File, Pos: from.Pos})
+	if err != nil {
+		return fmt.Errorf("starting binlog replication: %w", err)
+	}
+	logf(ctx, "Following prod binlog from %s for %d tables", from, len(followed))
+
+	pos := from
+	var updated, deleted int
+	lastReport := time.Now()
+	for {
+		ev, err := streamer.GetEvent(ctx)
+		if ctx.Err() != nil {
+			logf(ctx, "Stopped following the binlog at %s", pos)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading binlog at %s: %w", pos, err)
+		}
+		if ev.Header.LogPos > 0 {
+			pos.Pos = ev.Header.LogPos
+		}
+
+		switch e := ev.Event.(type) {
+		case *replication.RotateEvent:
+			pos = BinlogPosition{File: string(e.NextLogName), Pos: uint32(e.Position)}
+		case *replication.QueryEvent:
+			// DDL changes the column order the row events are matched against
+			if q := strings.ToUpper(strings.TrimSpace(string(e.Query))); strings.HasPrefix(q, "ALTER") || strings.HasPrefix(q, "CREATE") ||
+				strings.HasPrefix(q, "DROP") || strings.HasPrefix(q, "RENAME") {
+				prodDB.resetColumns()
+				devDB.resetColumns()
+			}
+		case *replication.RowsEvent:
+			if !strings.EqualFold(string(e.Table.Schema), schema) {
+				continue
+			}
+			t := followed[string(e.Table.Table)]
+			if t == nil {
+				continue
+			}
+			u, d, err := applyRowsEvent(ctx, prodDB, devDB, string(e.Table.Table), t, ev.Header.EventType, e.Rows)
+			if err != nil {
+				return fmt.Errorf("applying binlog event at %s to %s: %w", pos, t.devTable, err)
+			}
+			updated, deleted = updated+u, deleted+d
+		}
+
+		if time.Since(lastReport) >= binlogReportInterval && updated+deleted > 0 {
+			logEvent(ctx, fmt.Sprintf("Binlog: applied %d updates and %d deletes (at %s)", updated, deleted, pos),
+				"updated", updated, "deleted", deleted)
+			updated, deleted, lastReport = 0, 0, time.Now()
+		}
+	}
+}
+
+// applyRowsEvent writes the row images of one rows event to dev, for the rows
+// dev holds; it returns how many rows were updated and deleted
+func applyRowsEvent(ctx context.Context, prodDB, devDB *DB, table string, t *followedTable, typ replication.EventType, images [][]interface{}) (int, int, error) {
+	infos, err := prodDB.tableColumns(ctx, table)
+	if err != nil {
+		return 0, 0, err
+	}
+	idIdx := slices.IndexFunc(infos, func(c columnInfo) bool { return c.Name == idColumn })
+	if idIdx < 0 {
+		return 0, 0, nil
+	}
+	for _, img := range images {
+		if len(img) != len(infos) {
+			return 0, 0, fmt.Errorf("row image has %d columns, prod table has %d", len(img), len(infos))
+		}
+	}
+
+	updated, deleted := 0, 0
+	switch typ {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2,
+		replication.MARIADB_WRITE_ROWS_COMPRESSED_EVENT_V1:
+		// Only rows dev held before (deleted and inserted again during the copy)
+		for _, img := range images {
+			if id, ok := binlogID(img[idIdx]); ok && t.ids[id] {
+				if err := upsertBinlogRow(ctx, devDB, table, t, infos, img); err != nil {
+					return 0, 0, err
+				}
+				updated++
+			}
+		}
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2,
+		replication.MARIADB_UPDATE_ROWS_COMPRESSED_EVENT_V1:
+		// Images come in before/after pairs
+		for i := 0; i+1 < len(images); i += 2 {
+			before, after := images[i], images[i+1]
+			oldID, ok := binlogID(before[idIdx])
+			if !ok || !t.ids[oldID] {
+				continue
+			}
+			if newID, _ := binlogID(after[idIdx]); newID != oldID {
+				if err := deleteByID(ctx, devDB, t.devTable, oldID); err != nil {
+					return 0, 0, err
+				}
+				delete(t.ids, oldID)
+				t.ids[newID] = true
+			}
+			if err := upsertBinlogRow(ctx, devDB, table, t, infos, after); err != nil {
+				return 0, 0, err
+			}
+			updated++
+		}
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2,
+		replication.MARIADB_DELETE_ROWS_COMPRESSED_EVENT_V1:
+		for _, img := range images {
+			id, ok := binlogID(img[idIdx])
+			if !ok || !t.ids[id] {
+				continue
+			}
+			if err := deleteByID(ctx, devDB, t.devTable, id); err != nil {
+				return 0, 0, err
+			}
+			delete(t.ids, id)
+			deleted++
+		}
+	}
+	return updated, deleted, nil
+}
+
+// upsertBinlogRow writes a full row image to dev, leaving out generated columns
+// and columns dev does not have
+func upsertBinlogRow(ctx context.Context, devDB *DB, table string, t *followedTable, infos []columnInfo, img []interface{}) error {
+	devColumns, err := devDB.tableColumns(ctx, t.devTable)
+	if err != nil {
+		return err
+	}
+	var columns, updates []string
+	var args []interface{}
+	for i, c := range infos {
+		name := c.Name
+		if dev, ok := t.renames[name]; ok {
+			name = dev
+		}
+		if _, set := t.values[name]; set {
+			continue
+		}
+		if c.generated() || !slices.ContainsFunc(devColumns, func(d columnInfo) bool { return strings.EqualFold(d.Name, name) }) {
+			continue
+		}
+		columns = append(columns, name)
+		updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", QuoteIdent(name), QuoteIdent(name)))
+		args = append(args, binlogValue(img[i], c))
+	}
+	if err := applyTransforms(ctx, transformsFrom(ctx), table, columns, [][]interface{}{args}); err != nil {
+		return err
+	}
+	_, err = devDB.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+		quoteTable(t.devTable), quoteIdents(columns), placeholders(len(columns)), strings.Join(updates, ", ")), args...)
+	return err
+}
+
+func deleteByID(ctx context.Context, db *DB, table string, id int64) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", quoteTable(table), QuoteIdent(idColumn)), id)
+	return err
+}
+
+// binlogID returns the id column value of a row image
+func binlogID(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// binlogValue converts a value decoded from a row event into what the driver
+// sends for column c. Row events carry integers signed whatever the column's
+// signedness, ENUM values as 1-based indexes and SET values as bitmasks.
+func binlogValue(v interface{}, c columnInfo) interface{} {
+	if c.unsigned() {
+		switch n := v.(type) {
+		case int8:
+			return uint64(uint8(n))
+		case int16:
+			return uint64(uint16(n))
+		case int32:
+			if c.DataType == "mediumint" {
+				return uint64(uint32(n) & 0xFFFFFF)
+			}
+			return uint64(uint32(n))
+		case int64:
+			return uint64(n)
+		}
+	}
+	switch c.DataType {
+	case "enum":
+		if n, ok := v.(int64); ok {
+			if values := enumValues(c.ColumnType); n >= 1 && int(n) <= len(values) {
+				return values[n-1]
+			}
+			return ""
+		}
+	case "set":
+		if n, ok := v.(int64); ok {
+			var members []string
+			for i, m := range enumValues(c.ColumnType) {
+				if n&(1<<i) != 0 {
+					members = append(members, m)
+				}
+			}
+			return strings.Join(members, ",")
+		}
+	case "json":
+		if b, ok := v.([]byte); ok {
+			return string(b)
+		}
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// RowFunc is called with one row on its way into dev. It may change values in
+// place, but not the id; returning false leaves the row out, in which case rows
+// referencing it may be left without their parent.
+type RowFunc func(ctx context.Context, table string, columns []string, values []interface{}) (keep bool, err error)
+
+// WithOnRowFetched calls fn with every row read from prod for the copy, before any
+// remapping or transform, under the prod table's name and with the columns that
+// are written, by their dev names.
+func WithOnRowFetched(fn RowFunc) Option {
+	return func(s *Seeder) { s.opts.OnRowFetched = append(s.opts.OnRowFetched, fn) }
+}
+
+// WithBeforeInsert calls fn with every row just before it is inserted, with
+// the values and dev table name the INSERT uses.
+func WithBeforeInsert(fn RowFunc) Option {
+	return func(s *Seeder) { s.opts.BeforeInsert = append(s.opts.BeforeInsert, fn) }
+}
+
+// filterRows runs fns over every row and returns the rows they all keep, and
+// whether any was left out
+func filterRows(ctx context.Context, fns []RowFunc, table string, columns []string, rowsData [][]interface{}) ([][]interface{}, bool, error) {
+	if len(fns) == 0 {
+		return rowsData, false, nil
+	}
+	kept := rowsData[:0:0]
+	for _, row := range rowsData {
+		keep := true
+		for _, fn := range fns {
+			var err error
+			if keep, err = fn(ctx, table, columns, row); err != nil {
+				return nil, false, fmt.Errorf("row callback on %s, row %s: %w", table, rowID(columns, row), err)
+			}
+			if !keep {
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, row)
+		}
+	}
+	return kept, len(kept) < len(rowsData), nil
+}
+
+// rowIDs returns the ids of rowsData
+func rowIDs(columns []string, rowsData [][]interface{}) map[int64]bool {
+	ids := make(map[int64]bool, len(rowsData))
+	i := slices.Index(columns, idColumn)
+	if i < 0 {
+		return ids
+	}
+	for _, row := range rowsData {
+		if id, ok := int64Value(row[i]); ok {
+			ids[id] = true
+		}
+	}
+	return ids
+}
@@ -0,0 +1,119 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// lowerCaseTableNames reads the server's lower_case_table_names setting.
+// 0 means table names are case-sensitive; 1 and 2 mean they are compared case-insensitively.
+func lowerCaseTableNames(ctx context.Context, db *DB) (int, error) {
+	var v int
+	err := db.QueryRowContext(ctx, "SELECT @@lower_case_table_names").Scan(&v)
+	return v, err
+}
+
+// tableNameIndex resolves table names against the tables that actually exist on one server
+type tableNameIndex struct {
+	exact           map[string]bool
+	folded          map[string][]string // lower-cased name -> actual names
+	caseInsensitive bool
+}
+
+func newTableNameIndex(ctx context.Context, db *DB) (*tableNameIndex, error) {
+	lctn, err := lowerCaseTableNames(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read lower_case_table_names on %s: %w", db.Name, err)
+	}
+	filter, args := db.schemaFilter("table_schema")
+	names, err := listNames(ctx, db, `SELECT `+qualifiedName("table_schema", "table_name")+` FROM information_schema.tables WHERE `+filter, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &tableNameIndex{
+		exact:           make(map[string]bool, len(names)),
+		folded:          make(map[string][]string, len(names)),
+		caseInsensitive: lctn != 0,
+	}
+	for _, n := range names {
+		idx.exact[n] = true
+		idx.folded[strings.ToLower(n)] = append(idx.folded[strings.ToLower(n)], n)
+	}
+	return idx, nil
+}
+
+// resolve returns the server's spelling of name. On case-insensitive servers any
+// spelling matches; on case-sensitive ones a different-case match is only used
+// when it is the single candidate and there is no exact match.
+func (idx *tableNameIndex) resolve(name string) string {
+	if idx.exact[name] {
+		return name
+	}
+	candidates := idx.folded[strings.ToLower(name)]
+	if len(candidates) == 1 || (idx.caseInsensitive && len(candidates) > 0) {
+		return candidates[0]
+	}
+	return name
+}
+
+// normalizeTableNames makes table names comparable regardless of how each server's
+// lower_case_table_names is set. FK metadata and requested tables are rewritten to
+// prod's spelling, and the returned map gives dev's spelling wherever it differs,
+// including the tables tableMap renames. devDB may be nil when there is no dev
+// database (e.g. when dumping to a file).
+func normalizeTableNames(
+	ctx context.Context,
+	prodDB, devDB *DB,
+	allFks []ForeignKey,
+	requested map[string]int,
+	tableMap map[string]string,
+) ([]ForeignKey, map[string]int, map[string]string, error) {
+	prodIdx, err := newTableNameIndex(ctx, prodDB)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fks := make([]ForeignKey, len(allFks))
+	for i, fk := range allFks {
+		fk.FromTable = prodIdx.resolve(fk.FromTable)
+		fk.ToTable = prodIdx.resolve(fk.ToTable)
+		fks[i] = fk
+	}
+
+	tables := make(map[string]int, len(requested))
+	for name, limit := range requested {
+		resolved := prodIdx.resolve(name)
+		if resolved != name {
+			logf(ctx, "Table %s resolved to %s in prod", name, resolved)
+		}
+		// Spellings of the same table keep the highest limit, 0 (every row) being the highest
+		if prev, ok := tables[resolved]; ok && (prev == 0 || limit != 0 && prev > limit) {
+			limit = prev
+		}
+		tables[resolved] = limit
+	}
+
+	devNames := make(map[string]string)
+	if devDB == nil {
+		return fks, tables, devNames, nil
+	}
+	devIdx, err := newTableNameIndex(ctx, devDB)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for name := range prodIdx.exact {
+		if devName := devIdx.resolve(name); devName != name {
+			devNames[name] = devName
+		}
+	}
+	for prodName, devName := range tableMap {
+		prodName = prodIdx.resolve(prodName)
+		if !prodIdx.exact[prodName] {
+			return nil, nil, nil, fmt.Errorf("table_map: prod has no table %s", prodName)
+		}
+		devNames[prodName] = devIdx.resolve(devName)
+	}
+	return fks, tables, devNames, nil
+}
@@ -0,0 +1,71 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+)
+
+// narrowCharsets cannot hold everything utf8mb4 can (emoji, some CJK, ...)
+var narrowCharsets = map[string]bool{"latin1": true, "utf8mb3": true, "utf8": true, "ascii": true}
+
+// columnCharsets returns { "table.column" : charset } for the character columns of tables
+func columnCharsets(ctx context.Context, db *DB, tables []string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, t := range tables {
+		infos, err := db.tableColumns(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range infos {
+			if c.Charset != "" {
+				result[t+"."+c.Name] = c.Charset
+			}
+		}
+	}
+	return result, nil
+}
+
+// connectionCharset returns the charset the server uses for data sent on this connection
+func connectionCharset(ctx context.Context, db *DB) (string, error) {
+	var charset string
+	err := db.QueryRowContext(ctx, "SELECT @@character_set_connection").Scan(&charset)
+	return charset, err
+}
+
+// CheckCharsets warns when utf8mb4 data from prod would be squeezed through a
+// narrower charset, either in a dev column or in one of the connections.
+func CheckCharsets(ctx context.Context, prodDB, devDB *DB, tables []string) error {
+	prodCols, err := columnCharsets(ctx, prodDB, tables)
+	if err != nil {
+		return fmt.Errorf("cannot read prod column charsets: %w", err)
+	}
+	devCols, err := columnCharsets(ctx, devDB, tables)
+	if err != nil {
+		return fmt.Errorf("cannot read dev column charsets: %w", err)
+	}
+
+	usesUTF8MB4 := false
+	for col, prodCharset := range prodCols {
+		if prodCharset != "utf8mb4" {
+			continue
+		}
+		usesUTF8MB4 = true
+		if devCharset := devCols[col]; narrowCharsets[devCharset] {
+			logf(ctx, "Warning: %s is utf8mb4 in prod but %s in dev; some values may be mangled or rejected", col, devCharset)
+		}
+	}
+	if !usesUTF8MB4 {
+		return nil
+	}
+
+	for _, db := range []*DB{prodDB, devDB} {
+		charset, err := connectionCharset(ctx, db)
+		if err != nil {
+			return fmt.Errorf("cannot read %s connection charset: %w", db.Name, err)
+		}
+		if narrowCharsets[charset] {
+			logf(ctx, "Warning: the %s connection uses %s but prod data is utf8mb4; set force_utf8mb4 to fix the connection charset", db.Name, charset)
+		}
+	}
+	return nil
+}
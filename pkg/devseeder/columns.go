@@ -0,0 +1,122 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+// columnInfo is a column as information_schema.columns describes it
+type columnInfo struct {
+	Name       string
+	DataType   string // lower case, e.g. "varchar", "decimal", "json"
+	ColumnType string // full definition, e.g. "varbinary(16)" or "enum('a','b')"
+	Extra      string // e.g. "auto_increment" or "STORED GENERATED"
+	Charset    string // character_set_name; "" for non-character columns
+	MaxBytes   int64  // character_octet_length; 0 for non-string columns
+	Precision  int64  // numeric_precision and numeric_scale; 0 for non-numeric columns
+	Scale      int64
+}
+
+// generated reports a STORED/VIRTUAL generated column. Columns with an
+// expression default (extra = DEFAULT_GENERATED) are regular columns.
+func (c columnInfo) generated() bool {
+	extra := strings.ToUpper(c.Extra)
+	return strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED")
+}
+
+func (c columnInfo) autoIncrement() bool {
+	return strings.Contains(strings.ToLower(c.Extra), "auto_increment")
+}
+
+func (c columnInfo) unsigned() bool {
+	return strings.Contains(strings.ToLower(c.ColumnType), "unsigned")
+}
+
+// columnCache holds information_schema.columns for the whole schema of a DB (and
+// the schemas of WithSchemas, under "schema.table"). It
+// is loaded with one query on the first lookup, so the many per-table lookups
+// of a run (column lists, generated columns, types, charsets) cost nothing more.
+type columnCache struct {
+	mu     sync.Mutex
+	tables map[string][]columnInfo // nil until loaded
+	folded map[string][]columnInfo // by lower-cased table name
+}
+
+// tableColumns returns the columns of table in ordinal order, or none if the
+// table does not exist. Table names are matched exactly, then case-insensitively.
+func (db *DB) tableColumns(ctx context.Context, table string) ([]columnInfo, error) {
+	c := db.columns
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tables == nil {
+		if err := c.load(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	if cols, ok := c.tables[table]; ok {
+		return cols, nil
+	}
+	return c.folded[strings.ToLower(table)], nil
+}
+
+// resetColumns drops the cached columns, e.g. after tables were created or altered
+func (db *DB) resetColumns() {
+	db.columns.mu.Lock()
+	defer db.columns.mu.Unlock()
+	db.columns.tables, db.columns.folded = nil, nil
+	if db.metadata != nil {
+		db.metadata.reset()
+	}
+}
+
+func (c *columnCache) load(ctx context.Context, db *DB) error {
+	var tables map[string][]columnInfo
+	var err error
+	if db.metadata != nil {
+		tables, err = db.metadata.columns(ctx, db, func() (map[string][]columnInfo, error) { return queryColumns(ctx, db) })
+	} else {
+		tables, err = queryColumns(ctx, db)
+	}
+	if err != nil {
+		return err
+	}
+	folded := make(map[string][]columnInfo)
+	for table, cols := range tables {
+		folded[strings.ToLower(table)] = append(folded[strings.ToLower(table)], cols...)
+	}
+	c.tables, c.folded = tables, folded
+	return nil
+}
+
+// queryColumns reads the columns of every table from information_schema
+func queryColumns(ctx context.Context, db *DB) (map[string][]columnInfo, error) {
+	filter, args := db.schemaFilter("table_schema")
+	rows, err := db.QueryContext(ctx, `
+	SELECT `+qualifiedName("table_schema", "table_name")+`, column_name, data_type, column_type, extra, character_set_name,
+	       character_octet_length, numeric_precision, numeric_scale
+	FROM information_schema.columns
+	WHERE `+filter+`
+	ORDER BY table_schema, table_name, ordinal_position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string][]columnInfo)
+	for rows.Next() {
+		var table string
+		var col columnInfo
+		var charset sql.NullString
+		var maxBytes, precision, scale sql.NullInt64
+		if err := rows.Scan(&table, &col.Name, &col.DataType, &col.ColumnType, &col.Extra, &charset,
+			&maxBytes, &precision, &scale); err != nil {
+			return nil, err
+		}
+		col.DataType = strings.ToLower(col.DataType)
+		col.Charset, col.MaxBytes, col.Precision, col.Scale = charset.String, maxBytes.Int64, precision.Int64, scale.Int64
+		tables[table] = append(tables[table], col)
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,196 @@
+package devseeder
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression modes for dump artifacts.
+const (
+	CompressAuto = "auto" // pick from the file extension (.gz, .zst)
+	CompressNone = "none"
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// ageExt is the file suffix of age-encrypted artifacts.
+const ageExt = ".age"
+
+// compressionExt maps each compression mode to the file extension it produces.
+var compressionExt = map[string]string{
+	CompressNone: "",
+	CompressGzip: ".gz",
+	CompressZstd: ".zst",
+}
+
+// ResolveCompression turns CompressAuto into a concrete mode based on path's extension
+// (ignoring a trailing .age).
+func ResolveCompression(mode, path string) (string, error) {
+	path = strings.TrimSuffix(path, ageExt)
+	switch mode {
+	case "", CompressAuto:
+		switch {
+		case strings.HasSuffix(path, ".gz"):
+			return CompressGzip, nil
+		case strings.HasSuffix(path, ".zst"), strings.HasSuffix(path, ".zstd"):
+			return CompressZstd, nil
+		}
+		return CompressNone, nil
+	case CompressNone, CompressGzip, CompressZstd:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown compression %q (expected auto, none, gzip or zstd)", mode)
+}
+
+// ArtifactOptions controls how dump artifacts are written to disk.
+type ArtifactOptions struct {
+	// Compression mode, already resolved (never CompressAuto)
+	Compression string
+	// Encrypt to these age recipients when non-empty
+	Recipients []age.Recipient
+}
+
+// Ext returns the suffix appended to artifact file names, e.g. ".gz.age".
+func (o ArtifactOptions) Ext() string {
+	ext := compressionExt[o.Compression]
+	if len(o.Recipients) > 0 {
+		ext += ageExt
+	}
+	return ext
+}
+
+// layeredWriter writes through a stack of streams (compression, encryption)
+// and closes them innermost first so each one flushes into the next.
+type layeredWriter struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (w layeredWriter) Close() error {
+	var firstErr error
+	for _, c := range w.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// nopCloser keeps stdout open when the dump stream is closed.
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// CreateOutput opens path for writing ("-" is stdout), compressing and then
+// encrypting as opts asks. Closing the result flushes everything.
+func CreateOutput(path string, opts ArtifactOptions) (io.WriteCloser, error) {
+	var f io.WriteCloser = nopCloser{os.Stdout}
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+	}
+	w := layeredWriter{Writer: f, closers: []io.Closer{f}}
+
+	if len(opts.Recipients) > 0 {
+		ew, err := age.Encrypt(w.Writer, opts.Recipients...)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("cannot start encryption: %w", err)
+		}
+		w.Writer = ew
+		w.closers = append([]io.Closer{ew}, w.closers...)
+	}
+
+	var cw io.WriteCloser
+	switch opts.Compression {
+	case CompressGzip:
+		cw = gzip.NewWriter(w.Writer)
+	case CompressZstd:
+		zw, err := zstd.NewWriter(w.Writer)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		cw = zw
+	}
+	if cw != nil {
+		w.Writer = cw
+		w.closers = append([]io.Closer{cw}, w.closers...)
+	}
+	return w, nil
+}
+
+// layeredReader reads through a stack of streams (decryption, decompression)
+// and closes them outermost first.
+type layeredReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r layeredReader) Close() error {
+	var firstErr error
+	for _, c := range r.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// OpenInput opens an artifact written by CreateOutput, decrypting it with
+// identities when its name ends in .age and decompressing it as its extension
+// says (see ResolveCompression).
+func OpenInput(path string, identities []age.Identity) (io.ReadCloser, error) {
+	compression, err := ResolveCompression(CompressAuto, path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := layeredReader{Reader: f, closers: []io.Closer{f}}
+
+	if strings.HasSuffix(path, ageExt) {
+		if len(identities) == 0 {
+			r.Close()
+			return nil, fmt.Errorf("%s is encrypted; no age identity to decrypt it with", path)
+		}
+		dr, err := age.Decrypt(r.Reader, identities...)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("cannot decrypt %s: %w", path, err)
+		}
+		r.Reader = dr
+	}
+
+	switch compression {
+	case CompressGzip:
+		gr, err := gzip.NewReader(r.Reader)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.Reader = gr
+		r.closers = append([]io.Closer{gr}, r.closers...)
+	case CompressZstd:
+		zr, err := zstd.NewReader(r.Reader)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		rc := zr.IOReadCloser()
+		r.Reader = rc
+		r.closers = append([]io.Closer{rc}, r.closers...)
+	}
+	return r, nil
+}
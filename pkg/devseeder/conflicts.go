@@ -0,0 +1,173 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// What to do with a planned row whose id dev already has (see WithConflictStrategies)
+const (
+	ConflictSkip      = "skip"      // keep the dev row; children then reference it
+	ConflictOverwrite = "overwrite" // replace the dev row's values with prod's
+	ConflictRemap     = "remap"     // copy the row under a new id and rewrite its children's FKs
+	ConflictFail      = "fail"      // fail before writing the table, naming the clashing IDs
+)
+
+// conflictIDsShown is how many clashing IDs a ConflictFail error lists
+const conflictIDsShown = 10
+
+// WithConflictStrategies chooses per table what happens to planned rows whose id
+// dev already has: { tableName : ConflictSkip | ConflictOverwrite | ConflictRemap
+// | ConflictFail }. Other tables follow the write strategy; with the default
+// WriteInsert a clash fails the table's batch. Tables reset with
+// WithResetTables have no clashes.
+func WithConflictStrategies(strategies map[string]string) Option {
+	return func(s *Seeder) { s.opts.Conflicts = strategies }
+}
+
+// validConflictStrategies checks the configured strategy names
+func validConflictStrategies(strategies map[string]string) error {
+	for table, strategy := range strategies {
+		switch strategy {
+		case ConflictSkip, ConflictOverwrite, ConflictRemap, ConflictFail:
+		default:
+			return fmt.Errorf("conflicts: unknown strategy %q for %s (expected %s, %s, %s or %s)",
+				strategy, table, ConflictSkip, ConflictOverwrite, ConflictRemap, ConflictFail)
+		}
+	}
+	return nil
+}
+
+// insertMode returns how rows of table are inserted: plainly (""), skipping
+// clashes (ConflictSkip) or overwriting them (ConflictOverwrite)
+func (o SyncOptions) insertMode(table string) string {
+	switch o.Conflicts[table] {
+	case ConflictSkip:
+		return ConflictSkip
+	case ConflictOverwrite:
+		return ConflictOverwrite
+	case ConflictRemap, ConflictFail:
+		return ""
+	}
+	if o.WriteStrategy != WriteInsert {
+		return ConflictSkip
+	}
+	return ""
+}
+
+// resolveConflicts applies table's conflict strategy to the planned IDs before
+// anything is written to it. It returns the IDs left to copy and, for
+// ConflictRemap, the new id of every clashing row: past both dev's highest id and
+// every planned one.
+func resolveConflicts(ctx context.Context, devDB *DB, table, devTable string, idSet map[int64]bool, strategy string) (map[int64]bool, map[int64]int64, error) {
+	if strategy != ConflictSkip && strategy != ConflictRemap && strategy != ConflictFail {
+		return idSet, nil, nil
+	}
+	existing, err := existingIDs(ctx, devDB, devTable, idSet)
+	if err != nil {
+		return nil, nil, fmt.Errorf("looking up existing rows of dev %s: %w", devTable, err)
+	}
+	if len(existing) == 0 {
+		return idSet, nil, nil
+	}
+	clashing := make([]int64, 0, len(existing))
+	for id := range existing {
+		clashing = append(clashing, id)
+	}
+	slices.Sort(clashing)
+
+	switch strategy {
+	case ConflictFail:
+		shown := make([]string, 0, conflictIDsShown)
+		for _, id := range clashing[:min(len(clashing), conflictIDsShown)] {
+			shown = append(shown, fmt.Sprint(id))
+		}
+		more := ""
+		if len(clashing) > conflictIDsShown {
+			more = fmt.Sprintf(" and %d more", len(clashing)-conflictIDsShown)
+		}
+		return nil, nil, fmt.Errorf("table %s: %d planned rows already exist in dev (id %s%s)",
+			table, len(clashing), strings.Join(shown, ", "), more)
+	case ConflictSkip:
+		kept := make(map[int64]bool, len(idSet)-len(existing))
+		for id := range idSet {
+			if !existing[id] {
+				kept[id] = true
+			}
+		}
+		logf(ctx, "Table %s: %d planned rows are already in dev and are kept", table, len(existing))
+		return kept, nil, nil
+	}
+
+	var devMax sql.NullInt64
+	if err := devDB.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(%s) FROM %s", QuoteIdent(idColumn), quoteTable(devTable))).Scan(&devMax); err != nil {
+		return nil, nil, fmt.Errorf("reading highest id of dev %s: %w", devTable, err)
+	}
+	next := devMax.Int64
+	for id := range idSet {
+		next = max(next, id)
+	}
+	remap := make(map[int64]int64, len(clashing))
+	for _, id := range clashing {
+		next++
+		remap[id] = next
+	}
+	logf(ctx, "Table %s: %d planned rows clash with dev rows and are copied under new IDs from %d", table, len(clashing), remap[clashing[0]])
+	return idSet, remap, nil
+}
+
+// remapRows rewrites the id of remapped rows of table and the FK columns
+// pointing at remapped parent rows; parents maps a dev column to the table it references
+func remapRows(columns []string, rowsData [][]interface{}, table string, parents map[string]string, remapped map[string]map[int64]int64) {
+	for i, c := range columns {
+		target := parents[c]
+		if c == idColumn {
+			target = table
+		}
+		remap := remapped[target]
+		if len(remap) == 0 {
+			continue
+		}
+		for _, row := range rowsData {
+			if v, ok := int64Value(row[i]); ok {
+				if id, ok := remap[v]; ok {
+					row[i] = id
+				}
+			}
+		}
+	}
+}
+
+// copiedIDs returns the IDs a batch is written under after remapping
+func copiedIDs(batch map[int64]bool, remap map[int64]int64) map[int64]bool {
+	if len(remap) == 0 {
+		return batch
+	}
+	ids := make(map[int64]bool, len(batch))
+	for id := range batch {
+		if to, ok := remap[id]; ok {
+			id = to
+		}
+		ids[id] = true
+	}
+	return ids
+}
+
+// fkParents returns { devColumn : parent table } for table's foreign keys to the id of another table
+func fkParents(allFks []ForeignKey, table string, opts SyncOptions) map[string]string {
+	parents := make(map[string]string)
+	for _, fk := range allFks {
+		if fk.FromTable != table || fk.ToColumn != idColumn {
+			continue
+		}
+		col := fk.FromColumn
+		if dev, ok := opts.ColumnMap[table][col]; ok {
+			col = dev
+		}
+		parents[col] = fk.ToTable
+	}
+	return parents
+}
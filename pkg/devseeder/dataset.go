@@ -0,0 +1,76 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// WriteDataset saves the dev database into dir like WriteSnapshot and adds a
+// content checksum of every table with an id column to the manifest, so the
+// dataset can later be re-applied and proven identical (see ApplyDataset).
+func WriteDataset(ctx context.Context, db *DB, dir, version string) (*SnapshotManifest, error) {
+	m, err := WriteSnapshot(ctx, db, dir, version)
+	if err != nil {
+		return nil, err
+	}
+	m.Checksums, err = datasetChecksums(ctx, db, m.Order)
+	if err != nil {
+		return nil, err
+	}
+	return m, writeSnapshotManifest(dir, m)
+}
+
+// ApplyDataset restores the dataset in dir like RestoreSnapshot, though into any
+// database, and checks the restored tables against the checksums recorded when
+// it was written.
+func ApplyDataset(ctx context.Context, db *DB, dir string) error {
+	m, err := readCompleteSnapshot(dir)
+	if err != nil {
+		return err
+	}
+	if err := restoreSnapshotTables(ctx, db, dir, m); err != nil {
+		return err
+	}
+	sums, err := datasetChecksums(ctx, db, m.Order)
+	if err != nil {
+		return err
+	}
+	var mismatched []string
+	for table, want := range m.Checksums {
+		if sums[table] != want {
+			mismatched = append(mismatched, table)
+		}
+	}
+	if len(mismatched) > 0 {
+		slices.Sort(mismatched)
+		return fmt.Errorf("dataset %s: tables %s differ from the recorded checksums after applying it",
+			m.Name, strings.Join(mismatched, ", "))
+	}
+	logf(ctx, "Dataset %s applied; %d table checksums match", m.Name, len(m.Checksums))
+	return nil
+}
+
+// datasetChecksums returns { table : checksum } over all rows of the tables that
+// have an id column, hashing the same columns a copy would write
+func datasetChecksums(ctx context.Context, db *DB, tables []string) (map[string]string, error) {
+	sums := make(map[string]string, len(tables))
+	for _, table := range tables {
+		columns, err := copyableColumns(ctx, db, table)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(columns, idColumn) {
+			continue
+		}
+		ids, err := allIDs(ctx, db, table)
+		if err != nil {
+			return nil, fmt.Errorf("reading IDs of %s: %w", table, err)
+		}
+		if sums[table], err = tableChecksum(ctx, db, table, columns, ids); err != nil {
+			return nil, fmt.Errorf("checksum of %s: %w", table, err)
+		}
+	}
+	return sums, nil
+}
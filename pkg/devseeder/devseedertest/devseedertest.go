@@ -0,0 +1,158 @@
+// Package devseedertest starts a disposable MySQL container with testcontainers-go,
+// loads a schema and a DevSeeder subset into it, and hands back a ready *sql.DB for
+// integration tests:
+//
+//	func TestOrders(t *testing.T) {
+//		db := devseedertest.Seed(t,
+//			devseedertest.WithSchema("testdata/schema.sql"),
+//			devseedertest.WithArtifact("testdata/seed.sql.gz"),
+//		)
+//		...
+//	}
+package devseedertest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+)
+
+// DefaultImage is the MySQL image used unless WithImage says otherwise.
+const DefaultImage = "mysql:8.0"
+
+type config struct {
+	image      string
+	database   string
+	scripts    []string
+	source     *sql.DB
+	seederOpts []devseeder.Option
+}
+
+// Option configures the seeded container.
+type Option func(*config)
+
+// WithImage runs another MySQL-compatible image, e.g. "mysql:8.4" or "mariadb:11".
+func WithImage(image string) Option {
+	return func(c *config) { c.image = image }
+}
+
+// WithDatabase names the schema created in the container (default "devseeder").
+func WithDatabase(name string) Option {
+	return func(c *config) { c.database = name }
+}
+
+// WithSchema applies SQL files (.sql, .sql.gz, ...) when the container initializes,
+// in the given order and before any artifact.
+func WithSchema(files ...string) Option {
+	return func(c *config) { c.scripts = append(c.scripts, files...) }
+}
+
+// WithArtifact loads a cached `devseeder dump` artifact (plain or gzip-compressed SQL)
+// when the container initializes, after the schema files.
+func WithArtifact(path string) Option {
+	return func(c *config) { c.scripts = append(c.scripts, path) }
+}
+
+// WithSource seeds the container live from prod once it is up, creating any
+// tables the schema files did not. opts configure the Seeder (tables, partitions, ...).
+func WithSource(prod *sql.DB, opts ...devseeder.Option) Option {
+	return func(c *config) {
+		c.source = prod
+		c.seederOpts = opts
+	}
+}
+
+// Seed starts a seeded MySQL container for the duration of the test and returns
+// a connection to it. The container is removed when the test finishes.
+func Seed(tb testing.TB, opts ...Option) *sql.DB {
+	tb.Helper()
+	ctx := context.Background()
+
+	db, ctr, err := start(ctx, opts...)
+	testcontainers.CleanupContainer(tb, ctr)
+	if err != nil {
+		tb.Fatalf("devseedertest: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Start is Seed for callers without a testing.TB (e.g. TestMain). The returned
+// func closes the connection and removes the container.
+func Start(ctx context.Context, opts ...Option) (*sql.DB, func() error, error) {
+	db, ctr, err := start(ctx, opts...)
+	if err != nil {
+		testcontainers.TerminateContainer(ctr)
+		return nil, nil, err
+	}
+	return db, func() error {
+		db.Close()
+		return testcontainers.TerminateContainer(ctr)
+	}, nil
+}
+
+// start runs the container and seeds it. The container is returned even on error
+// so the caller can remove it.
+func start(ctx context.Context, opts ...Option) (*sql.DB, testcontainers.Container, error) {
+	c := config{image: DefaultImage, database: "devseeder"}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	// The entrypoint runs init scripts in name order, so number them to keep ours
+	var files []testcontainers.ContainerFile
+	for i, script := range c.scripts {
+		files = append(files, testcontainers.ContainerFile{
+			HostFilePath:      script,
+			ContainerFilePath: fmt.Sprintf("/docker-entrypoint-initdb.d/%03d-%s", i, filepath.Base(script)),
+			FileMode:          0o644,
+		})
+	}
+
+	ctr, err := mysql.Run(ctx, c.image,
+		mysql.WithDatabase(c.database),
+		testcontainers.CustomizeRequestOption(func(req *testcontainers.GenericContainerRequest) error {
+			req.Files = append(req.Files, files...)
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, ctr, fmt.Errorf("starting MySQL container: %w", err)
+	}
+
+	dsn, err := ctr.ConnectionString(ctx, "parseTime=true")
+	if err != nil {
+		return nil, ctr, err
+	}
+
+	if c.source != nil {
+		// The Seeder pins its dev handle to one connection; keep that off the test's pool
+		target, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return nil, ctr, err
+		}
+		seederOpts := append([]devseeder.Option{devseeder.WithCreateMissingTables()}, c.seederOpts...)
+		err = devseeder.New(c.source, target, seederOpts...).Sync(ctx)
+		target.Close()
+		if err != nil {
+			return nil, ctr, fmt.Errorf("seeding container: %w", err)
+		}
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, ctr, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, ctr, err
+	}
+	return db, ctr, nil
+}
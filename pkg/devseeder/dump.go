@@ -0,0 +1,232 @@
+package devseeder
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dumpBatchSize is the number of rows per INSERT statement in a dump
+const dumpBatchSize = 500
+
+// showCreateTable returns the CREATE TABLE statement for table
+func showCreateTable(ctx context.Context, db *DB, table string) (string, error) {
+	var name, ddl string
+	err := db.QueryRowContext(ctx, "SHOW CREATE TABLE "+quoteTable(table)).Scan(&name, &ddl)
+	return ddl, err
+}
+
+// rowBatches hands a table's rows to yield one fetch batch at a time, so only a
+// batch is held in memory however many rows the table has
+type rowBatches func(yield func(rowsData [][]interface{}) error) error
+
+// forEachPlanTable hands the planned rows of each table in prod to fn, in plan
+// order, as batches fetched when fn asks for them. Generated columns are left out
+// since no target can accept them.
+func forEachPlanTable(
+	ctx context.Context,
+	prodDB *DB,
+	plan *CopyPlan,
+	fn func(table string, columns []string, rows rowBatches) error,
+) error {
+	for _, table := range plan.Order {
+		n := plan.Rows(table)
+		if n == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		logEvent(ctx, fmt.Sprintf("Exporting %d rows from table %s", n, table), "table", table, "rows", n)
+
+		tableColumns, err := fetchColumns(ctx, prodDB, table)
+		if err != nil {
+			return err
+		}
+		generated, err := fetchGeneratedColumns(ctx, prodDB, table)
+		if err != nil {
+			return fmt.Errorf("fetchGeneratedColumns error on %s: %w", table, err)
+		}
+		keep := func(c string) bool { return !generated[c] }
+		columns, _, _ := projectColumns(tableColumns, nil, keep)
+
+		rows := func(yield func(rowsData [][]interface{}) error) error {
+			next := plan.batches(prodDB, table)
+			for {
+				batch, err := next(ctx)
+				if err != nil {
+					return fmt.Errorf("reading the IDs of %s: %w", table, err)
+				}
+				if len(batch) == 0 {
+					return nil
+				}
+				rowsData, fetched, err := fetchRowsByIDs(ctx, prodDB, table, batch)
+				if err != nil {
+					return fmt.Errorf("fetchRowsByIDs error: %w", err)
+				}
+				_, rowsData, _ = projectColumns(fetched, rowsData, keep)
+				if err := applyTransforms(ctx, transformsFrom(ctx), table, columns, rowsData); err != nil {
+					return err
+				}
+				if err := yield(rowsData); err != nil {
+					return err
+				}
+			}
+		}
+		if err := fn(table, columns, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpPlan writes the rows of a plan as an SQL script (CREATE TABLE IF NOT EXISTS +
+// INSERT statements, guarded by foreign_key_checks) that any MySQL client can apply.
+func DumpPlan(ctx context.Context, prodDB *DB, plan *CopyPlan, out io.Writer) error {
+	w := bufio.NewWriter(out)
+
+	// No timestamps: the same plan always dumps to the same bytes
+	fmt.Fprintln(w, "-- DevSeeder dump")
+	fmt.Fprintln(w, "SET NAMES utf8mb4;")
+	fmt.Fprintln(w, "SET TIME_ZONE = '+00:00';")
+	fmt.Fprintln(w, "SET @OLD_FOREIGN_KEY_CHECKS = @@FOREIGN_KEY_CHECKS;")
+	fmt.Fprintln(w, "SET FOREIGN_KEY_CHECKS = 0;")
+
+	err := forEachPlanTable(ctx, prodDB, plan, func(table string, columns []string, rows rowBatches) error {
+		ddl, err := showCreateTable(ctx, prodDB, table)
+		if err != nil {
+			return fmt.Errorf("SHOW CREATE TABLE %s: %w", table, err)
+		}
+		types, err := fetchColumnTypes(ctx, prodDB, table)
+		if err != nil {
+			return fmt.Errorf("fetchColumnTypes error on %s: %w", table, err)
+		}
+		fmt.Fprintf(w, "\n--\n-- Table %s\n--\n", quoteTable(table))
+		fmt.Fprintf(w, "%s;\n", strings.Replace(ddl, "CREATE TABLE", "CREATE TABLE IF NOT EXISTS", 1))
+		return rows(func(rowsData [][]interface{}) error {
+			writeInserts(w, table, columns, rowsData, types)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nSET FOREIGN_KEY_CHECKS = @OLD_FOREIGN_KEY_CHECKS;")
+	return w.Flush()
+}
+
+// writeInserts writes multi-row INSERT statements for rowsData, dumpBatchSize rows at a time
+func writeInserts(w io.Writer, table string, columns []string, rowsData [][]interface{}, types map[string]columnInfo) {
+	head := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", quoteTable(table), quoteIdents(columns))
+	for start := 0; start < len(rowsData); start += dumpBatchSize {
+		end := min(start+dumpBatchSize, len(rowsData))
+		io.WriteString(w, head)
+		for r, row := range rowsData[start:end] {
+			vals := make([]string, len(row))
+			for i, v := range row {
+				vals[i] = valueLiteral(v, types[columns[i]].DataType)
+			}
+			sep := ",\n"
+			if start+r == end-1 {
+				sep = ";\n"
+			}
+			io.WriteString(w, "("+strings.Join(vals, ",")+")"+sep)
+		}
+	}
+}
+
+// DumpPlanMysqldump writes the rows of a plan in the layout mysqldump produces
+// (--complete-insert): version-guarded session settings, DROP/CREATE TABLE, LOCK TABLES
+// and DISABLE KEYS around extended inserts, so existing restore tooling accepts it.
+func DumpPlanMysqldump(ctx context.Context, prodDB *DB, plan *CopyPlan, out io.Writer) error {
+	w := bufio.NewWriter(out)
+
+	var version, schema string
+	if err := prodDB.QueryRowContext(ctx, "SELECT VERSION(), DATABASE()").Scan(&version, &schema); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "-- MySQL dump (DevSeeder)\n--\n-- Host: %s    Database: %s\n", prodDB.Name, schema)
+	fmt.Fprintln(w, "-- ------------------------------------------------------")
+	fmt.Fprintf(w, "-- Server version\t%s\n\n", version)
+	fmt.Fprint(w, `/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+/*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS */;
+/*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTION */;
+/*!50503 SET NAMES utf8mb4 */;
+/*!40103 SET @OLD_TIME_ZONE=@@TIME_ZONE */;
+/*!40103 SET TIME_ZONE='+00:00' */;
+/*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0 */;
+/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;
+/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;
+/*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;
+`)
+
+	err := forEachPlanTable(ctx, prodDB, plan, func(table string, columns []string, rows rowBatches) error {
+		ddl, err := showCreateTable(ctx, prodDB, table)
+		if err != nil {
+			return fmt.Errorf("SHOW CREATE TABLE %s: %w", table, err)
+		}
+		types, err := fetchColumnTypes(ctx, prodDB, table)
+		if err != nil {
+			return fmt.Errorf("fetchColumnTypes error on %s: %w", table, err)
+		}
+		t := quoteTable(table)
+
+		fmt.Fprintf(w, "\n--\n-- Table structure for table %s\n--\n\n", t)
+		fmt.Fprintf(w, "DROP TABLE IF EXISTS %s;\n", t)
+		fmt.Fprintln(w, "/*!40101 SET @saved_cs_client     = @@character_set_client */;")
+		fmt.Fprintln(w, "/*!50503 SET character_set_client = utf8mb4 */;")
+		fmt.Fprintf(w, "%s;\n", ddl)
+		fmt.Fprintln(w, "/*!40101 SET character_set_client = @saved_cs_client */;")
+
+		fmt.Fprintf(w, "\n--\n-- Dumping data for table %s\n--\n\n", t)
+		fmt.Fprintf(w, "LOCK TABLES %s WRITE;\n", t)
+		fmt.Fprintf(w, "/*!40000 ALTER TABLE %s DISABLE KEYS */;\n", t)
+		if err := rows(func(rowsData [][]interface{}) error {
+			writeExtendedInserts(w, table, columns, rowsData, types)
+			return nil
+		}); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "/*!40000 ALTER TABLE %s ENABLE KEYS */;\n", t)
+		fmt.Fprintln(w, "UNLOCK TABLES;")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(w, `
+/*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;
+/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
+/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;
+/*!40014 SET UNIQUE_CHECKS=@OLD_UNIQUE_CHECKS */;
+/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
+/*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS */;
+/*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION */;
+/*!40111 SET SQL_NOTES=@OLD_SQL_NOTES */;
+`)
+	fmt.Fprintln(w, "\n-- Dump completed")
+	return w.Flush()
+}
+
+// writeExtendedInserts writes mysqldump-style single-line extended INSERTs,
+// starting a new statement every dumpBatchSize rows
+func writeExtendedInserts(w io.Writer, table string, columns []string, rowsData [][]interface{}, types map[string]columnInfo) {
+	head := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", quoteTable(table), quoteIdents(columns))
+	for start := 0; start < len(rowsData); start += dumpBatchSize {
+		end := min(start+dumpBatchSize, len(rowsData))
+		tuples := make([]string, 0, end-start)
+		for _, row := range rowsData[start:end] {
+			vals := make([]string, len(row))
+			for i, v := range row {
+				vals[i] = valueLiteral(v, types[columns[i]].DataType)
+			}
+			tuples = append(tuples, "("+strings.Join(vals, ",")+")")
+		}
+		io.WriteString(w, head+strings.Join(tuples, ",")+";\n")
+	}
+}
@@ -0,0 +1,46 @@
+package devseeder
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDumpZeroDatetime(t *testing.T) {
+	types := map[string]columnInfo{
+		"id":         {Name: "id", DataType: "bigint"},
+		"deleted_at": {Name: "deleted_at", DataType: "datetime"},
+	}
+	columns := []string{"id", "deleted_at"}
+	// With parseTime the driver scans 0000-00-00 00:00:00 as the zero time.Time
+	rows := [][]interface{}{
+		{int64(1), time.Time{}},
+		{int64(2), time.Date(2024, 2, 29, 13, 5, 0, 0, time.UTC)},
+	}
+	for name, write := range map[string]func(*strings.Builder){
+		"sql":       func(w *strings.Builder) { writeInserts(w, "orders", columns, rows, types) },
+		"mysqldump": func(w *strings.Builder) { writeExtendedInserts(w, "orders", columns, rows, types) },
+	} {
+		var b strings.Builder
+		write(&b)
+		out := b.String()
+		if !strings.Contains(out, "(1,'0000-00-00 00:00:00')") {
+			t.Errorf("%s: zero datetime not written as 0000-00-00 00:00:00:\n%s", name, out)
+		}
+		if strings.Contains(out, "0001-01-01") {
+			t.Errorf("%s: zero datetime written as a real date:\n%s", name, out)
+		}
+		if !strings.Contains(out, "(2,'2024-02-29 13:05:00')") {
+			t.Errorf("%s: datetime not written as is:\n%s", name, out)
+		}
+	}
+}
+
+func TestExportZeroDatetime(t *testing.T) {
+	if got := jsonValue(time.Time{}, "datetime"); got != "0000-00-00 00:00:00" {
+		t.Errorf("jsonValue of a zero datetime = %v, want 0000-00-00 00:00:00", got)
+	}
+	if got := fixtureValue(time.Time{}, "datetime").Value; got != "0000-00-00 00:00:00" {
+		t.Errorf("fixture value of a zero datetime = %v, want 0000-00-00 00:00:00", got)
+	}
+}
@@ -0,0 +1,120 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// WithEdgeLimits caps how many parent IDs a single FK may pull into the plan:
+// { childTable : { fkColumn : maxParentIDs } }, e.g. at most 10000 suppliers
+// through products.supplier_id, so one hub relationship cannot expand the subset
+// to its whole parent table. Parent rows already in the plan for other reasons
+// do not count. Rows whose parent was left out keep referencing it, so a
+// warning names every edge that hit its cap.
+func WithEdgeLimits(limits map[string]map[string]int) Option {
+	return func(s *Seeder) { s.opts.EdgeLimits = limits }
+}
+
+// edgeLimits applies WithEdgeLimits during the BFS of BuildCopyPlan
+type edgeLimits struct {
+	limits map[string]map[string]int
+	pulled map[string]map[int64]bool // "child.column" -> parent IDs the edge added
+	capped map[string]map[int64]bool // "child.column" -> parent IDs left out
+	edges  map[string]FkEdge
+}
+
+func newEdgeLimits(limits map[string]map[string]int, allFks []ForeignKey) *edgeLimits {
+	var tables []string
+	for _, fk := range allFks {
+		tables = append(tables, fk.FromTable, fk.ToTable)
+	}
+	return &edgeLimits{limits: normalizeTableKeys(limits, tables), pulled: make(map[string]map[int64]bool),
+		capped: make(map[string]map[int64]bool), edges: make(map[string]FkEdge)}
+}
+
+// limit returns the cap of the edge from childTable (0 = none)
+func (l *edgeLimits) limit(childTable string, edge FkEdge) int {
+	for column, n := range l.limits[childTable] {
+		if strings.EqualFold(column, edge.ChildColumn) {
+			return n
+		}
+	}
+	return 0
+}
+
+// add puts the parent IDs the edge references into parentSet, in ascending
+// order up to the edge's cap, and returns how many were new
+func (l *edgeLimits) add(childTable string, edge FkEdge, parentIDs, parentSet map[int64]bool) int {
+	limit := l.limit(childTable, edge)
+	added := 0
+	if limit <= 0 {
+		for pid := range parentIDs {
+			if !parentSet[pid] {
+				parentSet[pid] = true
+				added++
+			}
+		}
+		return added
+	}
+
+	key := childTable + "." + edge.ChildColumn
+	if l.pulled[key] == nil {
+		l.pulled[key], l.capped[key] = make(map[int64]bool), make(map[int64]bool)
+		l.edges[key] = edge
+	}
+	ids := make([]int64, 0, len(parentIDs))
+	for pid := range parentIDs {
+		ids = append(ids, pid)
+	}
+	slices.Sort(ids)
+	for _, pid := range ids {
+		switch {
+		case parentSet[pid]:
+		case len(l.pulled[key]) >= limit:
+			l.capped[key][pid] = true
+		default:
+			l.pulled[key][pid] = true
+			parentSet[pid] = true
+			added++
+		}
+	}
+	return added
+}
+
+// warn names every edge that hit its cap and the parent rows its child rows
+// reference but the plan lacks
+func (l *edgeLimits) warn(ctx context.Context, rowSets map[string]map[int64]bool) {
+	keys := make([]string, 0, len(l.capped))
+	for key := range l.capped {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		edge := l.edges[key]
+		missing := 0
+		for pid := range l.capped[key] {
+			if !rowSets[edge.ParentTable][pid] {
+				missing++
+			}
+		}
+		if missing == 0 {
+			continue
+		}
+		logf(ctx, "Warning: edge %s hit its limit of %d parent IDs; %d referenced %s rows are left out, and rows pointing at them will dangle in dev",
+			key, len(l.pulled[key]), missing, edge.ParentTable)
+	}
+}
+
+// String describes the limits for the plan cache key
+func (l *edgeLimits) String() string {
+	var parts []string
+	for table, columns := range l.limits {
+		for column, n := range columns {
+			parts = append(parts, fmt.Sprintf("%s.%s=%d", table, column, n))
+		}
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,47 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TableEstimate is the expected size of one table's share of a plan
+type TableEstimate struct {
+	Table string
+	Rows  int
+	Bytes int64 // Rows times prod's average row length
+}
+
+// Estimate is the expected size of a plan
+type Estimate struct {
+	Tables []TableEstimate // in copy order
+	Rows   int
+	Bytes  int64
+}
+
+// EstimatePlan sizes plan from prod's information_schema statistics. Row counts
+// are exact; bytes use AVG_ROW_LENGTH, which InnoDB only samples, so treat them
+// as a rough guide.
+func EstimatePlan(ctx context.Context, prodDB *DB, plan *CopyPlan) (*Estimate, error) {
+	est := &Estimate{}
+	for _, table := range plan.Order {
+		rows := plan.Rows(table)
+		if rows == 0 {
+			continue
+		}
+		var avgRowLength sql.NullInt64
+		err := prodDB.QueryRowContext(ctx, `
+		SELECT avg_row_length
+		FROM information_schema.tables
+		WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ?`, tableArgs(table)...).Scan(&avgRowLength)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("reading statistics of %s: %w", table, err)
+		}
+		t := TableEstimate{Table: table, Rows: rows, Bytes: int64(rows) * avgRowLength.Int64}
+		est.Tables = append(est.Tables, t)
+		est.Rows += t.Rows
+		est.Bytes += t.Bytes
+	}
+	return est, nil
+}
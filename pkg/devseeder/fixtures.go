@@ -0,0 +1,247 @@
+package devseeder
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fixtureValue converts a value scanned from the driver into the form
+// go-testfixtures expects in YAML: plain scalars, with times as "YYYY-MM-DD hh:mm:ss".
+// BIT columns are written as integers, other binary columns as !!binary (base64),
+// which decodes back to the exact bytes.
+func fixtureValue(v interface{}, dataType string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode}
+	switch val := v.(type) {
+	case nil:
+		node.Tag, node.Value = "!!null", "null"
+	case []byte:
+		if dataType == "bit" {
+			node.Tag, node.Value = "!!int", strconv.FormatUint(bitValue(val), 10)
+			break
+		}
+		if isBinaryType(dataType) {
+			node.Tag, node.Value = "!!binary", base64.StdEncoding.EncodeToString(val)
+			break
+		}
+		node.Tag, node.Value = "!!str", string(val)
+	case string:
+		node.Tag, node.Value = "!!str", val
+	case time.Time:
+		node.Tag, node.Value = "!!str", mysqlTime(val, "2006-01-02 15:04:05")
+	case bool:
+		node.Tag, node.Value = "!!bool", fmt.Sprint(val)
+	case int64, uint64:
+		node.Tag, node.Value = "!!int", fmt.Sprint(val)
+	case float32, float64:
+		node.Tag, node.Value = "!!float", fmt.Sprint(val)
+	default:
+		node.Tag, node.Value = "!!str", fmt.Sprint(val)
+	}
+	return node
+}
+
+// ExportFixtures writes one <table>.yml per table into dir, in the go-testfixtures
+// format (a list of rows, each a map of column -> value). Since testfixtures loads
+// files in the order it is given, order.txt lists the files parents-first; pass them
+// in that order with testfixtures.Files(...).
+func ExportFixtures(ctx context.Context, prodDB *DB, plan *CopyPlan, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	var order []string
+	err := forEachPlanTable(ctx, prodDB, plan, func(table string, columns []string, rows rowBatches) error {
+		types, err := fetchColumnTypes(ctx, prodDB, table)
+		if err != nil {
+			return fmt.Errorf("fetchColumnTypes error on %s: %w", table, err)
+		}
+		name := table + ".yml"
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// Block sequences written one after the other read as a single list
+		w := bufio.NewWriter(f)
+		empty := true
+		err = rows(func(rowsData [][]interface{}) error {
+			if len(rowsData) == 0 {
+				return nil
+			}
+			empty = false
+			doc := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, row := range rowsData {
+				m := &yaml.Node{Kind: yaml.MappingNode}
+				for i, c := range columns {
+					m.Content = append(m.Content,
+						&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: c},
+						fixtureValue(row[i], types[c].DataType),
+					)
+				}
+				doc.Content = append(doc.Content, m)
+			}
+			data, err := yaml.Marshal(doc)
+			if err != nil {
+				return fmt.Errorf("encoding fixtures for %s: %w", table, err)
+			}
+			_, err = w.Write(data)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if empty {
+			w.WriteString("[]\n")
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		order = append(order, name)
+		return f.Close()
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "order.txt"), []byte(strings.Join(order, "\n")+"\n"), 0o644)
+}
+
+// WithFixtures applies the static seed files in dir to dev after the prod subset
+// (see ApplyFixtures).
+func WithFixtures(dir string) Option {
+	return func(s *Seeder) { s.opts.FixturesDir = dir }
+}
+
+// ApplyFixtures loads the checked-in seed files in dir (feature flags, test
+// accounts, ...) into dev. Every <table>.yml or <table>.yaml, in the format
+// ExportFixtures writes, is applied first, parents before children; then every
+// *.sql file, in name order. File names are dev table names. Rows are upserted on
+// their keys, so fixtures take precedence over copied rows and applying them again
+// changes nothing.
+func ApplyFixtures(ctx context.Context, devDB *DB, allFks []ForeignKey, dir string, opts SyncOptions) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	prodNames := make(map[string]string, len(opts.DevTableNames))
+	for prod, dev := range opts.DevTableNames {
+		prodNames[dev] = prod
+	}
+	files := make(map[string]string) // prod table -> file
+	var tables, scripts []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			continue
+		}
+		switch ext := filepath.Ext(name); ext {
+		case ".yml", ".yaml":
+			table := strings.TrimSuffix(name, ext)
+			if prod, ok := prodNames[table]; ok {
+				table = prod
+			}
+			if _, dup := files[table]; dup {
+				return fmt.Errorf("fixtures for %s are in both %s and %s", table, files[table], name)
+			}
+			files[table] = name
+			tables = append(tables, table)
+		case ".sql":
+			scripts = append(scripts, name)
+		}
+	}
+	order, err := partialTopoSort(allFks, tables)
+	if err != nil {
+		return fmt.Errorf("ordering fixtures: %w", err)
+	}
+
+	for _, table := range order {
+		if err := applyFixtureFile(ctx, devDB, opts.devTable(table), filepath.Join(dir, files[table]), opts.Undo); err != nil {
+			return fmt.Errorf("fixtures %s: %w", files[table], err)
+		}
+	}
+	// os.ReadDir sorts by name
+	for _, name := range scripts {
+		if err := applySQLFile(ctx, devDB, filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("fixtures %s: %w", name, err)
+		}
+		logEvent(ctx, fmt.Sprintf("Applied fixtures %s", name), "file", name)
+	}
+	return nil
+}
+
+// applyFixtureFile upserts the rows of one YAML fixture file into table
+func applyFixtureFile(ctx context.Context, devDB *DB, table, path string, undo *UndoLog) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var rows []map[string]interface{}
+	if err := yaml.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+
+	// Rows new to dev go to the undo log; ones fixtures overwrite were there before
+	ids := make(map[int64]bool)
+	for _, row := range rows {
+		if id, ok := row[idColumn].(int); ok {
+			ids[int64(id)] = true
+		}
+	}
+	existing, err := existingIDs(ctx, devDB, table, ids)
+	if err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		columns := make([]string, 0, len(row))
+		for c := range row {
+			columns = append(columns, c)
+		}
+		slices.Sort(columns)
+		values := make([]interface{}, len(columns))
+		for j, c := range columns {
+			if values[j], err = fixtureArg(row[c]); err != nil {
+				return fmt.Errorf("row %d, column %s: %w", i+1, c, err)
+			}
+		}
+		if err := insertRows(ctx, devDB, table, columns, [][]interface{}{values}, nil, ConflictOverwrite); err != nil {
+			return fmt.Errorf("row %d: %w", i+1, err)
+		}
+	}
+	for id := range existing {
+		delete(ids, id)
+	}
+	undo.Record(table, ids)
+	if len(ids) > 0 {
+		if err := syncAutoIncrement(ctx, devDB, table); err != nil {
+			return fmt.Errorf("syncAutoIncrement error on %s: %w", table, err)
+		}
+	}
+	logEvent(ctx, fmt.Sprintf("Applied fixtures to %s: %d rows", table, len(rows)), "table", table, "rows", len(rows))
+	return nil
+}
+
+// fixtureArg converts a value decoded from a fixture file to what the driver
+// sends: !!binary values arrive decoded, nested maps and lists become JSON
+func fixtureArg(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05.999999"), nil
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(val)
+		return string(b), err
+	}
+	return v, nil
+}
@@ -0,0 +1,195 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// How dev's foreign_key_checks are handled during a sync (see WithFKChecks)
+const (
+	FKChecksDeferred = "deferred" // the default: left on; nullable FKs are written as NULL and set once every table is copied
+	FKChecksOn       = "on"       // left on: a row referencing a parent dev does not have yet fails the insert
+	FKChecksOff      = "off"      // disabled for the run: fastest, but nothing stops dangling references
+)
+
+// WithFKChecks selects how dev's foreign key checks are handled during Sync
+// (FKChecksDeferred, the default, FKChecksOn or FKChecksOff). The CLI's
+// disable_fk_checks: true stands for FKChecksOff. With checks on,
+// parents are copied before their children; only nullable FKs and
+// self-references can point at rows copied later, which FKChecksDeferred fills
+// in afterwards. Values whose parent row is not in dev stay NULL. Tables reset
+// with WithResetTables are truncated with checks briefly off, as TRUNCATE
+// refuses tables other tables reference.
+func WithFKChecks(mode string) Option {
+	return func(s *Seeder) { s.opts.FKChecks = mode }
+}
+
+// validFKChecks checks the configured mode
+func validFKChecks(mode string) error {
+	switch mode {
+	case "", FKChecksOff, FKChecksOn, FKChecksDeferred:
+		return nil
+	}
+	return fmt.Errorf("fk_checks: unknown mode %q (expected %s, %s or %s)", mode, FKChecksOff, FKChecksOn, FKChecksDeferred)
+}
+
+// fkChecks returns the foreign key check mode, FKChecksDeferred when unset
+func (o SyncOptions) fkChecks() string {
+	if o.FKChecks == "" {
+		return FKChecksDeferred
+	}
+	return o.FKChecks
+}
+
+// fkChecksOff reports whether the sync runs with dev's foreign key checks disabled
+func (o SyncOptions) fkChecksOff() bool {
+	return o.fkChecks() == FKChecksOff
+}
+
+// deferredColumn is a nullable FK column of a copied table whose values are set
+// after the copy
+type deferredColumn struct {
+	table, devTable, column string
+	index                   int    // of column in the inserted rows
+	parent, parentColumn    string // dev names
+	values                  map[int64]interface{}
+}
+
+// deferredColumns returns the nullable FK columns of table among the inserted
+// columns, when opts asks for deferred checks
+func deferredColumns(allFks []ForeignKey, table string, columns []string, opts SyncOptions) []*deferredColumn {
+	if opts.fkChecks() != FKChecksDeferred || !slices.Contains(columns, idColumn) {
+		return nil
+	}
+	var cols []*deferredColumn
+	for _, fk := range allFks {
+		if fk.FromTable != table || !fk.IsNullable {
+			continue
+		}
+		col := fk.FromColumn
+		if dev, ok := opts.ColumnMap[table][col]; ok {
+			col = dev
+		}
+		i := slices.Index(columns, col)
+		if i < 0 {
+			continue
+		}
+		parentColumn := fk.ToColumn
+		if dev, ok := opts.ColumnMap[fk.ToTable][parentColumn]; ok {
+			parentColumn = dev
+		}
+		cols = append(cols, &deferredColumn{table: table, devTable: opts.devTable(table), column: col, index: i,
+			parent: opts.devTable(fk.ToTable), parentColumn: parentColumn, values: make(map[int64]interface{})})
+	}
+	return cols
+}
+
+// hold moves the column's values out of rowsData, leaving NULL, except those
+// pointing at a row of a parent table copied already (copied tells)
+func (d *deferredColumn) hold(columns []string, rowsData [][]interface{}, copied func(devTable string, id int64) bool) {
+	idIndex := slices.Index(columns, idColumn)
+	for _, row := range rowsData {
+		v := row[d.index]
+		if v == nil {
+			continue
+		}
+		if n, ok := int64Value(v); ok && d.parentColumn == idColumn && copied(d.parent, n) {
+			continue
+		}
+		id, ok := int64Value(row[idIndex])
+		if !ok {
+			continue
+		}
+		d.values[id] = v
+		row[d.index] = nil
+	}
+}
+
+// applyDeferredColumns sets the held FK values whose parent row is in dev
+func applyDeferredColumns(ctx context.Context, devDB *DB, cols []*deferredColumn) error {
+	for _, d := range cols {
+		if len(d.values) == 0 {
+			continue
+		}
+		existing, err := existingValues(ctx, devDB, d.parent, d.parentColumn, d.values)
+		if err != nil {
+			return fmt.Errorf("looking up %s.%s: %w", d.parent, d.parentColumn, err)
+		}
+		ids := make([]int64, 0, len(d.values))
+		missing := 0
+		for id, v := range d.values {
+			if existing[textValue(v)] {
+				ids = append(ids, id)
+			} else {
+				missing++
+			}
+		}
+		slices.Sort(ids)
+		for start := 0; start < len(ids); start += copyBatchSize {
+			batch := ids[start:min(start+copyBatchSize, len(ids))]
+			args := make([]interface{}, 0, 3*len(batch))
+			for _, id := range batch {
+				args = append(args, id, d.values[id])
+			}
+			for _, id := range batch {
+				args = append(args, id)
+			}
+			q := fmt.Sprintf("UPDATE %s SET %s = CASE %s %s END WHERE %s IN (%s)",
+				quoteTable(d.devTable), QuoteIdent(d.column), QuoteIdent(idColumn),
+				strings.TrimSpace(strings.Repeat("WHEN ? THEN ? ", len(batch))), QuoteIdent(idColumn), placeholders(len(batch)))
+			if _, err := devDB.ExecContext(ctx, q, args...); err != nil {
+				return fmt.Errorf("setting %s.%s: %w", d.devTable, d.column, err)
+			}
+		}
+		logf(ctx, "Table %s: set %s on %d rows after the copy", d.table, d.column, len(ids))
+		if missing > 0 {
+			logf(ctx, "Warning: table %s: %d rows keep %s NULL, as the rows they reference in %s are not in dev\n",
+				d.table, missing, d.column, d.parent)
+		}
+	}
+	return nil
+}
+
+// existingValues returns which of the values (keyed by textValue) column of
+// table has
+func existingValues(ctx context.Context, db *DB, table, column string, values map[int64]interface{}) (map[string]bool, error) {
+	distinct := make(map[string]interface{})
+	for _, v := range values {
+		distinct[textValue(v)] = v
+	}
+	keys := make([]string, 0, len(distinct))
+	for k := range distinct {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	existing := make(map[string]bool, len(keys))
+	for start := 0; start < len(keys); start += copyBatchSize {
+		batch := keys[start:min(start+copyBatchSize, len(keys))]
+		args := make([]interface{}, len(batch))
+		for i, k := range batch {
+			args[i] = distinct[k]
+		}
+		names, err := listNames(ctx, db, fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IN (%s)",
+			QuoteIdent(column), quoteTable(table), QuoteIdent(column), placeholders(len(args))), args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			existing[n] = true
+		}
+	}
+	return existing, nil
+}
+
+// truncateChecked truncates table with dev's foreign key checks briefly off
+// unless they are off already: TRUNCATE refuses a table other tables reference.
+func truncateChecked(ctx context.Context, db *DB, table string, opts SyncOptions) error {
+	if opts.fkChecksOff() {
+		return truncateTable(ctx, db, table)
+	}
+	restore := disableFKChecks(ctx, db)
+	defer restore()
+	return truncateTable(ctx, db, table)
+}
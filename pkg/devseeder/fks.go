@@ -0,0 +1,81 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+)
+
+// ForeignKey represents one FK relationship.
+// Example: childTable.childColumn -> parentTable.parentColumn
+type ForeignKey struct {
+	FromTable  string
+	FromColumn string
+	ToTable    string
+	ToColumn   string
+	IsNullable bool
+}
+
+// ==============================================================================
+// 1) Fetch *ALL* foreign keys from your DB (not just the subset).
+// ==============================================================================
+func FetchAllForeignKeys(ctx context.Context, db *DB) (_ []ForeignKey, err error) {
+	ctx, span := startSpan(ctx, "devseeder.discover_fks")
+	defer func() { finishSpan(span, err) }()
+
+	if db.metadata != nil {
+		return db.metadata.foreignKeys(ctx, db, func() ([]ForeignKey, error) { return queryForeignKeys(ctx, db) })
+	}
+	return queryForeignKeys(ctx, db)
+}
+
+// queryForeignKeys reads the FKs of the traversed schemas from information_schema
+func queryForeignKeys(ctx context.Context, db *DB) ([]ForeignKey, error) {
+	// Tables of the traversed schemas are named as qualifiedName does; FKs to
+	// tables outside them cannot be followed
+	filter, args := db.schemaFilter("kcu.table_schema")
+	parentFilter, parentArgs := db.schemaFilter("kcu.referenced_table_schema")
+	query := `
+	SELECT
+		` + qualifiedName("kcu.table_schema", "kcu.table_name") + ` AS child_table,
+		kcu.column_name AS child_column,
+		` + qualifiedName("kcu.referenced_table_schema", "kcu.referenced_table_name") + ` AS parent_table,
+		kcu.referenced_column_name AS parent_column,
+		CASE c.is_nullable WHEN 'YES' THEN TRUE ELSE FALSE END AS is_nullable,
+		` + parentFilter + ` AS traversed
+	FROM information_schema.key_column_usage kcu
+	INNER JOIN information_schema.columns c
+		ON c.table_schema = kcu.table_schema
+		AND c.table_name = kcu.table_name
+		AND c.column_name = kcu.column_name
+	WHERE
+		kcu.referenced_table_name IS NOT NULL
+		AND ` + filter + `;
+	`
+	rows, err := db.QueryContext(ctx, query, append(parentArgs, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query all FKs: %w", err)
+	}
+	defer rows.Close()
+
+	var fks []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		var traversed bool
+		if err := rows.Scan(
+			&fk.FromTable,
+			&fk.FromColumn,
+			&fk.ToTable,
+			&fk.ToColumn,
+			&fk.IsNullable,
+			&traversed,
+		); err != nil {
+			return nil, err
+		}
+		if !traversed {
+			logf(ctx, "Warning: ignoring FK %s.%s -> %s, whose schema is not traversed (see WithSchemas)", fk.FromTable, fk.FromColumn, fk.ToTable)
+			continue
+		}
+		fks = append(fks, fk)
+	}
+	return fks, nil
+}
@@ -0,0 +1,122 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// FullTable is a requested table with limit 0, copied whole. Its IDs are not
+// held in the plan: copies read them from prod a batch at a time, by id range.
+// Incremental runs keep the rows dev already has instead of leaving them out
+// of the plan; conflict strategies that need the planned IDs up front (remap,
+// fail) are not available for it.
+type FullTable struct {
+	Rows       int      `json:"rows"`                 // prod's row count when planned
+	Partitions []string `json:"partitions,omitempty"` // the prod partitions it is read from, if restricted
+}
+
+// Rows returns how many rows of table plan copies (for a FullTable, how many
+// prod had when planned)
+func (p *CopyPlan) Rows(table string) int {
+	if f, ok := p.Full[table]; ok {
+		return f.Rows
+	}
+	return len(p.RowSets[table])
+}
+
+// TotalRows returns how many rows plan copies in all
+func (p *CopyPlan) TotalRows() int {
+	rows := 0
+	for _, table := range p.Order {
+		rows += p.Rows(table)
+	}
+	return rows
+}
+
+// copies reports whether plan copies the row of table with id
+func (p *CopyPlan) copies(table string, id int64) bool {
+	if _, ok := p.Full[table]; ok {
+		return true
+	}
+	return p.RowSets[table][id]
+}
+
+// idBatchIter returns the next ascending batch of IDs, empty once there are none left
+type idBatchIter func(ctx context.Context) (map[int64]bool, error)
+
+// batches iterates the prod IDs of table that plan copies
+func (p *CopyPlan) batches(prodDB *DB, table string) idBatchIter {
+	if f, ok := p.Full[table]; ok {
+		return tableBatches(prodDB, table, f.Partitions)
+	}
+	return setBatches(p.RowSets[table])
+}
+
+// setBatches iterates idSet in batches of copyBatchSize
+func setBatches(idSet map[int64]bool) idBatchIter {
+	batches := idBatches(idSet, copyBatchSize)
+	return func(context.Context) (map[int64]bool, error) {
+		if len(batches) == 0 {
+			return nil, nil
+		}
+		batch := batches[0]
+		batches = batches[1:]
+		return batch, nil
+	}
+}
+
+// tableBatches iterates every id of table (in the given partitions), reading
+// copyBatchSize of them at a time past the last one read
+func tableBatches(db *DB, table string, partitions []string) idBatchIter {
+	last, done := int64(math.MinInt64), false
+	return func(ctx context.Context) (map[int64]bool, error) {
+		if done {
+			return nil, nil
+		}
+		id := QuoteIdent(idColumn)
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s%s WHERE %s > ? ORDER BY %s LIMIT ?",
+			id, quoteTable(table), partitionClause(partitions), id, id), last, copyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		batch := make(map[int64]bool, copyBatchSize)
+		for rows.Next() {
+			if err := rows.Scan(&last); err != nil {
+				return nil, err
+			}
+			batch[last] = true
+		}
+		done = len(batch) < copyBatchSize
+		return batch, rows.Err()
+	}
+}
+
+// countRows returns how many rows table has (in the given partitions)
+func countRows(ctx context.Context, db *DB, table string, partitions []string) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s%s", quoteTable(table), partitionClause(partitions))).Scan(&n)
+	return n, err
+}
+
+// fetchAllReferencedParentIDs returns the parent IDs every row of childTable (in
+// the given partitions) references through edge
+func fetchAllReferencedParentIDs(ctx context.Context, db *DB, childTable string, partitions []string, edge FkEdge) (map[int64]bool, error) {
+	col := QuoteIdent(edge.ChildColumn)
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT %s FROM %s%s WHERE %s IS NOT NULL",
+		col, quoteTable(childTable), partitionClause(partitions), col))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	parentIDs := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		parentIDs[id] = true
+	}
+	return parentIDs, rows.Err()
+}
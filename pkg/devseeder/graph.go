@@ -0,0 +1,92 @@
+package devseeder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteDOT writes the FK graph in fks to w in Graphviz DOT, with an edge from
+// each child table to its parent labelled by the FK column. Nullable FKs, which
+// the BFS does not follow, are dashed; seed tables are drawn with a double border.
+//
+// With a plan, only the tables it copies are drawn, labelled with their row
+// counts, so the graph shows why each of them was pulled in.
+func WriteDOT(w io.Writer, fks []ForeignKey, plan *CopyPlan, seeds map[string]int) error {
+	inGraph := func(table string) bool {
+		return plan == nil || plan.Rows(table) > 0
+	}
+
+	tables := make(map[string]bool)
+	for _, fk := range fks {
+		if inGraph(fk.FromTable) && inGraph(fk.ToTable) {
+			tables[fk.FromTable] = true
+			tables[fk.ToTable] = true
+		}
+	}
+	for table := range seeds {
+		if inGraph(table) {
+			tables[table] = true
+		}
+	}
+	if plan != nil {
+		for _, table := range plan.Order {
+			tables[table] = true
+		}
+	}
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph devseeder {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+	for _, table := range names {
+		label := table
+		if plan != nil {
+			label = fmt.Sprintf("%s\n%d rows", table, plan.Rows(table))
+		}
+		attrs := "label=" + dotQuote(label)
+		if _, ok := seeds[table]; ok {
+			attrs += ", peripheries=2"
+		}
+		fmt.Fprintf(bw, "\t%s [%s];\n", dotQuote(table), attrs)
+	}
+
+	edges := make([]ForeignKey, 0, len(fks))
+	for _, fk := range fks {
+		if tables[fk.FromTable] && tables[fk.ToTable] {
+			edges = append(edges, fk)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.FromTable != b.FromTable {
+			return a.FromTable < b.FromTable
+		}
+		if a.ToTable != b.ToTable {
+			return a.ToTable < b.ToTable
+		}
+		return a.FromColumn < b.FromColumn
+	})
+	for _, fk := range edges {
+		attrs := "label=" + dotQuote(fk.FromColumn)
+		if fk.IsNullable {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(bw, "\t%s -> %s [%s];\n", dotQuote(fk.FromTable), dotQuote(fk.ToTable), attrs)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a DOT double-quoted string.
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,57 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SQLHooks are statements run on dev around a sync, e.g. to disable a trigger,
+// refresh a summary table or fix a sequence. They run on the sync's dev session,
+// so foreign key checks are off and session variables they set stay in effect.
+type SQLHooks struct {
+	BeforeRun []string // once the plan is built, before anything is written
+	AfterRun  []string // once the copy and its checks succeeded
+	// { tableName : statements } run before a table is truncated or written and
+	// after its rows are copied; keyed by the prod or the dev spelling of the table
+	BeforeTable map[string][]string
+	AfterTable  map[string][]string
+}
+
+// WithSQLHooks runs h's statements on dev before and after the sync and each table.
+func WithSQLHooks(h SQLHooks) Option {
+	return func(s *Seeder) { s.opts.Hooks = h }
+}
+
+// tableHooks returns the statements hooks holds for table, looked up by its prod
+// name and then by its dev name
+func tableHooks(hooks map[string][]string, table, devTable string) []string {
+	if stmts, ok := hooks[table]; ok {
+		return stmts
+	}
+	return hooks[devTable]
+}
+
+// runSQLHooks executes stmts on db in order, stopping at the first failure. stage
+// names the hook in logs and errors, e.g. "before_table orders".
+func runSQLHooks(ctx context.Context, db *DB, stage string, stmts []string) error {
+	for i, stmt := range stmts {
+		logEvent(ctx, fmt.Sprintf("Running %s hook %d/%d", stage, i+1, len(stmts)), "hook", stage, "statement", stmt)
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%s hook %d (%s): %w", stage, i+1, abbreviateStatement(stmt), err)
+		}
+	}
+	return nil
+}
+
+// abbreviateStatement shortens stmt to its first line, at most 60 characters, for error messages
+func abbreviateStatement(stmt string) string {
+	stmt, _, cut := strings.Cut(strings.TrimSpace(stmt), "\n")
+	if r := []rune(stmt); len(r) > 60 {
+		stmt, cut = string(r[:60]), true
+	}
+	if cut {
+		stmt += "..."
+	}
+	return stmt
+}
@@ -0,0 +1,53 @@
+package devseeder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IncompleteCopyError is returned by Sync when the copy stopped partway. It says
+// which tables dev got in full, which one was being written and which were never
+// reached; Err is the cause. Session settings are restored all the same.
+type IncompleteCopyError struct {
+	Err error
+	// tables copied in full, in copy order
+	Copied []string
+	// the table being written when the copy stopped, if any, with the rows it got
+	// and the rows planned for it
+	Partial                   string
+	PartialRows, PartialTotal int
+	// whether Partial was emptied by WithResetTables first, and where its previous
+	// rows were backed up (empty without a backup)
+	PartialReset  bool
+	PartialBackup string
+	// planned tables the copy never reached
+	NotCopied []string
+}
+
+func (e *IncompleteCopyError) Error() string { return e.Err.Error() }
+func (e *IncompleteCopyError) Unwrap() error { return e.Err }
+
+// Summary describes what dev was left with.
+func (e *IncompleteCopyError) Summary() string {
+	var parts []string
+	if e.Partial != "" {
+		s := fmt.Sprintf("table %s has %d of %d planned rows", e.Partial, e.PartialRows, e.PartialTotal)
+		switch {
+		case e.PartialReset && e.PartialBackup != "":
+			s += fmt.Sprintf(" (it was reset first; its previous rows are in %s)", e.PartialBackup)
+		case e.PartialReset:
+			s += " (it was reset first, so its previous rows are gone)"
+		}
+		parts = append(parts, s)
+	}
+	if len(e.NotCopied) > 0 {
+		parts = append(parts, "not copied: "+strings.Join(e.NotCopied, ", "))
+	}
+	if len(e.Copied) > 0 {
+		parts = append(parts, "copied in full: "+strings.Join(e.Copied, ", "))
+	}
+	if len(parts) == 0 {
+		return "the copy stopped before writing anything"
+	}
+	return "the copy was left incomplete: " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,126 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WithIncremental only copies planned rows whose IDs dev does not have yet,
+// leaving the rows it already has as they are. Meant for repeated runs against
+// the same dev database, such as sync -watch. It has no effect on tables reset
+// with WithResetTables.
+func WithIncremental() Option {
+	return func(s *Seeder) { s.opts.Incremental = true }
+}
+
+// How rows are written to dev (see WithWriteStrategy)
+const (
+	WriteInsert = ""       // plain INSERT; a row dev already has fails the sync
+	WriteIgnore = "ignore" // keep the rows dev has and only add the missing ones
+	WriteAppend = "append" // only add rows with IDs outside the range dev already uses
+)
+
+// WithWriteStrategy selects how rows are written to dev. WriteIgnore never
+// touches existing dev rows, e.g. ones a developer changed locally: planned
+// rows whose IDs dev has are not copied, and rows clashing with dev rows on
+// another unique key are skipped with INSERT IGNORE. WriteAppend goes further
+// and leaves the whole ID range dev uses alone, so rows created locally are
+// never touched even where their IDs were taken by prod rows since.
+func WithWriteStrategy(strategy string) Option {
+	return func(s *Seeder) { s.opts.WriteStrategy = strategy }
+}
+
+// existingIDs returns the IDs of idSet present in table
+func existingIDs(ctx context.Context, db *DB, table string, idSet map[int64]bool) (map[int64]bool, error) {
+	existing := make(map[int64]bool)
+	for _, batch := range idBatches(idSet, copyBatchSize) {
+		args := idArgs(batch)
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)",
+			QuoteIdent(idColumn), quoteTable(table), QuoteIdent(idColumn), placeholders(len(args))), args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			existing[id] = true
+		}
+		err = rows.Close()
+		if err == nil {
+			err = rows.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// newRowsPlan returns a copy of plan without the rows dev already has.
+func newRowsPlan(ctx context.Context, devDB *DB, plan *CopyPlan, opts SyncOptions) (*CopyPlan, error) {
+	out := *plan
+	out.RowSets = make(map[string]map[int64]bool, len(plan.RowSets))
+	skipped := 0
+	for table, idSet := range plan.RowSets {
+		// Tables with a conflict strategy settle clashes themselves; tables
+		// copied whole skip the rows dev has as they are inserted
+		if _, full := plan.Full[table]; full || opts.Conflicts[table] != "" {
+			out.RowSets[table] = idSet
+			continue
+		}
+		existing, err := existingIDs(ctx, devDB, opts.devTable(table), idSet)
+		if err != nil {
+			return nil, fmt.Errorf("looking up existing rows of dev %s: %w", opts.devTable(table), err)
+		}
+		missing := make(map[int64]bool, len(idSet)-len(existing))
+		for id := range idSet {
+			if !existing[id] {
+				missing[id] = true
+			}
+		}
+		out.RowSets[table] = missing
+		skipped += len(existing)
+	}
+	logf(ctx, "%d planned rows are already in dev and are left as they are", skipped)
+	return &out, nil
+}
+
+// appendRowsPlan returns a copy of plan with only the rows whose IDs lie outside
+// the range of IDs in each dev table, which therefore cannot clash with rows
+// seeded earlier or created locally.
+func appendRowsPlan(ctx context.Context, devDB *DB, plan *CopyPlan, opts SyncOptions) (*CopyPlan, error) {
+	out := *plan
+	out.RowSets = make(map[string]map[int64]bool, len(plan.RowSets))
+	skipped := 0
+	for table, idSet := range plan.RowSets {
+		if _, full := plan.Full[table]; full || opts.Conflicts[table] != "" {
+			out.RowSets[table] = idSet
+			continue
+		}
+		devTable := opts.devTable(table)
+		var lo, hi sql.NullInt64
+		if err := devDB.QueryRowContext(ctx, fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s",
+			QuoteIdent(idColumn), QuoteIdent(idColumn), quoteTable(devTable))).Scan(&lo, &hi); err != nil {
+			return nil, fmt.Errorf("reading the ID range of dev %s: %w", devTable, err)
+		}
+		outside := make(map[int64]bool, len(idSet))
+		for id := range idSet {
+			if !lo.Valid || id < lo.Int64 || id > hi.Int64 {
+				outside[id] = true
+			}
+		}
+		if n := len(idSet) - len(outside); n > 0 {
+			logf(ctx, "Table %s: %d planned rows fall within the IDs dev uses (%d-%d) and are not copied", table, n, lo.Int64, hi.Int64)
+			skipped += n
+		}
+		out.RowSets[table] = outside
+	}
+	if skipped > 0 {
+		logf(ctx, "Warning: append mode skipped %d planned rows; copied rows may reference parents dev has under the same ID with other data", skipped)
+	}
+	return &out, nil
+}
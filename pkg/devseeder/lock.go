@@ -0,0 +1,50 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// AcquireRunLock takes a server-wide advisory lock for the dev database, so two
+// DevSeeder runs can never write to the same target at the same time.
+// It waits up to timeoutSeconds for another run to finish (0 refuses immediately)
+// and returns a func that releases the lock.
+//
+// The lock belongs to the session that took it; dev uses a single connection,
+// so it is held for the whole run.
+func AcquireRunLock(ctx context.Context, db *DB, timeoutSeconds int) (func(), error) {
+	var name string
+	if err := db.QueryRowContext(ctx, "SELECT LEFT(CONCAT('devseeder.', DATABASE()), 64)").Scan(&name); err != nil {
+		return nil, fmt.Errorf("cannot determine lock name: %w", err)
+	}
+
+	var got sql.NullInt64
+	if err := db.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, timeoutSeconds).Scan(&got); err != nil {
+		return nil, fmt.Errorf("cannot acquire lock %s: %w", name, err)
+	}
+	if !got.Valid || got.Int64 != 1 {
+		var holder sql.NullInt64
+		_ = db.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", name).Scan(&holder)
+		return nil, fmt.Errorf("another DevSeeder run (connection id %d) holds lock %s on the target", holder.Int64, name)
+	}
+
+	// A new connection must take the lock again before writing anything
+	db.keepSession(ctx, "run lock", func(ctx context.Context) error {
+		var got sql.NullInt64
+		if err := db.DB.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&got); err != nil {
+			return err
+		}
+		if !got.Valid || got.Int64 != 1 {
+			return fmt.Errorf("another DevSeeder run took lock %s while the connection was down", name)
+		}
+		return nil
+	})
+
+	return func() {
+		db.forgetSession("run lock")
+		if _, err := db.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name); err != nil {
+			logf(ctx, "Warning: cannot release lock %s: %v\n", name, err)
+		}
+	}, nil
+}
@@ -0,0 +1,70 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// Logger receives progress messages and warnings. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// attrLogger is implemented by Loggers that also take structured attributes;
+// see NewSlogLogger.
+type attrLogger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+// SlogLogger is a Logger writing to a *slog.Logger. Plain messages are logged at
+// info level, or warn when they start with "Warning:"; the engine's key events
+// (tables copied, row counts, durations) also carry their values as attributes.
+type SlogLogger struct {
+	*slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing structured records to l.
+func NewSlogLogger(l *slog.Logger) SlogLogger {
+	return SlogLogger{l}
+}
+
+// Printf logs a plain message
+func (l SlogLogger) Printf(format string, v ...interface{}) {
+	msg := strings.TrimSuffix(fmt.Sprintf(format, v...), "\n")
+	level := slog.LevelInfo
+	if rest, ok := strings.CutPrefix(msg, "Warning: "); ok {
+		level, msg = slog.LevelWarn, rest
+	}
+	l.Logger.Log(context.Background(), level, msg)
+}
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx whose messages go to l instead of the
+// standard logger. The Seeder does this for every call; it only matters when
+// calling the lower-level functions directly.
+func ContextWithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// logf logs through the Logger carried by ctx, or the standard logger.
+func logf(ctx context.Context, format string, v ...interface{}) {
+	if l, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		l.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// logEvent logs msg with key/value attributes. Loggers that take attributes get
+// them as fields; the others only get msg, which should already read well alone.
+func logEvent(ctx context.Context, msg string, args ...any) {
+	if l, ok := ctx.Value(loggerKey{}).(attrLogger); ok {
+		l.Log(ctx, slog.LevelInfo, msg, args...)
+		return
+	}
+	logf(ctx, "%s", msg)
+}
@@ -0,0 +1,29 @@
+package devseeder
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPlanTooLarge is wrapped by the error Sync returns when the plan writes more
+// rows than WithMaxRows allows.
+var ErrPlanTooLarge = errors.New("plan too large")
+
+// WithMaxRows fails the sync, before anything is written to dev, when the plan
+// would write more than maxRows rows in total (0, the default, allows any size).
+// Rows dev already has and keeps are not counted.
+func WithMaxRows(maxRows int) Option {
+	return func(s *Seeder) { s.opts.MaxRows = maxRows }
+}
+
+// checkPlanSize returns ErrPlanTooLarge if plan holds more than maxRows rows
+func checkPlanSize(plan *CopyPlan, maxRows int) error {
+	if maxRows <= 0 {
+		return nil
+	}
+	rows := plan.TotalRows()
+	if rows > maxRows {
+		return fmt.Errorf("%w: %d rows planned, at most %d allowed", ErrPlanTooLarge, rows, maxRows)
+	}
+	return nil
+}
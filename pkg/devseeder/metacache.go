@@ -0,0 +1,167 @@
+package devseeder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// WithMetadataCache keeps the FK and column metadata read from information_schema
+// in dir between runs. Each file is keyed by the server, the schemas and a hash of
+// their current definition, so any schema change is picked up by the next run.
+func WithMetadataCache(dir string) Option {
+	return func(s *Seeder) {
+		for _, db := range []*DB{s.prod, s.dev} {
+			if db != nil {
+				db.metadata = &metadataCache{dir: dir}
+			}
+		}
+	}
+}
+
+// metadataCache is the on-disk metadata of one DB
+type metadataCache struct {
+	mu      sync.Mutex
+	dir     string
+	path    string // "" until the schema version is computed
+	loaded  bool
+	content cachedMetadata
+}
+
+type cachedMetadata struct {
+	ForeignKeys []ForeignKey            `json:"foreign_keys"`
+	Columns     map[string][]columnInfo `json:"columns"`
+}
+
+// schemaVersion identifies the server and schemas of db and hashes what the cached
+// metadata is derived from: tables, columns and FK constraints. Cheap aggregates
+// of these catalog tables are far quicker than the key_column_usage join.
+func schemaVersion(ctx context.Context, db *DB) (identity, version string, err error) {
+	var host, schema string
+	var port int
+	if err := db.QueryRowContext(ctx, "SELECT @@hostname, @@port, DATABASE()").Scan(&host, &port, &schema); err != nil {
+		return "", "", err
+	}
+	id := sha256.Sum256([]byte(fmt.Sprintf("%s:%d/%s+%s", host, port, schema, strings.Join(db.schemas, ","))))
+
+	h := sha256.New()
+	for _, q := range []struct{ table, columns, schemaColumn string }{
+		{"tables", "table_name, create_time", "table_schema"},
+		{"columns", "table_name, column_name, ordinal_position, column_type, is_nullable, extra, character_set_name", "table_schema"},
+		{"referential_constraints", "constraint_name, table_name, referenced_table_name, unique_constraint_name", "constraint_schema"},
+	} {
+		filter, args := db.schemaFilter(q.schemaColumn)
+		var n, sum int64
+		query := fmt.Sprintf("SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS(':', %s, %s))), 0) FROM information_schema.%s WHERE %s",
+			q.schemaColumn, q.columns, q.table, filter)
+		if err := db.QueryRowContext(ctx, query, args...).Scan(&n, &sum); err != nil {
+			return "", "", fmt.Errorf("hashing information_schema.%s: %w", q.table, err)
+		}
+		fmt.Fprintf(h, "%s:%d:%d;", q.table, n, sum)
+	}
+	return hex.EncodeToString(id[:8]), hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// load reads the cache file for db's current schema version, if any. It reports
+// false when there is no usable cache, e.g. because the version cannot be read.
+func (c *metadataCache) load(ctx context.Context, db *DB) bool {
+	if c.loaded {
+		return true
+	}
+	identity, version, err := schemaVersion(ctx, db)
+	if err != nil {
+		logf(ctx, "Warning: not caching %s metadata: %v", db.Name, err)
+		return false
+	}
+	c.path = filepath.Join(c.dir, fmt.Sprintf("metadata-%s-%s.json", identity, version))
+	c.loaded = true
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logf(ctx, "Warning: cannot read metadata cache %s: %v", c.path, err)
+		}
+		return true
+	}
+	if err := json.Unmarshal(data, &c.content); err != nil {
+		logf(ctx, "Warning: ignoring corrupt metadata cache %s: %v", c.path, err)
+		c.content = cachedMetadata{}
+	}
+	return true
+}
+
+// save writes the cache file, replacing the files of older schema versions
+func (c *metadataCache) save(ctx context.Context) {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		logf(ctx, "Warning: cannot write metadata cache: %v", err)
+		return
+	}
+	data, err := json.Marshal(c.content)
+	if err == nil {
+		err = os.WriteFile(c.path, data, 0o600)
+	}
+	if err != nil {
+		logf(ctx, "Warning: cannot write metadata cache %s: %v", c.path, err)
+		return
+	}
+	identity, _, _ := strings.Cut(strings.TrimPrefix(filepath.Base(c.path), "metadata-"), "-")
+	stale, _ := filepath.Glob(filepath.Join(c.dir, "metadata-"+identity+"-*.json"))
+	for _, f := range stale {
+		if f != c.path {
+			os.Remove(f)
+		}
+	}
+}
+
+// reset forgets the schema version, e.g. after tables were created or altered
+func (c *metadataCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.path, c.loaded, c.content = "", false, cachedMetadata{}
+}
+
+// foreignKeys returns the cached FKs, or calls fetch and caches its result
+func (c *metadataCache) foreignKeys(ctx context.Context, db *DB, fetch func() ([]ForeignKey, error)) ([]ForeignKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.load(ctx, db) {
+		return fetch()
+	}
+	if c.content.ForeignKeys != nil {
+		return c.content.ForeignKeys, nil
+	}
+	fks, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.content.ForeignKeys = fks
+	if c.content.ForeignKeys == nil {
+		c.content.ForeignKeys = []ForeignKey{}
+	}
+	c.save(ctx)
+	return fks, nil
+}
+
+// columns returns the cached columns of every table, or calls fetch and caches its result
+func (c *metadataCache) columns(ctx context.Context, db *DB, fetch func() (map[string][]columnInfo, error)) (map[string][]columnInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.load(ctx, db) {
+		return fetch()
+	}
+	if c.content.Columns != nil {
+		return c.content.Columns, nil
+	}
+	cols, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.content.Columns = cols
+	c.save(ctx)
+	return cols, nil
+}
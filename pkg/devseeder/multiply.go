@@ -0,0 +1,219 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Multiplication turns the copied subset into a larger load-test dataset.
+type Multiplication struct {
+	// Factor is how many times the dataset ends up in dev: every copied row gets
+	// Factor-1 copies. 0 and 1 leave the copy alone.
+	Factor int
+	// Perturb varies the copies' decimal and floating point values by up to 10%
+	// and their dates by up to 30 days, so they do not all aggregate alike.
+	Perturb bool
+	// Seed makes the perturbation reproducible
+	Seed int64
+}
+
+// WithMultiplication duplicates every copied row after the sync (see multiplyRows).
+func WithMultiplication(m Multiplication) Option {
+	return func(s *Seeder) { s.opts.Multiply = m }
+}
+
+// multiplyRows inserts Factor-1 copies of every row the plan copied into dev. Copy
+// k of a table's row gets id + k*span, where span is the table's highest id in
+// prod or dev, so copies never collide with each other or with any prod row.
+// Foreign keys to copied parent rows are shifted the same way, so each copy is a
+// self-contained replica of the subset. Unique character columns get a "-k"
+// suffix; other unique columns must be made distinct with column_values or a
+// transform. Rows are read back from dev, so they carry every transform applied.
+func multiplyRows(ctx context.Context, prodDB, devDB *DB, allFks []ForeignKey, plan *CopyPlan, opts SyncOptions) error {
+	m := opts.Multiply
+	spans := make(map[string]int64, len(plan.Order))
+	for _, table := range plan.Order {
+		var span int64
+		for _, side := range []struct {
+			db    *DB
+			table string
+		}{{prodDB, table}, {devDB, opts.devTable(table)}} {
+			var maxID sql.NullInt64
+			q := fmt.Sprintf("SELECT MAX(%s) FROM %s", QuoteIdent(idColumn), quoteTable(side.table))
+			if err := side.db.QueryRowContext(ctx, q).Scan(&maxID); err != nil {
+				return fmt.Errorf("reading highest id of %s %s: %w", side.db.Name, side.table, err)
+			}
+			span = max(span, maxID.Int64)
+		}
+		spans[table] = span
+	}
+
+	rng := rand.New(rand.NewSource(m.Seed))
+	total := 0
+	for _, table := range plan.Order {
+		if plan.Rows(table) == 0 {
+			continue
+		}
+		devTable := opts.devTable(table)
+		types, err := fetchColumnTypes(ctx, devDB, devTable)
+		if err != nil {
+			return err
+		}
+		unique, err := uniqueColumns(ctx, devDB, devTable)
+		if err != nil {
+			return fmt.Errorf("reading unique keys of %s: %w", devTable, err)
+		}
+		parents := fkParents(allFks, table, opts)
+
+		copied := 0
+		next := plan.batches(prodDB, table)
+		for {
+			batch, err := next(ctx)
+			if err != nil {
+				return fmt.Errorf("reading the IDs of %s: %w", table, err)
+			}
+			if len(batch) == 0 {
+				break
+			}
+			rowsData, columns, err := fetchRowsByIDs(ctx, devDB, devTable, batch)
+			if err != nil {
+				return fmt.Errorf("reading copied rows of %s: %w", devTable, err)
+			}
+			generated, err := fetchGeneratedColumns(ctx, devDB, devTable)
+			if err != nil {
+				return err
+			}
+			columns, rowsData, _ = projectColumns(columns, rowsData, func(c string) bool { return !generated[c] })
+
+			for k := 1; k < m.Factor; k++ {
+				copies := make([][]interface{}, len(rowsData))
+				newIDs := make(map[int64]bool, len(rowsData))
+				for r, row := range rowsData {
+					out := slices.Clone(row)
+					for i, c := range columns {
+						switch {
+						case c == idColumn:
+							id, _ := int64Value(out[i])
+							out[i] = id + int64(k)*spans[table]
+							newIDs[id+int64(k)*spans[table]] = true
+						case parents[c] != "":
+							parent := parents[c]
+							if v, ok := int64Value(out[i]); ok && plan.copies(parent, v) {
+								out[i] = v + int64(k)*spans[parent]
+							}
+						case unique[c] && isCharType(types[c].DataType) && out[i] != nil:
+							out[i] = fmt.Sprintf("%s-%d", textValue(out[i]), k)
+						case m.Perturb && !unique[c]:
+							out[i] = perturb(rng, out[i], types[c])
+						}
+					}
+					copies[r] = out
+				}
+				if err := prepareRows(devTable, columns, copies, types, opts); err != nil {
+					return err
+				}
+				if err := insertRows(ctx, devDB, devTable, columns, copies, nil, ""); err != nil {
+					return fmt.Errorf("inserting copies into %s: %w", devTable, err)
+				}
+				opts.Undo.Record(devTable, newIDs)
+				copied += len(copies)
+			}
+		}
+		if err := syncAutoIncrement(ctx, devDB, devTable); err != nil {
+			return fmt.Errorf("syncAutoIncrement error on %s: %w", devTable, err)
+		}
+		logEvent(ctx, fmt.Sprintf("Multiplied table %s: %d extra rows", table, copied), "table", table, "rows", copied)
+		total += copied
+	}
+	logEvent(ctx, fmt.Sprintf("Multiplied the dataset %d times: %d extra rows", m.Factor, total), "rows", total)
+	return nil
+}
+
+// uniqueColumns returns the columns of table that are part of a unique key
+// other than the primary key
+func uniqueColumns(ctx context.Context, db *DB, table string) (map[string]bool, error) {
+	names, err := listNames(ctx, db, `SELECT DISTINCT column_name FROM information_schema.statistics
+	WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ? AND non_unique = 0 AND index_name <> 'PRIMARY'`, tableArgs(table)...)
+	if err != nil {
+		return nil, err
+	}
+	unique := make(map[string]bool, len(names))
+	for _, n := range names {
+		unique[n] = true
+	}
+	return unique, nil
+}
+
+func isCharType(dataType string) bool {
+	switch dataType {
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext":
+		return true
+	}
+	return false
+}
+
+// int64Value returns an integer column value scanned from the driver
+func int64Value(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	case []byte:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func textValue(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// perturb varies a decimal, floating point or date value at random
+func perturb(rng *rand.Rand, v interface{}, c columnInfo) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch c.DataType {
+	case "decimal":
+		// Exact arithmetic: a float64 would lose the digits of wide DECIMALs
+		r, ok := new(big.Rat).SetString(textValue(v))
+		if !ok {
+			return v
+		}
+		r.Mul(r, new(big.Rat).SetFloat64(0.9+rng.Float64()*0.2))
+		// Keep within the column: rounding to its scale, never past its precision
+		s := r.FloatString(int(c.Scale))
+		if digits := len(strings.TrimLeft(strings.NewReplacer("-", "", ".", "").Replace(s), "0")); c.Precision > 0 && int64(digits) > c.Precision {
+			return v
+		}
+		return s
+	case "float", "double":
+		f, err := strconv.ParseFloat(textValue(v), 64)
+		if err != nil {
+			return v
+		}
+		return f * (0.9 + rng.Float64()*0.2)
+	case "date", "datetime", "timestamp":
+		t, ok := v.(time.Time)
+		if !ok || t.IsZero() {
+			return v
+		}
+		return t.AddDate(0, 0, rng.Intn(61)-30)
+	}
+	return v
+}
@@ -0,0 +1,30 @@
+package devseeder
+
+import (
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestPerturbDecimalExact(t *testing.T) {
+	c := columnInfo{DataType: "decimal", ColumnType: "decimal(65,30)", Precision: 65, Scale: 30}
+	orig := "12345678901234567890123.123456789012345678901234567890"
+	want, _ := new(big.Rat).SetString(orig)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		s, ok := perturb(rng, []byte(orig), c).(string)
+		if !ok {
+			t.Fatalf("perturb returned %T, want a string", perturb(rng, []byte(orig), c))
+		}
+		if _, frac, _ := strings.Cut(s, "."); len(frac) != 30 {
+			t.Fatalf("perturb(%s) = %s, want 30 decimals", orig, s)
+		}
+		// Within 10% of the original, computed without going through float64
+		got, _ := new(big.Rat).SetString(s)
+		ratio, _ := new(big.Rat).Quo(got, want).Float64()
+		if ratio < 0.9 || ratio > 1.1 {
+			t.Fatalf("perturb(%s) = %s, off by a factor of %v", orig, s, ratio)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package devseeder
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+)
+
+// jsonValue converts a value scanned from the driver into something that keeps its
+// meaning in JSON: numbers stay numbers, DECIMALs stay exact, JSON columns are
+// embedded as JSON, BIT columns become numbers and binary data is base64-encoded.
+func jsonValue(v interface{}, dataType string) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		switch {
+		case dataType == "json" && json.Valid(val):
+			return json.RawMessage(val)
+		case dataType == "decimal":
+			return json.Number(val)
+		case dataType == "bit":
+			return bitValue(val)
+		case isBinaryType(dataType) || !utf8.Valid(val):
+			return base64.StdEncoding.EncodeToString(val)
+		default:
+			return string(val)
+		}
+	case time.Time:
+		return mysqlTime(val, time.RFC3339Nano)
+	default:
+		return val
+	}
+}
+
+// isBinaryType reports whether a MySQL data_type holds raw bytes rather than text
+func isBinaryType(dataType string) bool {
+	switch dataType {
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit", "geometry":
+		return true
+	}
+	return false
+}
+
+// ExportNDJSON writes one <table>.ndjson file per table into dir, one JSON object
+// per row with column names as keys. Compressed or encrypted files get a .gz/.zst/.age suffix.
+func ExportNDJSON(ctx context.Context, prodDB *DB, plan *CopyPlan, dir string, artifact ArtifactOptions) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return forEachPlanTable(ctx, prodDB, plan, func(table string, columns []string, rows rowBatches) error {
+		types, err := fetchColumnTypes(ctx, prodDB, table)
+		if err != nil {
+			return fmt.Errorf("fetchColumnTypes error on %s: %w", table, err)
+		}
+
+		f, err := CreateOutput(filepath.Join(dir, table+".ndjson"+artifact.Ext()), artifact)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w := bufio.NewWriter(f)
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		err = rows(func(rowsData [][]interface{}) error {
+			for _, row := range rowsData {
+				obj := make(map[string]interface{}, len(columns))
+				for i, c := range columns {
+					obj[c] = jsonValue(row[i], types[c].DataType)
+				}
+				if err := enc.Encode(obj); err != nil {
+					return fmt.Errorf("encoding row of %s: %w", table, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return f.Close()
+	})
+}
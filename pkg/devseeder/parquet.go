@@ -0,0 +1,261 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize is the number of rows buffered per Parquet row group
+const parquetRowGroupSize = 10000
+
+// mysqlColumn describes a column closely enough to pick its Parquet type
+type mysqlColumn struct {
+	dataType  string
+	unsigned  bool
+	precision int
+	scale     int
+}
+
+// fetchMySQLColumns returns { column : type details } for table
+func fetchMySQLColumns(ctx context.Context, db *DB, table string) (map[string]mysqlColumn, error) {
+	infos, err := db.tableColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	cols := make(map[string]mysqlColumn, len(infos))
+	for _, c := range infos {
+		cols[c.Name] = mysqlColumn{dataType: c.DataType, unsigned: c.unsigned(), precision: int(c.Precision), scale: int(c.Scale)}
+	}
+	return cols, nil
+}
+
+// parquetNode maps a MySQL column to an optional Parquet node. Types without a
+// faithful Parquet equivalent (TIME, ENUM, SET, large DECIMALs) are written as strings.
+func parquetNode(c mysqlColumn) parquet.Node {
+	var node parquet.Node
+	switch c.dataType {
+	case "tinyint", "smallint", "mediumint", "year":
+		node = parquet.Int(32)
+	case "int":
+		if c.unsigned {
+			node = parquet.Int(64)
+		} else {
+			node = parquet.Int(32)
+		}
+	case "bigint":
+		if c.unsigned {
+			node = parquet.Uint(64)
+		} else {
+			node = parquet.Int(64)
+		}
+	case "float":
+		node = parquet.Leaf(parquet.FloatType)
+	case "double":
+		node = parquet.Leaf(parquet.DoubleType)
+	case "decimal":
+		if c.precision <= 18 {
+			node = parquet.Decimal(c.scale, c.precision, parquet.Int64Type)
+		} else {
+			node = parquet.String()
+		}
+	case "date":
+		node = parquet.Date()
+	case "datetime", "timestamp":
+		node = parquet.Timestamp(parquet.Microsecond)
+	case "json":
+		node = parquet.JSON()
+	default:
+		if isBinaryType(c.dataType) {
+			node = parquet.Leaf(parquet.ByteArrayType)
+		} else {
+			node = parquet.String()
+		}
+	}
+	return parquet.Optional(node)
+}
+
+// parquetValue converts a value scanned from the driver into the physical
+// representation parquetNode chose for the column.
+func parquetValue(v interface{}, c mysqlColumn) (parquet.Value, error) {
+	if v == nil {
+		return parquet.NullValue(), nil
+	}
+	text := func() string {
+		switch val := v.(type) {
+		case []byte:
+			return string(val)
+		case time.Time:
+			return val.Format("2006-01-02 15:04:05.999999")
+		default:
+			return fmt.Sprint(val)
+		}
+	}
+
+	switch c.dataType {
+	case "tinyint", "smallint", "mediumint", "year", "int", "bigint":
+		if c.unsigned {
+			n, err := strconv.ParseUint(text(), 10, 64)
+			if err != nil {
+				return parquet.Value{}, err
+			}
+			if c.dataType == "int" || c.dataType == "bigint" {
+				return parquet.Int64Value(int64(n)), nil
+			}
+			return parquet.Int32Value(int32(n)), nil
+		}
+		n, err := strconv.ParseInt(text(), 10, 64)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		if c.dataType == "bigint" {
+			return parquet.Int64Value(n), nil
+		}
+		return parquet.Int32Value(int32(n)), nil
+	case "float", "double":
+		f, err := strconv.ParseFloat(text(), 64)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		if c.dataType == "float" {
+			return parquet.FloatValue(float32(f)), nil
+		}
+		return parquet.DoubleValue(f), nil
+	case "decimal":
+		if c.precision > 18 {
+			return parquet.ByteArrayValue([]byte(text())), nil
+		}
+		unscaled, err := unscaledDecimal(text(), c.scale)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(unscaled), nil
+	case "date":
+		t, err := parseMySQLTime(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		return parquet.Int32Value(int32(days)), nil
+	case "datetime", "timestamp":
+		t, err := parseMySQLTime(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(t.UnixMicro()), nil
+	}
+	if b, ok := v.([]byte); ok {
+		return parquet.ByteArrayValue(b), nil
+	}
+	return parquet.ByteArrayValue([]byte(text())), nil
+}
+
+// unscaledDecimal turns "123.45" with scale 2 into 12345
+func unscaledDecimal(s string, scale int) (int64, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid decimal %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("decimal %q has more than %d decimals", s, scale)
+	}
+	return r.Num().Int64(), nil
+}
+
+// parseMySQLTime accepts a time.Time (parseTime=true) or the raw text the driver
+// returns otherwise. Values are taken as UTC wall-clock times.
+func parseMySQLTime(v interface{}) (time.Time, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case []byte:
+		s := string(val)
+		for _, layout := range []string{"2006-01-02 15:04:05.999999", "2006-01-02"} {
+			if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse time %q", s)
+	}
+	return time.Time{}, fmt.Errorf("unexpected time value %T", v)
+}
+
+// ExportParquet writes one <table>.parquet file per table into dir, with a schema
+// derived from the MySQL column types so DuckDB/Spark read typed columns.
+func ExportParquet(ctx context.Context, prodDB *DB, plan *CopyPlan, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return forEachPlanTable(ctx, prodDB, plan, func(table string, columns []string, rows rowBatches) error {
+		types, err := fetchMySQLColumns(ctx, prodDB, table)
+		if err != nil {
+			return fmt.Errorf("fetchMySQLColumns error on %s: %w", table, err)
+		}
+
+		group := make(parquet.Group, len(columns))
+		for _, c := range columns {
+			group[c] = parquetNode(types[c])
+		}
+		schema := parquet.NewSchema(table, group)
+
+		// Rows must list values in the schema's column order
+		colIndex := make([]int, len(columns))
+		for i, c := range columns {
+			leaf, ok := schema.Lookup(c)
+			if !ok {
+				return fmt.Errorf("column %s missing from Parquet schema of %s", c, table)
+			}
+			colIndex[i] = leaf.ColumnIndex
+		}
+
+		f, err := os.Create(filepath.Join(dir, table+".parquet"))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w := parquet.NewWriter(f, schema, parquet.Compression(&parquet.Snappy))
+		batch := make([]parquet.Row, 0, parquetRowGroupSize)
+		err = rows(func(rowsData [][]interface{}) error {
+			for _, data := range rowsData {
+				row := make(parquet.Row, len(columns))
+				for i, c := range columns {
+					val, err := parquetValue(data[i], types[c])
+					if err != nil {
+						return fmt.Errorf("converting %s.%s: %w", table, c, err)
+					}
+					def := 1
+					if val.IsNull() {
+						def = 0
+					}
+					row[colIndex[i]] = val.Level(0, def, colIndex[i])
+				}
+				batch = append(batch, row)
+				if len(batch) == parquetRowGroupSize {
+					if _, err := w.WriteRows(batch); err != nil {
+						return err
+					}
+					batch = batch[:0]
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := w.WriteRows(batch); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return f.Close()
+	})
+}
@@ -0,0 +1,65 @@
+package devseeder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// errNoPartitionForValue is MySQL's ER_NO_PARTITION_FOR_GIVEN_VALUE
+const errNoPartitionForValue = 1526
+
+// partitionClause returns " PARTITION (`p1`,`p2`)" to restrict a SELECT to the
+// given partitions, or "" when no partitions are configured.
+func partitionClause(partitions []string) string {
+	if len(partitions) == 0 {
+		return ""
+	}
+	return " PARTITION (" + quoteIdents(partitions) + ")"
+}
+
+// checkPartitions verifies that every configured partition exists on the prod table,
+// so a typo fails up front instead of silently selecting nothing.
+func checkPartitions(ctx context.Context, db *DB, partitions map[string][]string) error {
+	for table, wanted := range partitions {
+		existing, err := listNames(ctx, db, `
+		SELECT partition_name
+		FROM information_schema.partitions
+		WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ? AND partition_name IS NOT NULL`, tableArgs(table)...)
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			return fmt.Errorf("table %s is not partitioned in prod", table)
+		}
+		have := make(map[string]bool, len(existing))
+		for _, p := range existing {
+			have[p] = true
+		}
+		var missing []string
+		for _, p := range wanted {
+			if !have[p] {
+				missing = append(missing, p)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("table %s has no partitions %s in prod (available: %s)",
+				table, strings.Join(missing, ", "), strings.Join(existing, ", "))
+		}
+	}
+	return nil
+}
+
+// explainPartitionError turns MySQL's "no partition for value" error into one that
+// says which dev table rejected the copied rows and what to do about it.
+func explainPartitionError(table string, err error) error {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == errNoPartitionForValue {
+		return fmt.Errorf("dev table %s is partitioned and has no partition for some of the copied rows; "+
+			"add matching partitions in dev or restrict the prod fetch with the partitions option: %w", table, err)
+	}
+	return err
+}
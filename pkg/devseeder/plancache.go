@@ -0,0 +1,138 @@
+package devseeder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// WithPlanCache keeps the computed closure (the IDs of every table's rows) in dir
+// and reuses it while the requested tables, partitions and prod schema are the
+// same and it is younger than maxAge (0: no limit; negative: plan again and
+// replace it), skipping the discovery phase.
+// Prod rows added or changed since are then not picked up; the cached closure
+// is also the starting point of incremental runs, which only copy the rows dev
+// does not have yet.
+func WithPlanCache(dir string, maxAge time.Duration) Option {
+	return func(s *Seeder) {
+		s.planCacheDir = dir
+		s.planCacheMaxAge = maxAge
+	}
+}
+
+// savedPlan is a CopyPlan as stored on disk, with sorted ID lists
+type savedPlan struct {
+	CreatedAt time.Time                    `json:"created_at"`
+	Order     []string                     `json:"order"`
+	RowSets   map[string][]int64           `json:"row_sets"`
+	Full      map[string]FullTable         `json:"full,omitempty"`
+	Seeds     map[string]int               `json:"seeds"`
+	Reasons   map[string][]InclusionReason `json:"reasons,omitempty"`
+}
+
+func newSavedPlan(plan *CopyPlan) savedPlan {
+	sp := savedPlan{CreatedAt: time.Now().UTC(), Order: plan.Order, Full: plan.Full, Seeds: plan.Seeds, Reasons: plan.Reasons,
+		RowSets: make(map[string][]int64, len(plan.RowSets))}
+	for table, idSet := range plan.RowSets {
+		ids := make([]int64, 0, len(idSet))
+		for id := range idSet {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		sp.RowSets[table] = ids
+	}
+	return sp
+}
+
+func (sp savedPlan) plan() *CopyPlan {
+	plan := &CopyPlan{Order: sp.Order, Full: sp.Full, Seeds: sp.Seeds, Reasons: sp.Reasons,
+		RowSets: make(map[string]map[int64]bool, len(sp.RowSets))}
+	for table, ids := range sp.RowSets {
+		idSet := make(map[int64]bool, len(ids))
+		for _, id := range ids {
+			idSet[id] = true
+		}
+		plan.RowSets[table] = idSet
+	}
+	return plan
+}
+
+// planCacheKey hashes what a plan depends on: prod's server, schemas and schema
+// version, the FKs, the requested tables with their limits, partitions and order, the
+// reference table threshold and the edge limits
+func planCacheKey(ctx context.Context, prodDB *DB, allFks []ForeignKey, opts SyncOptions) (string, error) {
+	identity, version, err := schemaVersion(ctx, prodDB)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s/%s\n", identity, version)
+	fks := make([]string, len(allFks))
+	for i, fk := range allFks {
+		fks[i] = fmt.Sprintf("%s.%s>%s.%s:%v", fk.FromTable, fk.FromColumn, fk.ToTable, fk.ToColumn, fk.IsNullable)
+	}
+	sort.Strings(fks)
+	for _, fk := range fks {
+		fmt.Fprintln(h, fk)
+	}
+	tables := make([]string, 0, len(opts.Tables))
+	for t := range opts.Tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		fmt.Fprintf(h, "table %s %d %q %q\n", t, opts.Tables[t], opts.Partitions[t], opts.OrderBy[t])
+	}
+	fmt.Fprintf(h, "reference tables %d\n", opts.ReferenceTableRows)
+	fmt.Fprintf(h, "edge limits %s\n", newEdgeLimits(opts.EdgeLimits, allFks))
+	return hex.EncodeToString(h.Sum(nil)[:12]), nil
+}
+
+// buildPlan is BuildCopyPlan going through the plan cache, if there is one
+func (s *Seeder) buildPlan(ctx context.Context, allFks []ForeignKey, opts SyncOptions) (*CopyPlan, error) {
+	if s.planCacheDir == "" {
+		return BuildCopyPlan(ctx, s.prod, allFks, opts)
+	}
+	key, err := planCacheKey(ctx, s.prod, allFks, opts)
+	if err != nil {
+		logf(ctx, "Warning: not caching the plan: %v", err)
+		return BuildCopyPlan(ctx, s.prod, allFks, opts)
+	}
+	path := filepath.Join(s.planCacheDir, "plan-"+key+".json")
+
+	if data, err := os.ReadFile(path); err == nil && s.planCacheMaxAge >= 0 {
+		var sp savedPlan
+		switch err := json.Unmarshal(data, &sp); {
+		case err != nil:
+			logf(ctx, "Warning: ignoring corrupt plan cache %s: %v", path, err)
+		case s.planCacheMaxAge > 0 && time.Since(sp.CreatedAt) > s.planCacheMaxAge:
+			logf(ctx, "Cached plan from %s is too old; planning again", sp.CreatedAt.Local().Format(time.DateTime))
+		default:
+			logf(ctx, "Reusing the plan computed %s ago; rows added to prod since are not included",
+				time.Since(sp.CreatedAt).Round(time.Second))
+			return sp.plan(), nil
+		}
+	}
+
+	plan, err := BuildCopyPlan(ctx, s.prod, allFks, opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(newSavedPlan(plan))
+	if err == nil {
+		if err = os.MkdirAll(s.planCacheDir, 0o700); err == nil {
+			err = os.WriteFile(path, data, 0o600)
+		}
+	}
+	if err != nil {
+		logf(ctx, "Warning: cannot cache the plan: %v", err)
+	}
+	return plan, nil
+}
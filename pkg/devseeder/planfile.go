@@ -0,0 +1,86 @@
+package devseeder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// planFileVersion is the format ReadPlanFile understands
+const planFileVersion = 1
+
+// PlanFile is a plan exported with Seeder.WritePlan and run later with
+// WithPlanFile, so planning (following the FK closure, which needs wide read
+// access to prod) and applying it (which only reads the planned rows, by ID)
+// can be done by different people on different machines.
+type PlanFile struct {
+	Plan          *CopyPlan
+	ForeignKeys   []ForeignKey // prod's FKs when planned, so applying does not read them
+	SchemaVersion string       // of prod when planned
+	CreatedAt     time.Time
+}
+
+// planFileJSON is a PlanFile as written to disk
+type planFileJSON struct {
+	Version       int          `json:"version"`
+	SchemaVersion string       `json:"schema_version"`
+	ForeignKeys   []ForeignKey `json:"foreign_keys"`
+	savedPlan
+}
+
+// WritePlan writes plan to w as a JSON plan file (see PlanFile), with the
+// prod FKs it was computed from.
+func (s *Seeder) WritePlan(ctx context.Context, plan *CopyPlan, w io.Writer) error {
+	if s.prod == nil {
+		return fmt.Errorf("writing a plan needs a prod database")
+	}
+	ctx = ContextWithLogger(ctx, s.logger)
+	allFks, _, err := s.prodForeignKeys(ctx)
+	if err != nil {
+		return err
+	}
+	_, version, err := schemaVersion(ctx, s.prod)
+	if err != nil {
+		return fmt.Errorf("reading the prod schema version: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(planFileJSON{Version: planFileVersion, SchemaVersion: version, ForeignKeys: allFks,
+		savedPlan: newSavedPlan(plan)})
+}
+
+// ReadPlanFile reads a plan file written by Seeder.WritePlan.
+func ReadPlanFile(r io.Reader) (*PlanFile, error) {
+	var pf planFileJSON
+	if err := json.NewDecoder(r).Decode(&pf); err != nil {
+		return nil, fmt.Errorf("reading plan file: %w", err)
+	}
+	if pf.Version != planFileVersion {
+		return nil, fmt.Errorf("plan file version %d is not supported (expected %d)", pf.Version, planFileVersion)
+	}
+	return &PlanFile{Plan: pf.plan(), ForeignKeys: pf.ForeignKeys, SchemaVersion: pf.SchemaVersion,
+		CreatedAt: pf.CreatedAt}, nil
+}
+
+// WithPlanFile makes Sync copy the rows of an exported plan instead of planning:
+// the requested tables, FKs and IDs all come from pf. Rows are still read from
+// prod (or whatever source prod stands for) at sync time, so rows changed since
+// are copied as they are now; rows deleted since are missing.
+func WithPlanFile(pf *PlanFile) Option {
+	return func(s *Seeder) { s.planFile = pf }
+}
+
+// checkPlanFile warns when prod's schema changed since the plan file was made
+func (s *Seeder) checkPlanFile(ctx context.Context) {
+	logf(ctx, "Applying the plan made %s: %d rows in %d tables",
+		s.planFile.CreatedAt.Local().Format(time.DateTime), s.planFile.Plan.TotalRows(), len(s.planFile.Plan.Order))
+	_, version, err := schemaVersion(ctx, s.prod)
+	switch {
+	case err != nil:
+		logf(ctx, "Warning: cannot compare the prod schema with the plan's: %v", err)
+	case version != s.planFile.SchemaVersion:
+		logf(ctx, "Warning: the prod schema changed since the plan was made; it is applied as it is")
+	}
+}
@@ -0,0 +1,156 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// grantee is the current account formatted the way information_schema lists it: 'user'@'host'
+const granteeExpr = `CONCAT("'", SUBSTRING_INDEX(CURRENT_USER(), '@', 1), "'@'", SUBSTRING_INDEX(CURRENT_USER(), '@', -1), "'")`
+
+// privileges holds the privileges the connected account has on the traversed schemas
+type privileges struct {
+	global map[string]bool            // *.* grants
+	schema map[string]map[string]bool // db.* grants; "" is the connection's schema
+	table  map[string]map[string]bool // db.table grants, tables named as qualifiedName does
+}
+
+// has reports whether priv (e.g. "SELECT") is granted on table at any level
+func (p privileges) has(table, priv string) bool {
+	schema, _, _ := splitTable(table)
+	return p.global[priv] || p.schema[schema][priv] || p.table[table][priv]
+}
+
+// fetchPrivileges reads the account's grants from information_schema.
+// Privileges that only come from activated roles are not visible there.
+func fetchPrivileges(ctx context.Context, db *DB) (privileges, error) {
+	p := privileges{
+		global: make(map[string]bool),
+		schema: make(map[string]map[string]bool),
+		table:  make(map[string]map[string]bool),
+	}
+
+	filter, args := db.schemaFilter("table_schema")
+	queries := []struct {
+		q     string
+		args  []interface{}
+		grant map[string]map[string]bool
+	}{
+		{`SELECT '', privilege_type FROM information_schema.user_privileges WHERE grantee = ` + granteeExpr, nil, nil},
+		{`SELECT IF(table_schema = DATABASE(), '', table_schema), privilege_type FROM information_schema.schema_privileges
+		WHERE grantee = ` + granteeExpr + ` AND ` + filter, args, p.schema},
+		{`SELECT ` + qualifiedName("table_schema", "table_name") + `, privilege_type FROM information_schema.table_privileges
+		WHERE grantee = ` + granteeExpr + ` AND ` + filter, args, p.table},
+	}
+	for _, q := range queries {
+		rows, err := db.QueryContext(ctx, q.q, q.args...)
+		if err != nil {
+			return p, err
+		}
+		for rows.Next() {
+			var name, priv string
+			if err := rows.Scan(&name, &priv); err != nil {
+				rows.Close()
+				return p, err
+			}
+			if q.grant == nil {
+				p.global[priv] = true
+				continue
+			}
+			if q.grant[name] == nil {
+				q.grant[name] = make(map[string]bool)
+			}
+			q.grant[name][priv] = true
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// reachableTables returns the requested tables plus every parent table the BFS
+// could pull rows from, without looking at any data.
+func reachableTables(allFks []ForeignKey, requested map[string]int) []string {
+	parents := make(map[string][]string)
+	for _, fk := range allFks {
+		if fk.FromTable == fk.ToTable || fk.IsNullable {
+			continue
+		}
+		parents[fk.FromTable] = append(parents[fk.FromTable], fk.ToTable)
+	}
+
+	seen := make(map[string]bool)
+	var queue []string
+	for t := range requested {
+		seen[t] = true
+		queue = append(queue, t)
+	}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		for _, p := range parents[t] {
+			if !seen[p] {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	tables := make([]string, 0, len(seen))
+	for t := range seen {
+		tables = append(tables, t)
+	}
+	slices.Sort(tables)
+	return tables
+}
+
+// devPrivilegesFor lists the dev privileges needed by the chosen options
+func devPrivilegesFor(opts SyncOptions) []string {
+	privs := []string{"INSERT", "ALTER"} // ALTER for AUTO_INCREMENT counters
+	if opts.ResetTables {
+		privs = append(privs, "DROP") // TRUNCATE TABLE requires DROP
+		if opts.BackupSuffix != "" {
+			privs = append(privs, "CREATE", "SELECT")
+		}
+	}
+	if opts.Undo != nil {
+		privs = append(privs, "DELETE") // needed later by devseeder undo
+	}
+	return privs
+}
+
+// PreflightChecks verifies up front that the prod account can read every table the
+// sync may touch and the dev account can write them, and reports all missing grants at once.
+func PreflightChecks(ctx context.Context, prodDB, devDB *DB, allFks []ForeignKey, opts SyncOptions) error {
+	tables := reachableTables(allFks, opts.Tables)
+
+	prodPrivs, err := fetchPrivileges(ctx, prodDB)
+	if err != nil {
+		return fmt.Errorf("cannot read prod privileges: %w", err)
+	}
+	devPrivs, err := fetchPrivileges(ctx, devDB)
+	if err != nil {
+		return fmt.Errorf("cannot read dev privileges: %w", err)
+	}
+
+	var missing []string
+	for _, table := range tables {
+		if !prodPrivs.has(table, "SELECT") {
+			missing = append(missing, fmt.Sprintf("prod: SELECT on %s", table))
+		}
+		for _, priv := range devPrivilegesFor(opts) {
+			if !devPrivs.has(opts.devTable(table), priv) {
+				missing = append(missing, fmt.Sprintf("dev: %s on %s", priv, opts.devTable(table)))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing grants:\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package devseeder
+
+// Progress stages reported while copying a plan
+const (
+	StagePlanned      = "planned"       // the plan is ready; Queue lists the tables to copy
+	StageTableStarted = "table_started" // about to fetch Table's rows from prod
+	StageRowsCopied   = "rows_copied"   // another batch of Table's rows is in dev
+	StageTableCopied  = "table_copied"  // Table's rows are in dev
+)
+
+// ProgressEvent reports how far a sync has got
+type ProgressEvent struct {
+	Stage       string `json:"stage"`
+	Table       string `json:"table"`
+	Rows        int    `json:"rows"`         // rows planned for Table
+	RowsDone    int    `json:"rows_done"`    // rows of Table inserted so far
+	TablesDone  int    `json:"tables_done"`  // tables fully copied so far
+	TablesTotal int    `json:"tables_total"` // tables with rows to copy
+
+	TotalRowsDone int `json:"total_rows_done"` // rows inserted so far, all tables
+	TotalRows     int `json:"total_rows"`      // rows planned, all tables
+
+	Queue []string `json:"queue,omitempty"` // StagePlanned only: tables to copy, in order
+}
+
+// ProgressFunc receives progress events. It is called synchronously from the
+// copy loop, so it should return quickly.
+type ProgressFunc func(ProgressEvent)
+
+// report sends ev to the configured ProgressFunc, if any
+func (o SyncOptions) report(ev ProgressEvent) {
+	if o.Progress != nil {
+		o.Progress(ev)
+	}
+}
@@ -0,0 +1,84 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithPrune deletes, after the copy, the dev rows of the copied tables that were
+// deleted from prod since they were seeded (see pruneRemovedRows), so repeated
+// incremental runs keep dev a faithful subset of prod.
+func WithPrune() Option {
+	return func(s *Seeder) { s.opts.Prune = true }
+}
+
+// pruneRemovedRows deletes the dev rows of every table in plan whose IDs prod no
+// longer has. Rows created locally do not exist in prod either; they are told
+// apart by their IDs, which AUTO_INCREMENT hands out past every seeded row (see
+// syncAutoIncrement): only rows below the highest ID dev shares with prod are
+// deleted. Children are pruned before their parents.
+func pruneRemovedRows(ctx context.Context, prodDB, devDB *DB, plan *CopyPlan, opts SyncOptions) error {
+	total := 0
+	for i := len(plan.Order) - 1; i >= 0; i-- {
+		table := plan.Order[i]
+		devTable := opts.devTable(table)
+		devIDs, err := allIDs(ctx, devDB, devTable)
+		if err != nil {
+			return fmt.Errorf("listing dev %s IDs: %w", devTable, err)
+		}
+		var removed []int64
+		var highestShared int64
+		for _, batch := range idBatches(devIDs, copyBatchSize) {
+			inProd, err := existingIDs(ctx, prodDB, table, batch)
+			if err != nil {
+				return fmt.Errorf("looking up prod %s IDs: %w", table, err)
+			}
+			for id := range batch {
+				if inProd[id] {
+					highestShared = max(highestShared, id)
+				} else {
+					removed = append(removed, id)
+				}
+			}
+		}
+		doomed := make(map[int64]bool)
+		for _, id := range removed {
+			if id < highestShared {
+				doomed[id] = true
+			}
+		}
+		if len(doomed) == 0 {
+			continue
+		}
+		for _, batch := range idBatches(doomed, copyBatchSize) {
+			args := idArgs(batch)
+			if _, err := devDB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)",
+				quoteTable(devTable), QuoteIdent(idColumn), placeholders(len(args))), args...); err != nil {
+				return fmt.Errorf("pruning dev %s: %w", devTable, err)
+			}
+		}
+		logf(ctx, "Pruned %d rows from %s that were deleted in prod", len(doomed), devTable)
+		total += len(doomed)
+	}
+	logf(ctx, "Pruned %d rows deleted in prod since they were seeded", total)
+	return nil
+}
+
+// allIDs returns every ID in table
+func allIDs(ctx context.Context, db *DB, table string) (map[int64]bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s", QuoteIdent(idColumn), quoteTable(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
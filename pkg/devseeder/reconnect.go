@@ -0,0 +1,154 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// WithReconnect re-opens dropped connections to prod and dev up to attempts times
+// in a row (0, the default, fails the run on the first drop). The statement that
+// hit the drop is run again once the connection is back, after dev's session
+// settings (foreign_key_checks, sql_mode, the run lock) are restored, so the copy
+// resumes with the batch it was writing. Charset settings come from the DSN and
+// apply to every new connection. prod reads have no snapshot to restore: rows
+// read after a reconnection may be newer than the ones read before.
+func WithReconnect(attempts int) Option {
+	return func(s *Seeder) {
+		for _, db := range []*DB{s.prod, s.dev} {
+			if db != nil {
+				db.session.attempts = attempts
+			}
+		}
+	}
+}
+
+// reconnectBackoff is how long the first reconnection attempt waits; each further
+// attempt waits that much longer
+const reconnectBackoff = time.Second
+
+// session holds what reconnect needs: the attempts allowed and the settings a
+// new connection must get to continue where the dropped one stopped
+type session struct {
+	attempts int
+
+	mu       sync.Mutex
+	settings []sessionSetting
+	connID   int64 // of the connection the settings were last applied to
+}
+
+type sessionSetting struct {
+	name  string
+	apply func(ctx context.Context) error
+}
+
+// connectionLost reports whether err means the connection dropped, rather than
+// the server rejecting the statement
+func connectionLost(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
+
+// keepSession records a session setting that was just made, so reconnect applies
+// it again on a new connection. apply must talk to db.DB directly.
+func (db *DB) keepSession(ctx context.Context, name string, apply func(ctx context.Context) error) {
+	if db.session.attempts == 0 {
+		return
+	}
+	db.session.mu.Lock()
+	defer db.session.mu.Unlock()
+	db.session.settings = slices.DeleteFunc(db.session.settings, func(s sessionSetting) bool { return s.name == name })
+	db.session.settings = append(db.session.settings, sessionSetting{name, apply})
+	_ = db.DB.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&db.session.connID)
+}
+
+// forgetSession drops a setting that was reverted
+func (db *DB) forgetSession(name string) {
+	db.session.mu.Lock()
+	defer db.session.mu.Unlock()
+	db.session.settings = slices.DeleteFunc(db.session.settings, func(s sessionSetting) bool { return s.name == name })
+}
+
+// retry runs fn, reconnecting and running it again while it fails on a dropped
+// connection
+func (db *DB) retry(ctx context.Context, fn func() error) error {
+	err := fn()
+	for i := 0; i < db.session.attempts && connectionLost(err) && ctx.Err() == nil; i++ {
+		if rerr := db.reconnect(ctx, err); rerr != nil {
+			return rerr
+		}
+		err = fn()
+	}
+	return err
+}
+
+// reconnect opens a new connection in place of the dropped one and restores the
+// session settings on it, waiting a little longer before each further attempt
+func (db *DB) reconnect(ctx context.Context, cause error) error {
+	logf(ctx, "Warning: lost the %s connection (%v), reconnecting\n", db.Name, cause)
+	var err error
+	for attempt := 1; attempt <= db.session.attempts; attempt++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * reconnectBackoff):
+		}
+		if err = db.DB.PingContext(ctx); err == nil {
+			if err = db.restoreSession(ctx); err == nil {
+				logf(ctx, "Reconnected to %s (attempt %d)", db.Name, attempt)
+				return nil
+			}
+			if !connectionLost(err) {
+				return fmt.Errorf("restoring the %s session after a reconnection: %w", db.Name, err)
+			}
+		}
+	}
+	return fmt.Errorf("reconnecting to %s after %d attempts: %w", db.Name, db.session.attempts, err)
+}
+
+// restoreSession applies the session settings to the current connection
+func (db *DB) restoreSession(ctx context.Context) error {
+	db.session.mu.Lock()
+	defer db.session.mu.Unlock()
+	if len(db.session.settings) == 0 {
+		return nil
+	}
+	if err := db.DB.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&db.session.connID); err != nil {
+		return err
+	}
+	for _, s := range db.session.settings {
+		if err := s.apply(ctx); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+// checkSession restores the session settings if the pool replaced the connection
+// without an error reaching us, e.g. when it found the idle one dead
+func (db *DB) checkSession(ctx context.Context) error {
+	db.session.mu.Lock()
+	want, kept := db.session.connID, len(db.session.settings) > 0
+	db.session.mu.Unlock()
+	if !kept {
+		return nil
+	}
+	var id int64
+	if err := db.retry(ctx, func() error {
+		return db.DB.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id)
+	}); err != nil {
+		return err
+	}
+	if id == want {
+		return nil
+	}
+	logf(ctx, "Warning: the %s connection was replaced, restoring its session settings", db.Name)
+	return db.restoreSession(ctx)
+}
@@ -0,0 +1,68 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithReferenceTables copies tables with at most maxRows rows in full when rows
+// in the plan reference them, through any FK including nullable ones, so lookup
+// tables (countries, currencies, statuses, ...) need not be listed one by one.
+func WithReferenceTables(maxRows int) Option {
+	return func(s *Seeder) { s.opts.ReferenceTableRows = maxRows }
+}
+
+// referenceTables tracks the small parent tables BuildCopyPlan copies in full
+type referenceTables struct {
+	maxRows   int
+	parentsOf map[string][]ForeignKey // child table -> its FKs to other tables
+	small     map[string]bool         // tables already sized
+	included  map[string]bool
+}
+
+func newReferenceTables(allFks []ForeignKey, maxRows int) *referenceTables {
+	r := &referenceTables{maxRows: maxRows, parentsOf: make(map[string][]ForeignKey),
+		small: make(map[string]bool), included: make(map[string]bool)}
+	for _, fk := range allFks {
+		if fk.FromTable != fk.ToTable {
+			r.parentsOf[fk.FromTable] = append(r.parentsOf[fk.FromTable], fk)
+		}
+	}
+	return r
+}
+
+// include adds every row of table to idSet if the table is small, once. It
+// returns how many IDs were new.
+func (r *referenceTables) include(ctx context.Context, db *DB, table string, idSet map[int64]bool) (int, error) {
+	if r.included[table] {
+		return 0, nil
+	}
+	small, sized := r.small[table]
+	if !sized {
+		// Count no further than the threshold, however big the table is
+		var n int
+		q := fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s LIMIT %d) t", quoteTable(table), r.maxRows+1)
+		if err := db.QueryRowContext(ctx, q).Scan(&n); err != nil {
+			return 0, fmt.Errorf("counting rows of %s: %w", table, err)
+		}
+		small = n <= r.maxRows
+		r.small[table] = small
+	}
+	if !small {
+		return 0, nil
+	}
+	r.included[table] = true
+	ids, err := fetchSomeIDs(ctx, db, table, nil, "", r.maxRows)
+	if err != nil {
+		return 0, fmt.Errorf("fetchSomeIDs error for table %s: %w", table, err)
+	}
+	added := 0
+	for _, id := range ids {
+		if !idSet[id] {
+			idSet[id] = true
+			added++
+		}
+	}
+	logf(ctx, "Including reference table %s in full (%d rows)", table, len(ids))
+	return added, nil
+}
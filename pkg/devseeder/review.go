@@ -0,0 +1,113 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// PlanReview is the outcome of reviewing a plan before anything is written.
+type PlanReview struct {
+	// Skip lists tables to leave out of the copy; rows of other tables may then
+	// reference rows dev does not have
+	Skip []string
+	// Limits sets new row limits for requested tables; the subset is planned
+	// again and reviewed once more
+	Limits map[string]int
+}
+
+// PlanReviewFunc inspects a computed plan, e.g. by asking the user about each table.
+type PlanReviewFunc func(ctx context.Context, plan *CopyPlan) (PlanReview, error)
+
+// WithPlanReview lets fn approve, prune or re-limit the plan of a sync before any
+// data moves. An error from fn aborts the sync.
+func WithPlanReview(fn PlanReviewFunc) Option {
+	return func(s *Seeder) { s.review = fn }
+}
+
+// reviewPlan runs the review until no limit changes, replanning in between, and
+// returns the reviewed plan and the options with the final limits
+func (s *Seeder) reviewPlan(ctx context.Context, allFks []ForeignKey, plan *CopyPlan, opts SyncOptions) (*CopyPlan, SyncOptions, error) {
+	skipped := make(map[string]bool)
+	for {
+		r, err := s.review(ctx, plan)
+		if err != nil {
+			return nil, opts, err
+		}
+		for _, t := range r.Skip {
+			if _, ok := plan.RowSets[t]; !ok {
+				return nil, opts, fmt.Errorf("review: table %s is not in the plan", t)
+			}
+			skipped[t] = true
+		}
+		changed := false
+		for t, limit := range r.Limits {
+			if _, ok := opts.Tables[t]; !ok {
+				return nil, opts, fmt.Errorf("review: table %s is not a requested table", t)
+			}
+			if opts.Tables[t] != limit {
+				if !changed {
+					opts.Tables = maps.Clone(opts.Tables)
+				}
+				opts.Tables[t] = limit
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		if plan, err = BuildCopyPlan(ctx, s.prod, allFks, opts); err != nil {
+			return nil, opts, err
+		}
+	}
+	return skipPlanTables(ctx, allFks, plan, skipped), opts, nil
+}
+
+// skipPlanTables removes tables from plan, warning about the FKs left dangling
+func skipPlanTables(ctx context.Context, allFks []ForeignKey, plan *CopyPlan, skip map[string]bool) *CopyPlan {
+	if len(skip) == 0 {
+		return plan
+	}
+	out := &CopyPlan{
+		RowSets: make(map[string]map[int64]bool, len(plan.RowSets)),
+		Full:    make(map[string]FullTable, len(plan.Full)),
+		Seeds:   make(map[string]int, len(plan.Seeds)),
+		Reasons: plan.Reasons,
+	}
+	for _, t := range plan.Order {
+		if !skip[t] {
+			out.Order = append(out.Order, t)
+		}
+	}
+	for t, ids := range plan.RowSets {
+		if !skip[t] {
+			out.RowSets[t] = ids
+		}
+	}
+	for t, f := range plan.Full {
+		if !skip[t] {
+			out.Full[t] = f
+		}
+	}
+	for t, limit := range plan.Seeds {
+		if !skip[t] {
+			out.Seeds[t] = limit
+		}
+	}
+
+	tables := make([]string, 0, len(skip))
+	for t := range skip {
+		tables = append(tables, t)
+	}
+	slices.Sort(tables)
+	for _, t := range tables {
+		logf(ctx, "Skipping table %s (%d rows) as reviewed", t, plan.Rows(t))
+		for _, fk := range allFks {
+			if fk.ToTable == t && fk.FromTable != t && out.Rows(fk.FromTable) > 0 {
+				logf(ctx, "Warning: copied rows of %s may reference rows of skipped table %s through %s", fk.FromTable, t, fk.FromColumn)
+			}
+		}
+	}
+	return out
+}
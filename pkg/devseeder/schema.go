@@ -0,0 +1,239 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// fetchColumns returns the column names of table in the current database, in ordinal order
+func fetchColumns(ctx context.Context, db *DB, table string) ([]string, error) {
+	infos, err := db.tableColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]string, len(infos))
+	for i, c := range infos {
+		cols[i] = c.Name
+	}
+	return cols, nil
+}
+
+// copyableColumns returns the columns of table that can be copied with INSERT ... SELECT:
+// every column including invisible ones, except generated columns.
+func copyableColumns(ctx context.Context, db *DB, table string) ([]string, error) {
+	columns, err := fetchColumns(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	generated, err := fetchGeneratedColumns(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	columns, _, _ = projectColumns(columns, nil, func(c string) bool { return !generated[c] })
+	return columns, nil
+}
+
+// fetchGeneratedColumns returns the STORED/VIRTUAL generated columns of table.
+// Columns with an expression default (extra = DEFAULT_GENERATED) are regular columns and not included.
+func fetchGeneratedColumns(ctx context.Context, db *DB, table string) (map[string]bool, error) {
+	infos, err := db.tableColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	generated := make(map[string]bool)
+	for _, c := range infos {
+		if c.generated() {
+			generated[c.Name] = true
+		}
+	}
+	return generated, nil
+}
+
+// projectColumns keeps only the columns for which keep returns true, dropping
+// the others from every row. It returns the kept columns, the projected rows and
+// the names of the dropped columns.
+func projectColumns(columns []string, rowsData [][]interface{}, keep func(string) bool) ([]string, [][]interface{}, []string) {
+	var idx []int
+	var kept, dropped []string
+	for i, c := range columns {
+		if keep(c) {
+			idx = append(idx, i)
+			kept = append(kept, c)
+		} else {
+			dropped = append(dropped, c)
+		}
+	}
+	if len(dropped) == 0 {
+		return columns, rowsData, nil
+	}
+
+	projected := make([][]interface{}, len(rowsData))
+	for r, row := range rowsData {
+		out := make([]interface{}, len(idx))
+		for j, i := range idx {
+			out[j] = row[i]
+		}
+		projected[r] = out
+	}
+	return kept, projected, dropped
+}
+
+// intersectColumns keeps only the prod columns that also exist in dev and drops the
+// rest from every row. Columns that only exist in dev are left to their defaults.
+func intersectColumns(ctx context.Context, table string, columns []string, rowsData [][]interface{}, devColumns []string) ([]string, [][]interface{}) {
+	inDev := make(map[string]bool, len(devColumns))
+	for _, c := range devColumns {
+		inDev[c] = true
+	}
+	columns, rowsData, dropped := projectColumns(columns, rowsData, func(c string) bool { return inDev[c] })
+	if len(dropped) > 0 {
+		logf(ctx, "Table %s: skipping prod-only columns %v", table, dropped)
+	}
+	return columns, rowsData
+}
+
+// renameColumns returns columns with the prod names in renames ({ prodColumn :
+// devColumn }) replaced by their dev names; the row values keep their positions
+func renameColumns(columns []string, renames map[string]string) []string {
+	if len(renames) == 0 {
+		return columns
+	}
+	renamed := make([]string, len(columns))
+	for i, c := range columns {
+		if dev, ok := renames[c]; ok {
+			c = dev
+		}
+		renamed[i] = c
+	}
+	return renamed
+}
+
+// columnValue is a dev column written with an SQL expression instead of a copied value
+type columnValue struct {
+	column, expr string
+}
+
+// columnValueList returns a table's { column : expression } setting in column order
+func columnValueList(m map[string]string) []columnValue {
+	values := make([]columnValue, 0, len(m))
+	for c, expr := range m {
+		values = append(values, columnValue{c, expr})
+	}
+	slices.SortFunc(values, func(a, b columnValue) int { return strings.Compare(a.column, b.column) })
+	return values
+}
+
+// normalizeColumnMap rewrites the table keys of a per-table column setting to
+// prod's spelling among tables, and refuses setting the id column the copy is
+// keyed on; name is the setting's name in errors
+func normalizeColumnMap[V any](name string, m map[string]map[string]V, tables []string) (map[string]map[string]V, error) {
+	for table, columns := range m {
+		if _, ok := columns[idColumn]; ok {
+			return nil, fmt.Errorf("%s: the %s column of %s cannot be set", name, idColumn, table)
+		}
+	}
+	return normalizeTableKeys(m, tables), nil
+}
+
+// normalizeTableKeys rewrites the keys of a per-table setting to prod's spelling
+// among tables; keys matching none are kept as they are
+func normalizeTableKeys[V any](m map[string]V, tables []string) map[string]V {
+	normalized := make(map[string]V, len(m))
+	for table, v := range m {
+		if i := slices.IndexFunc(tables, func(t string) bool { return strings.EqualFold(t, table) }); i >= 0 {
+			table = tables[i]
+		}
+		normalized[table] = v
+	}
+	return normalized
+}
+
+// syncAutoIncrement moves the AUTO_INCREMENT counter of a dev table past the highest
+// seeded ID, so rows created locally afterwards don't collide with seeded ones.
+// Tables without an AUTO_INCREMENT column are left alone.
+func syncAutoIncrement(ctx context.Context, db *DB, table string) error {
+	infos, err := db.tableColumns(ctx, table)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(infos, columnInfo.autoIncrement)
+	if i < 0 {
+		return nil
+	}
+	column := infos[i].Name
+
+	var maxID sql.NullInt64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(%s) FROM %s", QuoteIdent(column), quoteTable(table))).Scan(&maxID); err != nil {
+		return err
+	}
+	if !maxID.Valid {
+		return nil
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %d", quoteTable(table), maxID.Int64+1))
+	return err
+}
+
+// CreateMissingTables creates every prod base table that does not exist in dev,
+// from prod's own DDL. Foreign key checks must be off so tables can be created in any order.
+// Tables in tableMap ({ prodTable : devTable }) are looked up and created under their dev name.
+func CreateMissingTables(ctx context.Context, prodDB, devDB *DB, tableMap map[string]string) error {
+	const q = `SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'`
+	prodTables, err := listNames(ctx, prodDB, q)
+	if err != nil {
+		return err
+	}
+	devTables, err := listNames(ctx, devDB, q)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(devTables))
+	for _, t := range devTables {
+		existing[strings.ToLower(t)] = true
+	}
+	mapped := make(map[string]string, len(tableMap))
+	for prod, dev := range tableMap {
+		mapped[strings.ToLower(prod)] = dev
+	}
+	devName := func(t string) string {
+		if dev, ok := mapped[strings.ToLower(t)]; ok {
+			return dev
+		}
+		return t
+	}
+
+	for _, t := range prodTables {
+		dev := devName(t)
+		if existing[strings.ToLower(dev)] {
+			continue
+		}
+		ddl, err := showCreateTable(ctx, prodDB, t)
+		if err != nil {
+			return fmt.Errorf("SHOW CREATE TABLE %s: %w", t, err)
+		}
+		if len(mapped) > 0 {
+			ddl = renameTablesInDDL(ddl, t, devName)
+		}
+		if _, err := devDB.ExecContext(ctx, ddl); err != nil {
+			return fmt.Errorf("creating table %s in dev: %w", dev, err)
+		}
+		logf(ctx, "Created table %s in dev", dev)
+		devDB.resetColumns()
+	}
+	return nil
+}
+
+var referencesPattern = regexp.MustCompile("REFERENCES `((?:[^`]|``)+)`")
+
+// renameTablesInDDL rewrites prod's CREATE TABLE for table to use the dev names
+// devName gives, for the table itself and the parents its foreign keys reference
+func renameTablesInDDL(ddl, table string, devName func(string) string) string {
+	ddl = strings.Replace(ddl, "CREATE TABLE "+QuoteIdent(table), "CREATE TABLE "+QuoteIdent(devName(table)), 1)
+	return referencesPattern.ReplaceAllStringFunc(ddl, func(m string) string {
+		parent := strings.ReplaceAll(referencesPattern.FindStringSubmatch(m)[1], "``", "`")
+		return "REFERENCES " + QuoteIdent(devName(parent))
+	})
+}
@@ -0,0 +1,88 @@
+package devseeder
+
+import (
+	"strings"
+	"sync"
+)
+
+// qualifiedSchemas are the schemas ever named with WithSchemas. Only a table
+// name starting with one of them and a dot is read as "schema.table": names
+// that merely contain a dot, like `a.b`, stay whole.
+var qualifiedSchemas struct {
+	sync.RWMutex
+	names map[string]bool
+}
+
+// WithSchemas also traverses the tables of these schemas on the prod server, and
+// the FKs between them and the connection's schema. Their tables are named
+// "schema.table" wherever tables are named (WithTables, WithTableMap, ...) and are
+// written to the schemas of the same name on the dev server.
+func WithSchemas(schemas ...string) Option {
+	return func(s *Seeder) {
+		qualifiedSchemas.Lock()
+		if qualifiedSchemas.names == nil {
+			qualifiedSchemas.names = make(map[string]bool)
+		}
+		for _, schema := range schemas {
+			qualifiedSchemas.names[schema] = true
+		}
+		qualifiedSchemas.Unlock()
+		for _, db := range []*DB{s.prod, s.dev} {
+			if db != nil {
+				db.schemas = schemas
+				db.resetColumns()
+			}
+		}
+	}
+}
+
+// schemaFilter returns the condition selecting the rows of information_schema
+// whose column (e.g. "table_schema") is one of db's schemas, and its arguments
+func (db *DB) schemaFilter(column string) (string, []interface{}) {
+	if len(db.schemas) == 0 {
+		return column + " = DATABASE()", nil
+	}
+	args := make([]interface{}, len(db.schemas))
+	for i, s := range db.schemas {
+		args[i] = s
+	}
+	return "(" + column + " = DATABASE() OR " + column + " IN (" + placeholders(len(args)) + "))", args
+}
+
+// qualifiedName is the SQL expression naming a table of information_schema the
+// way DevSeeder does: bare in the connection's schema, "schema.table" elsewhere
+func qualifiedName(schemaColumn, tableColumn string) string {
+	return "IF(" + schemaColumn + " = DATABASE(), " + tableColumn + ", CONCAT(" + schemaColumn + ", '.', " + tableColumn + "))"
+}
+
+// splitTable splits a table name qualified with one of the schemas of
+// WithSchemas into the schema and the table; ok is false for any other name
+func splitTable(name string) (schema, table string, ok bool) {
+	schema, table, ok = strings.Cut(name, ".")
+	if !ok {
+		return "", name, false
+	}
+	qualifiedSchemas.RLock()
+	defer qualifiedSchemas.RUnlock()
+	if !qualifiedSchemas.names[schema] {
+		return "", name, false
+	}
+	return schema, table, true
+}
+
+// quoteTable quotes a table name that may be qualified with its schema
+func quoteTable(name string) string {
+	if schema, table, ok := splitTable(name); ok {
+		return QuoteIdent(schema) + "." + QuoteIdent(table)
+	}
+	return QuoteIdent(name)
+}
+
+// tableArgs returns the arguments of "table_schema = COALESCE(?, DATABASE()) AND
+// table_name = ?" for a table name that may be qualified with its schema
+func tableArgs(name string) []interface{} {
+	if schema, table, ok := splitTable(name); ok {
+		return []interface{}{schema, table}
+	}
+	return []interface{}{nil, name}
+}
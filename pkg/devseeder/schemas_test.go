@@ -0,0 +1,29 @@
+package devseeder
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestQuoteTableDottedNames(t *testing.T) {
+	// Register the schema as a sync configured with it would
+	WithSchemas("billing_test")(&Seeder{})
+
+	tests := []struct {
+		name, quoted string
+		args         []interface{}
+	}{
+		{"users", "`users`", []interface{}{nil, "users"}},
+		{"a.b", "`a.b`", []interface{}{nil, "a.b"}}, // a dot in a plain table name
+		{"billing_test.invoices", "`billing_test`.`invoices`", []interface{}{"billing_test", "invoices"}},
+		{"billing_test.x.y", "`billing_test`.`x.y`", []interface{}{"billing_test", "x.y"}},
+	}
+	for _, tc := range tests {
+		if got := quoteTable(tc.name); got != tc.quoted {
+			t.Errorf("quoteTable(%q) = %s, want %s", tc.name, got, tc.quoted)
+		}
+		if got := tableArgs(tc.name); !slices.Equal(got, tc.args) {
+			t.Errorf("tableArgs(%q) = %v, want %v", tc.name, got, tc.args)
+		}
+	}
+}
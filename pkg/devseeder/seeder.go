@@ -0,0 +1,603 @@
+// Package devseeder copies a referentially complete subset of a production MySQL
+// database into a development one: it seeds the requested tables, follows foreign
+// keys to every parent row they need, and inserts the result in dependency order.
+//
+// The usual entry point is a Seeder:
+//
+//	s := devseeder.New(prodDB, devDB,
+//		devseeder.WithTables(map[string]int{"orders": 1000}),
+//		devseeder.WithLogger(logger),
+//	)
+//	err := s.Sync(ctx)
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// Seeder copies subsets from a prod database to a dev database.
+// Build one with New; it is not safe for concurrent use.
+type Seeder struct {
+	prod, dev *DB
+	logger    Logger
+	opts      SyncOptions
+
+	backup        bool
+	lockTimeout   int
+	preflight     bool
+	createMissing bool
+	copyViews     bool
+	copyTriggers  bool
+	copyRoutines  bool
+	trackState    bool
+	review        PlanReviewFunc
+
+	planFile        *PlanFile
+	planCacheDir    string
+	planCacheMaxAge time.Duration
+	beforeSync      []func(context.Context) error
+	verification    Verification
+}
+
+// Option configures a Seeder.
+type Option func(*Seeder)
+
+// ErrInvalidOptions is wrapped by the errors Sync returns for options that
+// contradict each other, before anything is read.
+var ErrInvalidOptions = errors.New("invalid options")
+
+// WithTables sets the tables to seed and how many rows to take from each; 0
+// copies the whole table (see FullTable).
+func WithTables(tables map[string]int) Option {
+	return func(s *Seeder) { s.opts.Tables = tables }
+}
+
+// WithLogger sends progress messages and warnings to l instead of the standard logger.
+func WithLogger(l Logger) Option {
+	return func(s *Seeder) { s.logger = l }
+}
+
+// WithAuditLog records every statement run on either database in audit.
+func WithAuditLog(audit *AuditLog) Option {
+	return func(s *Seeder) {
+		if s.prod != nil {
+			s.prod.audit = audit
+		}
+		if s.dev != nil {
+			s.dev.audit = audit
+		}
+	}
+}
+
+// WithResetTables truncates dev tables before copying into them. With backup,
+// each table is first copied to <table>_backup_<timestamp> (see Restore).
+func WithResetTables(backup bool) Option {
+	return func(s *Seeder) {
+		s.opts.ResetTables = true
+		s.backup = backup
+	}
+}
+
+// WithUndoLog records the IDs of every inserted row in u, so they can be deleted later.
+func WithUndoLog(u *UndoLog) Option {
+	return func(s *Seeder) { s.opts.Undo = u }
+}
+
+// WithColumnIntersection inserts only the columns present in both prod and dev,
+// relying on dev defaults for the rest, instead of failing on schema drift.
+func WithColumnIntersection() Option {
+	return func(s *Seeder) { s.opts.ColumnIntersection = true }
+}
+
+// WithDisabledTriggers drops dev triggers on the copied tables during the load and
+// recreates them afterwards; their definitions are saved to a file in backupDir.
+func WithDisabledTriggers(backupDir string) Option {
+	return func(s *Seeder) {
+		s.opts.DisableTriggers = true
+		s.opts.TriggerBackupDir = backupDir
+	}
+}
+
+// WithPartitions restricts seed selection on partitioned prod tables: { table : [partitions] }.
+func WithPartitions(partitions map[string][]string) Option {
+	return func(s *Seeder) { s.opts.Partitions = partitions }
+}
+
+// WithOrderBy chooses which rows of a requested table are seeded: the first
+// ones by an SQL ORDER BY list, e.g. { "tickets": "priority DESC, created_at DESC" },
+// instead of the lowest IDs. Ties are broken by id.
+func WithOrderBy(orderBy map[string]string) Option {
+	return func(s *Seeder) { s.opts.OrderBy = orderBy }
+}
+
+// WithProgress calls fn as each table is started and finished. It can be given
+// several times; every fn receives every event.
+func WithProgress(fn ProgressFunc) Option {
+	return func(s *Seeder) {
+		if prev := s.opts.Progress; prev != nil {
+			s.opts.Progress = func(ev ProgressEvent) {
+				prev(ev)
+				fn(ev)
+			}
+			return
+		}
+		s.opts.Progress = fn
+	}
+}
+
+// WithLockTimeout waits up to seconds for another run on the same dev database to
+// finish instead of failing right away.
+func WithLockTimeout(seconds int) Option {
+	return func(s *Seeder) { s.lockTimeout = seconds }
+}
+
+// WithoutPreflight skips the privilege checks run before anything is written.
+func WithoutPreflight() Option {
+	return func(s *Seeder) { s.preflight = false }
+}
+
+// WithTableMap writes the rows of prod tables into differently named dev tables:
+// { prodTable : devTable }. Truncation, verification and pruning use the dev names too.
+func WithTableMap(m map[string]string) Option {
+	return func(s *Seeder) { s.opts.TableMap = m }
+}
+
+// WithColumnMap inserts prod columns into differently named dev columns:
+// { prodTable : { prodColumn : devColumn } }, e.g. while a rename is rolled out.
+func WithColumnMap(m map[string]map[string]string) Option {
+	return func(s *Seeder) { s.opts.ColumnMap = m }
+}
+
+// WithColumnValues writes dev columns with SQL expressions instead of the prod
+// values, or fills columns prod does not have: { prodTable : { devColumn : expr } },
+// e.g. {"users": {"password_hash": "'$2a$10$...'", "environment": "'dev'"}}.
+func WithColumnValues(m map[string]map[string]string) Option {
+	return func(s *Seeder) { s.opts.ColumnValues = m }
+}
+
+// WithCreateMissingTables creates prod tables missing from dev before seeding.
+func WithCreateMissingTables() Option {
+	return func(s *Seeder) { s.createMissing = true }
+}
+
+// WithCopyViews recreates prod views in dev after the data copy.
+func WithCopyViews() Option {
+	return func(s *Seeder) { s.copyViews = true }
+}
+
+// WithCopyTriggers creates prod triggers missing from dev after the data copy.
+func WithCopyTriggers() Option {
+	return func(s *Seeder) { s.copyTriggers = true }
+}
+
+// WithCopyRoutines creates prod stored procedures and functions missing from dev.
+func WithCopyRoutines() Option {
+	return func(s *Seeder) { s.copyRoutines = true }
+}
+
+// WithVerification runs checks on dev once the data is copied (see Verification).
+func WithVerification(v Verification) Option {
+	return func(s *Seeder) { s.verification = v }
+}
+
+// WithBeforeSync runs fn once the dev lock is held and before anything is read,
+// e.g. to apply migrations.
+func WithBeforeSync(fn func(ctx context.Context) error) Option {
+	return func(s *Seeder) { s.beforeSync = append(s.beforeSync, fn) }
+}
+
+// New returns a Seeder reading from prod and writing to dev. dev may be nil for a
+// Seeder that only plans and exports, prod for one that only restores or undoes.
+// The caller keeps ownership of both handles; dev is limited to a single
+// connection so session settings apply to every write.
+func New(prod, dev *sql.DB, opts ...Option) *Seeder {
+	s := &Seeder{
+		logger:    log.Default(),
+		preflight: true,
+	}
+	if prod != nil {
+		s.prod = NewDB(prod, "prod", nil)
+	}
+	if dev != nil {
+		dev.SetMaxOpenConns(1)
+		s.dev = NewDB(dev, "dev", nil)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Plan computes the subset to copy without writing anything.
+func (s *Seeder) Plan(ctx context.Context) (plan *CopyPlan, err error) {
+	if s.prod == nil {
+		return nil, fmt.Errorf("plan needs a prod database")
+	}
+	ctx = ContextWithLogger(ctx, s.logger)
+	ctx, span := startSpan(ctx, "devseeder.Plan")
+	defer func() { finishSpan(span, err) }()
+
+	allFks, tables, err := s.prodForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildPlan(ctx, allFks, s.planOptions(tables))
+}
+
+// Estimate sizes plan from prod's table statistics (see EstimatePlan).
+func (s *Seeder) Estimate(ctx context.Context, plan *CopyPlan) (*Estimate, error) {
+	return EstimatePlan(ContextWithLogger(ctx, s.logger), s.prod, plan)
+}
+
+// Graph writes the prod FK graph to w in Graphviz DOT (see WriteDOT). With closure,
+// the subset is planned first and only the tables it would copy are drawn.
+func (s *Seeder) Graph(ctx context.Context, w io.Writer, closure bool) error {
+	if s.prod == nil {
+		return fmt.Errorf("graph needs a prod database")
+	}
+	ctx = ContextWithLogger(ctx, s.logger)
+	allFks, tables, err := s.prodForeignKeys(ctx)
+	if err != nil {
+		return err
+	}
+	var plan *CopyPlan
+	if closure {
+		if plan, err = BuildCopyPlan(ctx, s.prod, allFks, s.planOptions(tables)); err != nil {
+			return err
+		}
+	}
+	return WriteDOT(w, allFks, plan, tables)
+}
+
+// planOptions returns the options a plan of tables depends on
+func (s *Seeder) planOptions(tables map[string]int) SyncOptions {
+	return SyncOptions{Tables: tables, Partitions: s.opts.Partitions, OrderBy: s.opts.OrderBy,
+		ReferenceTableRows: s.opts.ReferenceTableRows, EdgeLimits: s.opts.EdgeLimits}
+}
+
+// prodForeignKeys returns every prod FK and the requested tables, with table
+// names spelled the way prod compares them.
+func (s *Seeder) prodForeignKeys(ctx context.Context) ([]ForeignKey, map[string]int, error) {
+	allFks, err := FetchAllForeignKeys(ctx, s.prod)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching all FKs: %w", err)
+	}
+	allFks, tables, _, err := normalizeTableNames(ctx, s.prod, nil, allFks, s.opts.Tables, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("normalizing table names: %w", err)
+	}
+	return allFks, tables, nil
+}
+
+// Sync copies the subset into dev. Session settings on dev are restored before it
+// returns, even on error or cancellation.
+func (s *Seeder) Sync(ctx context.Context) (err error) {
+	if s.prod == nil || s.dev == nil {
+		return fmt.Errorf("sync needs both a prod and a dev database")
+	}
+	if (s.opts.WriteStrategy == WriteIgnore || s.opts.WriteStrategy == WriteAppend) && s.opts.ResetTables {
+		return fmt.Errorf("%w: write strategy %q keeps existing dev rows, which resetting tables would delete", ErrInvalidOptions, s.opts.WriteStrategy)
+	}
+	if s.opts.WriteStrategy == WriteAppend && s.opts.Prune {
+		return fmt.Errorf("%w: pruning cannot tell local rows from seeded ones with write strategy %q", ErrInvalidOptions, WriteAppend)
+	}
+	if err := validConflictStrategies(s.opts.Conflicts); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	if err := validFKChecks(s.opts.FKChecks); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	seeds := s.opts.Tables
+	if s.planFile != nil {
+		seeds = s.planFile.Plan.Seeds
+	}
+	for table, limit := range seeds {
+		if strategy := s.opts.Conflicts[table]; limit == 0 && (strategy == ConflictRemap || strategy == ConflictFail) {
+			return fmt.Errorf("%w: conflict strategy %q needs the IDs of %s up front, which a table copied whole (limit 0) does not have", ErrInvalidOptions, strategy, table)
+		}
+	}
+	if s.opts.Multiply.Factor > 1 && (s.opts.Incremental || s.opts.Prune) {
+		return fmt.Errorf("%w: multiplied rows sit above prod's IDs; incremental runs and pruning would treat them as prod rows", ErrInvalidOptions)
+	}
+	ctx = ContextWithLogger(ctx, s.logger)
+	ctx, span := startSpan(ctx, "devseeder.Sync")
+	defer func() { finishSpan(span, err) }()
+
+	unlock, err := AcquireRunLock(ctx, s.dev, s.lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, fn := range s.beforeSync {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+	// Hooks such as migrations may have changed the dev schema
+	s.dev.resetColumns()
+
+	// By setting foreign_key_checks to 0, we can disable foreign key constraints during data synchronization.
+	// This allows us to perform operations that would otherwise violate foreign key constraints.
+	if s.opts.fkChecksOff() {
+		restoreFKChecks := disableFKChecks(ctx, s.dev)
+		defer restoreFKChecks()
+	}
+
+	if s.opts.ZeroDates == ZeroDatesRelax {
+		restoreSQLMode, err := relaxZeroDates(ctx, s.dev)
+		if err != nil {
+			return err
+		}
+		defer restoreSQLMode()
+	}
+
+	if s.createMissing {
+		if err := CreateMissingTables(ctx, s.prod, s.dev, s.opts.TableMap); err != nil {
+			return fmt.Errorf("creating missing tables: %w", err)
+		}
+	}
+
+	// Fetch all foreign keys from the production database, unless an exported
+	// plan brings them along with the tables it was made for
+	requested := s.opts.Tables
+	var allFks []ForeignKey
+	if s.planFile != nil {
+		s.checkPlanFile(ctx)
+		allFks, requested = s.planFile.ForeignKeys, s.planFile.Plan.Seeds
+	} else if allFks, err = FetchAllForeignKeys(ctx, s.prod); err != nil {
+		return fmt.Errorf("fetching all FKs: %w", err)
+	}
+
+	// Compare table names the way the servers do (lower_case_table_names)
+	allFks, tables, devTableNames, err := normalizeTableNames(ctx, s.prod, s.dev, allFks, requested, s.opts.TableMap)
+	if err != nil {
+		return fmt.Errorf("normalizing table names: %w", err)
+	}
+
+	opts := s.opts
+	opts.Tables = tables
+	opts.DevTableNames = devTableNames
+	if opts.ColumnMap, err = normalizeColumnMap("column_map", opts.ColumnMap, reachableTables(allFks, tables)); err != nil {
+		return err
+	}
+	if opts.ColumnValues, err = normalizeColumnMap("column_values", opts.ColumnValues, reachableTables(allFks, tables)); err != nil {
+		return err
+	}
+	opts.Conflicts = normalizeTableKeys(opts.Conflicts, reachableTables(allFks, tables))
+	if s.trackState {
+		// Record whatever was inserted, even if the sync fails halfway
+		state := NewUndoLog()
+		state.next, opts.Undo = opts.Undo, state
+		defer func() {
+			if stateErr := recordSeededRows(context.WithoutCancel(ctx), s.dev, state); stateErr != nil {
+				if err == nil {
+					err = stateErr
+				} else {
+					logf(ctx, "Warning: %v", stateErr)
+				}
+			}
+		}()
+	}
+	if opts.ResetTables && s.backup {
+		opts.BackupSuffix = newBackupSuffix()
+		logf(ctx, "Dev tables will be backed up with suffix %s before truncating; undo with: devseeder restore %s",
+			opts.BackupSuffix, strings.TrimPrefix(opts.BackupSuffix, backupInfix))
+	}
+
+	if err := CheckCharsets(ctx, s.prod, s.dev, reachableTables(allFks, tables)); err != nil {
+		return fmt.Errorf("charset checks: %w", err)
+	}
+	if s.preflight {
+		if err := PreflightChecks(ctx, s.prod, s.dev, allFks, opts); err != nil {
+			return fmt.Errorf("pre-flight checks failed: %w", err)
+		}
+	}
+	var plan *CopyPlan
+	if s.planFile != nil {
+		plan = s.planFile.Plan
+	} else if plan, err = s.buildPlan(ctx, allFks, opts); err != nil {
+		return err
+	}
+	if s.review != nil {
+		if plan, opts, err = s.reviewPlan(ctx, allFks, plan, opts); err != nil {
+			return err
+		}
+	}
+	// Leave out the rows dev keeps; only the rows written are verified
+	switch {
+	case opts.WriteStrategy == WriteAppend:
+		plan, err = appendRowsPlan(ctx, s.dev, plan, opts)
+	case (opts.Incremental || opts.WriteStrategy == WriteIgnore) && !opts.ResetTables:
+		plan, err = newRowsPlan(ctx, s.dev, plan, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkPlanSize(plan, opts.MaxRows); err != nil {
+		return err
+	}
+	if err := runSQLHooks(ctx, s.dev, "before_run", opts.Hooks.BeforeRun); err != nil {
+		return err
+	}
+	if err := copyPlan(ctx, s.prod, s.dev, allFks, plan, opts); err != nil {
+		return err
+	}
+	if opts.Multiply.Factor > 1 {
+		if err := multiplyRows(context.WithoutCancel(ctx), s.prod, s.dev, allFks, plan, opts); err != nil {
+			return fmt.Errorf("multiplying rows: %w", err)
+		}
+	}
+	if opts.Prune {
+		if err := pruneRemovedRows(ctx, s.prod, s.dev, plan, opts); err != nil {
+			return err
+		}
+	}
+	if opts.FixturesDir != "" {
+		if err := ApplyFixtures(ctx, s.dev, allFks, opts.FixturesDir, opts); err != nil {
+			return fmt.Errorf("applying fixtures: %w", err)
+		}
+	}
+	if err := s.verify(ctx, allFks, plan, opts); err != nil {
+		return err
+	}
+
+	if s.copyViews {
+		if err := CopyViews(ctx, s.prod, s.dev); err != nil {
+			return fmt.Errorf("copying views: %w", err)
+		}
+	}
+	if s.copyRoutines {
+		if err := CopyMissingRoutines(ctx, s.prod, s.dev); err != nil {
+			return fmt.Errorf("copying routines: %w", err)
+		}
+	}
+	if s.copyTriggers {
+		if err := CopyMissingTriggers(ctx, s.prod, s.dev); err != nil {
+			return fmt.Errorf("copying triggers: %w", err)
+		}
+	}
+	return runSQLHooks(ctx, s.dev, "after_run", opts.Hooks.AfterRun)
+}
+
+// Dump writes plan as SQL to w: CREATE TABLE IF NOT EXISTS plus INSERTs, or
+// mysqldump-compatible output when mysqldump is set.
+func (s *Seeder) Dump(ctx context.Context, plan *CopyPlan, w io.Writer, mysqldump bool) error {
+	ctx = s.exportContext(ctx)
+	if mysqldump {
+		return DumpPlanMysqldump(ctx, s.prod, plan, w)
+	}
+	return DumpPlan(ctx, s.prod, plan, w)
+}
+
+// ExportNDJSON writes plan as one <table>.ndjson file per table into dir.
+func (s *Seeder) ExportNDJSON(ctx context.Context, plan *CopyPlan, dir string, artifact ArtifactOptions) error {
+	return ExportNDJSON(s.exportContext(ctx), s.prod, plan, dir, artifact)
+}
+
+// ExportFixtures writes plan as go-testfixtures YAML files into dir.
+func (s *Seeder) ExportFixtures(ctx context.Context, plan *CopyPlan, dir string) error {
+	return ExportFixtures(s.exportContext(ctx), s.prod, plan, dir)
+}
+
+// ExportGoFixtures writes plan as Go source of package pkg (see ExportGoFixtures).
+func (s *Seeder) ExportGoFixtures(ctx context.Context, plan *CopyPlan, w io.Writer, pkg string) error {
+	return ExportGoFixtures(s.exportContext(ctx), s.prod, plan, w, pkg)
+}
+
+// ExportParquet writes plan as one <table>.parquet file per table into dir.
+func (s *Seeder) ExportParquet(ctx context.Context, plan *CopyPlan, dir string) error {
+	return ExportParquet(s.exportContext(ctx), s.prod, plan, dir)
+}
+
+// exportContext carries the Seeder's logger and row transforms to the exporters
+func (s *Seeder) exportContext(ctx context.Context) context.Context {
+	return contextWithTransforms(ContextWithLogger(ctx, s.logger), s.opts.Transforms)
+}
+
+// Restore puts dev tables back from the backups taken by a previous sync with
+// WithResetTables(true). The latest backup is used when stamp is empty.
+func (s *Seeder) Restore(ctx context.Context, stamp string) error {
+	return s.withDevSession(ctx, func(ctx context.Context) error {
+		return RestoreBackups(ctx, s.dev, stamp)
+	})
+}
+
+// Snapshot saves the dev tables and their rows into dir (see WriteSnapshot).
+func (s *Seeder) Snapshot(ctx context.Context, dir, name string) (m *SnapshotManifest, err error) {
+	err = s.withDevSession(ctx, func(ctx context.Context) error {
+		m, err = WriteSnapshot(ctx, s.dev, dir, name)
+		return err
+	})
+	return m, err
+}
+
+// RestoreSnapshot puts the dev tables back as saved by Snapshot into dir.
+func (s *Seeder) RestoreSnapshot(ctx context.Context, dir string) error {
+	return s.withDevSession(ctx, func(ctx context.Context) error {
+		return RestoreSnapshot(ctx, s.dev, dir)
+	})
+}
+
+// TagDataset saves the dev tables into dir as dataset version (see WriteDataset).
+func (s *Seeder) TagDataset(ctx context.Context, dir, version string) (m *SnapshotManifest, err error) {
+	err = s.withDevSession(ctx, func(ctx context.Context) error {
+		m, err = WriteDataset(ctx, s.dev, dir, version)
+		return err
+	})
+	return m, err
+}
+
+// ApplyDataset puts the dataset saved by TagDataset into dir back into dev.
+func (s *Seeder) ApplyDataset(ctx context.Context, dir string) error {
+	return s.withDevSession(ctx, func(ctx context.Context) error {
+		return ApplyDataset(ctx, s.dev, dir)
+	})
+}
+
+// Undo deletes the rows recorded in an undo script (see UndoLog.WriteScript) from dev.
+func (s *Seeder) Undo(ctx context.Context, script string) error {
+	return s.withDevSession(ctx, func(ctx context.Context) error {
+		return ApplyUndoScript(ctx, s.dev, script)
+	})
+}
+
+// LoadScript executes every statement of an SQL script, such as one written by
+// Dump, against dev and returns how many it ran.
+func (s *Seeder) LoadScript(ctx context.Context, r io.Reader) (int, error) {
+	count := 0
+	err := s.withDevSession(ctx, func(ctx context.Context) error {
+		return SplitSQLStatements(r, func(stmt string) error {
+			if _, err := s.dev.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("statement %d failed: %w", count+1, err)
+			}
+			count++
+			return nil
+		})
+	})
+	return count, err
+}
+
+// withDevSession runs fn holding the dev lock with foreign key checks off.
+func (s *Seeder) withDevSession(ctx context.Context, fn func(context.Context) error) error {
+	if s.dev == nil {
+		return fmt.Errorf("no dev database")
+	}
+	ctx = ContextWithLogger(ctx, s.logger)
+
+	unlock, err := AcquireRunLock(ctx, s.dev, s.lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	restoreFKChecks := disableFKChecks(ctx, s.dev)
+	defer restoreFKChecks()
+	return fn(ctx)
+}
+
+// disableFKChecks turns off foreign_key_checks on dev and returns a func that turns
+// them back on. The returned func ignores cancellation so it always runs.
+func disableFKChecks(ctx context.Context, devDB *DB) func() {
+	if _, err := devDB.ExecContext(ctx, "SET foreign_key_checks = 0"); err != nil {
+		logf(ctx, "Warning: cannot disable foreign_key_checks: %v\n", err)
+	} else {
+		devDB.keepSession(ctx, "foreign_key_checks", func(ctx context.Context) error {
+			_, err := devDB.DB.ExecContext(ctx, "SET foreign_key_checks = 0")
+			return err
+		})
+	}
+	return func() {
+		devDB.forgetSession("foreign_key_checks")
+		if _, err := devDB.ExecContext(context.WithoutCancel(ctx), "SET foreign_key_checks = 1"); err != nil {
+			logf(ctx, "Warning: cannot re-enable foreign_key_checks: %v\n", err)
+		}
+	}
+}
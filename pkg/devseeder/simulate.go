@@ -0,0 +1,175 @@
+package devseeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+// tableStats are prod's information_schema statistics of one table
+type tableStats struct {
+	rows         int64 // TABLE_ROWS, an InnoDB estimate
+	avgRowLength int64
+}
+
+// SimulateClosure approximates the plan of the requested tables without reading
+// any IDs: seed tables contribute min(limit, rows), and n rows of a child table
+// reference about d*(1-(1-n/N)^(N/d)) parent rows, where N is the child's row
+// count and d the number of distinct values of the FK column (the cardinality of
+// its index). Row counts come from table statistics, so the result is a quick
+// guide for choosing limits, not a count; partitions are not taken into account.
+// Tables are in copy order.
+func SimulateClosure(ctx context.Context, prodDB *DB, allFks []ForeignKey, tables map[string]int) (*Estimate, error) {
+	var edges []ForeignKey
+	for _, fk := range allFks {
+		// The same FKs the BFS of BuildCopyPlan follows
+		if fk.FromTable != fk.ToTable && !fk.IsNullable {
+			edges = append(edges, fk)
+		}
+	}
+
+	stats := make(map[string]tableStats)
+	statsOf := func(table string) (tableStats, error) {
+		if st, ok := stats[table]; ok {
+			return st, nil
+		}
+		var rows, avg sql.NullInt64
+		err := prodDB.QueryRowContext(ctx, `
+		SELECT table_rows, avg_row_length
+		FROM information_schema.tables
+		WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ?`, tableArgs(table)...).Scan(&rows, &avg)
+		if err != nil && err != sql.ErrNoRows {
+			return tableStats{}, fmt.Errorf("reading statistics of %s: %w", table, err)
+		}
+		st := tableStats{rows: rows.Int64, avgRowLength: avg.Int64}
+		stats[table] = st
+		return st, nil
+	}
+	distinct := make(map[ForeignKey]int64)
+	for _, fk := range edges {
+		d, err := distinctValues(ctx, prodDB, fk.FromTable, fk.FromColumn)
+		if err != nil {
+			return nil, err
+		}
+		distinct[fk] = d
+	}
+
+	estimates := make(map[string]float64)
+	for table, limit := range tables {
+		st, err := statsOf(table)
+		if err != nil {
+			return nil, err
+		}
+		estimates[table] = math.Min(float64(limit), float64(st.rows))
+	}
+	seeds := make(map[string]float64, len(estimates))
+	for t, n := range estimates {
+		seeds[t] = n
+	}
+
+	// Relax until no estimate grows; estimates only grow and are capped by the
+	// table sizes, and the pass limit keeps FK cycles from converging slowly
+	for pass := 0; pass <= len(edges); pass++ {
+		next := make(map[string]float64, len(seeds))
+		for t, n := range seeds {
+			next[t] = n
+		}
+		for _, fk := range edges {
+			n := estimates[fk.FromTable]
+			if n == 0 {
+				continue
+			}
+			child, err := statsOf(fk.FromTable)
+			if err != nil {
+				return nil, err
+			}
+			parent, err := statsOf(fk.ToTable)
+			if err != nil {
+				return nil, err
+			}
+			next[fk.ToTable] = math.Min(next[fk.ToTable]+referencedRows(n, float64(child.rows), float64(distinct[fk])), float64(parent.rows))
+		}
+		changed := false
+		for t, n := range next {
+			if math.Round(n) > math.Round(estimates[t]) {
+				changed = true
+			}
+			next[t] = math.Max(n, estimates[t])
+		}
+		estimates = next
+		if !changed {
+			break
+		}
+	}
+
+	var reached []string
+	for t, n := range estimates {
+		if math.Round(n) > 0 {
+			reached = append(reached, t)
+		}
+	}
+	order, err := partialTopoSort(allFks, reached)
+	if err != nil {
+		return nil, fmt.Errorf("topoSort error: %w", err)
+	}
+	est := &Estimate{}
+	for _, table := range order {
+		rows := int(math.Round(estimates[table]))
+		t := TableEstimate{Table: table, Rows: rows, Bytes: int64(rows) * stats[table].avgRowLength}
+		est.Tables = append(est.Tables, t)
+		est.Rows += t.Rows
+		est.Bytes += t.Bytes
+	}
+	return est, nil
+}
+
+// referencedRows is the expected number of distinct parent rows that n of a
+// child's N rows reference, when its FK column has d distinct values
+func referencedRows(n, N, d float64) float64 {
+	if N <= 0 || d <= 0 {
+		return 0
+	}
+	if n >= N {
+		return d
+	}
+	return d * (1 - math.Pow(1-n/N, N/d))
+}
+
+// distinctValues returns the number of distinct values of column: the
+// cardinality of an index starting with it, or COUNT(DISTINCT) when no index
+// has statistics
+func distinctValues(ctx context.Context, db *DB, table, column string) (int64, error) {
+	var card sql.NullInt64
+	args := append(tableArgs(table), column)
+	err := db.QueryRowContext(ctx, `
+	SELECT MAX(cardinality)
+	FROM information_schema.statistics
+	WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ? AND column_name = ? AND seq_in_index = 1`, args...).Scan(&card)
+	if err != nil {
+		return 0, fmt.Errorf("reading index statistics of %s.%s: %w", table, column, err)
+	}
+	if card.Int64 > 0 {
+		return card.Int64, nil
+	}
+	var n int64
+	q := fmt.Sprintf("SELECT COUNT(DISTINCT %s) FROM %s", QuoteIdent(column), quoteTable(table))
+	if err := db.QueryRowContext(ctx, q).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting distinct values of %s.%s: %w", table, column, err)
+	}
+	return n, nil
+}
+
+// Simulate approximates the size of the planned subset from statistics alone
+// (see SimulateClosure), which is much faster than Plan on a large prod.
+func (s *Seeder) Simulate(ctx context.Context) (*Estimate, error) {
+	if s.prod == nil {
+		return nil, fmt.Errorf("simulate needs a prod database")
+	}
+	ctx = ContextWithLogger(ctx, s.logger)
+	allFks, tables, err := s.prodForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return SimulateClosure(ctx, s.prod, allFks, tables)
+}
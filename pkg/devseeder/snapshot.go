@@ -0,0 +1,221 @@
+package devseeder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// snapshotManifest is the name of the metadata file in a snapshot directory
+const snapshotManifest = "manifest.json"
+
+// SnapshotManifest describes a snapshot of the dev database: one <table>.sql
+// file per table next to a manifest.json holding this.
+type SnapshotManifest struct {
+	Name      string         `json:"name"`
+	CreatedAt time.Time      `json:"created_at"`
+	Database  string         `json:"database"`
+	Tables    map[string]int `json:"tables"` // rows per table
+	Order     []string       `json:"order"`  // tables in the order they were written
+
+	// Content checksums per table (see tableChecksum), for datasets
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// ReadSnapshotManifest reads the manifest of the snapshot in dir.
+func ReadSnapshotManifest(dir string) (*SnapshotManifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, snapshotManifest))
+	if err != nil {
+		return nil, err
+	}
+	var m SnapshotManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, snapshotManifest), err)
+	}
+	return &m, nil
+}
+
+// WriteSnapshot saves every base table of the dev database (backup tables
+// excepted) into dir: its DDL and rows as <table>.sql, plus a manifest. The
+// manifest is written last, so a directory without one is an incomplete snapshot.
+func WriteSnapshot(ctx context.Context, db *DB, dir, name string) (*SnapshotManifest, error) {
+	var database string
+	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database); err != nil {
+		return nil, err
+	}
+	tables, err := listNames(ctx, db,
+		`SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	m := &SnapshotManifest{Name: name, CreatedAt: time.Now().UTC(), Database: database, Tables: make(map[string]int)}
+	for _, table := range tables {
+		if strings.Contains(table, backupInfix) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := snapshotTable(ctx, db, dir, table)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot of %s: %w", table, err)
+		}
+		m.Tables[table] = n
+		m.Order = append(m.Order, table)
+		logEvent(ctx, fmt.Sprintf("Saved %d rows of table %s", n, table), "table", table, "rows", n)
+	}
+
+	return m, writeSnapshotManifest(dir, m)
+}
+
+// writeSnapshotManifest (re)writes the manifest of the snapshot in dir
+func writeSnapshotManifest(dir string, m *SnapshotManifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, snapshotManifest), append(b, '\n'), 0o600)
+}
+
+// snapshotFile returns the file table is saved to in the snapshot in dir. The
+// name is escaped so no table name (e.g. with a "/") can point outside dir;
+// plain names are kept as they are.
+func snapshotFile(dir, table string) string {
+	return filepath.Join(dir, url.PathEscape(table)+".sql")
+}
+
+// snapshotTable writes table to dir/<table>.sql and returns its row count.
+// Rows are streamed in INSERT batches so large tables are not held in memory.
+func snapshotTable(ctx context.Context, db *DB, dir, table string) (int, error) {
+	ddl, err := showCreateTable(ctx, db, table)
+	if err != nil {
+		return 0, err
+	}
+	columns, err := copyableColumns(ctx, db, table)
+	if err != nil {
+		return 0, err
+	}
+	types, err := fetchColumnTypes(ctx, db, table)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(snapshotFile(dir, table), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "DROP TABLE IF EXISTS %s;\n%s;\n", quoteTable(table), ddl)
+
+	q := fmt.Sprintf("SELECT %s FROM %s", quoteIdents(columns), quoteTable(table))
+	if slices.Contains(columns, idColumn) {
+		q += " ORDER BY " + QuoteIdent(idColumn)
+	}
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	batch := make([][]interface{}, 0, dumpBatchSize)
+	for rows.Next() {
+		vals := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return 0, err
+		}
+		for i, c := range columns {
+			if b, ok := vals[i].([]byte); ok && types[c].DataType == "bit" {
+				vals[i] = bitValue(b)
+			}
+		}
+		batch = append(batch, vals)
+		count++
+		if len(batch) == dumpBatchSize {
+			writeInserts(w, table, columns, batch, types)
+			batch = batch[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	writeInserts(w, table, columns, batch, types)
+
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	return count, f.Close()
+}
+
+// RestoreSnapshot recreates the tables saved in the snapshot in dir with the
+// rows they held. Tables created after the snapshot are left alone. Foreign key
+// checks must be off, as tables are recreated in name order.
+func RestoreSnapshot(ctx context.Context, db *DB, dir string) error {
+	m, err := readCompleteSnapshot(dir)
+	if err != nil {
+		return err
+	}
+	var database string
+	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database); err != nil {
+		return err
+	}
+	if database != m.Database {
+		return fmt.Errorf("snapshot %s was taken of database %s, not %s", m.Name, m.Database, database)
+	}
+	return restoreSnapshotTables(ctx, db, dir, m)
+}
+
+// restoreSnapshotTables applies the table files of the snapshot in dir
+func restoreSnapshotTables(ctx context.Context, db *DB, dir string, m *SnapshotManifest) error {
+	logf(ctx, "Restoring %d tables from snapshot %s (%s)", len(m.Order), m.Name, m.CreatedAt.Local().Format(time.DateTime))
+	for _, table := range m.Order {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := applySQLFile(ctx, db, snapshotFile(dir, table)); err != nil {
+			return fmt.Errorf("restore error on %s: %w", table, err)
+		}
+		logEvent(ctx, fmt.Sprintf("Restored %d rows of table %s", m.Tables[table], table), "table", table, "rows", m.Tables[table])
+	}
+	db.resetColumns()
+	return nil
+}
+
+// readCompleteSnapshot reads the manifest in dir, telling an incomplete snapshot apart
+func readCompleteSnapshot(dir string) (*SnapshotManifest, error) {
+	m, err := ReadSnapshotManifest(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s is not a complete snapshot (no %s)", dir, snapshotManifest)
+	}
+	return m, err
+}
+
+// applySQLFile executes every statement of the SQL script at path against db
+func applySQLFile(ctx context.Context, db *DB, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return SplitSQLStatements(f, func(stmt string) error {
+		_, err := db.ExecContext(ctx, stmt)
+		return err
+	})
+}
@@ -0,0 +1,99 @@
+package devseeder
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// SplitSQLStatements reads an SQL script (e.g. mysqldump output) and calls fn for each
+// statement, without its terminating delimiter. It understands quoted strings and
+// identifiers, comments, and DELIMITER lines. Version-guarded /*!...*/ comments are
+// kept, since they are executable.
+func SplitSQLStatements(r io.Reader, fn func(stmt string) error) error {
+	br := bufio.NewReaderSize(r, 1<<20)
+	delimiter := []byte(";")
+	var stmt strings.Builder
+	var quote byte   // current quote char, 0 when outside quotes
+	inBlock := false // inside /* */ (non-executable) comment
+	var line []byte
+
+	flush := func() error {
+		s := strings.TrimSpace(stmt.String())
+		stmt.Reset()
+		if s == "" {
+			return nil
+		}
+		return fn(s)
+	}
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+		line = append(line[:0], chunk...)
+		if err == bufio.ErrBufferFull {
+			// very long line (extended INSERT): keep reading until the newline
+			for err == bufio.ErrBufferFull {
+				chunk, err = br.ReadSlice('\n')
+				line = append(line, chunk...)
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if quote == 0 && !inBlock && stmt.Len() == 0 {
+			trimmed := bytes.TrimSpace(line)
+			if len(trimmed) > len("DELIMITER ") && bytes.EqualFold(trimmed[:len("DELIMITER ")], []byte("DELIMITER ")) {
+				delimiter = bytes.Clone(bytes.TrimSpace(trimmed[len("DELIMITER "):]))
+				line = line[:0]
+			}
+		}
+
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			switch {
+			case inBlock:
+				if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+					inBlock = false
+					i++
+				}
+				continue
+			case quote != 0:
+				stmt.WriteByte(c)
+				if c == '\\' && quote != '`' && i+1 < len(line) {
+					stmt.WriteByte(line[i+1])
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			}
+
+			switch {
+			case c == '\'' || c == '"' || c == '`':
+				quote = c
+				stmt.WriteByte(c)
+			case c == '#' || (c == '-' && i+2 < len(line) && line[i+1] == '-' && (line[i+2] == ' ' || line[i+2] == '\t' || line[i+2] == '\n')):
+				i = len(line) // comment to end of line
+			case c == '/' && i+1 < len(line) && line[i+1] == '*' && !(i+2 < len(line) && line[i+2] == '!'):
+				inBlock = true
+				i++
+			case bytes.HasPrefix(line[i:], delimiter):
+				if err := flush(); err != nil {
+					return err
+				}
+				i += len(delimiter) - 1
+			case stmt.Len() == 0 && (c == ' ' || c == '\t' || c == '\r' || c == '\n'):
+				// skip whitespace between statements
+			default:
+				stmt.WriteByte(c)
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,48 @@
+package devseeder
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitSQLStatements(t *testing.T) {
+	script := "-- comment\nSET NAMES utf8mb4;\n" +
+		"INSERT INTO `t` VALUES (1,'a;b'),(2,'it\\'s');\n" +
+		"/* block; */ /*!40101 SET @x = 1 */;\n" +
+		"DELIMITER ;;\nCREATE TRIGGER tr BEFORE INSERT ON t FOR EACH ROW BEGIN SET NEW.a = 1; END;;\ndelimiter ;\n" +
+		"SELECT 1;"
+	want := []string{
+		"SET NAMES utf8mb4",
+		"INSERT INTO `t` VALUES (1,'a;b'),(2,'it\\'s')",
+		"/*!40101 SET @x = 1 */",
+		"CREATE TRIGGER tr BEFORE INSERT ON t FOR EACH ROW BEGIN SET NEW.a = 1; END",
+		"SELECT 1",
+	}
+	var got []string
+	if err := SplitSQLStatements(strings.NewReader(script), func(stmt string) error {
+		got = append(got, stmt)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("SplitSQLStatements =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSplitSQLStatementsLongLine(t *testing.T) {
+	// An extended INSERT of several MB, like mysqldump writes, on one line
+	values := strings.TrimSuffix(strings.Repeat("(1,'x;y'),", 500000), ",")
+	script := "INSERT INTO t VALUES " + values + ";INSERT INTO t VALUES (2,'z');\n"
+	var got []string
+	if err := SplitSQLStatements(strings.NewReader(script), func(stmt string) error {
+		got = append(got, stmt)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "INSERT INTO t VALUES "+values || got[1] != "INSERT INTO t VALUES (2,'z')" {
+		t.Errorf("SplitSQLStatements split the long line into %d statements", len(got))
+	}
+}
@@ -0,0 +1,143 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// idColumn is the primary key column every copied table is expected to have
+const idColumn = "id"
+
+// QuoteIdent quotes a table or column name for use in a MySQL statement.
+// Embedded backticks are doubled, so any name can be used safely.
+func QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quoteIdents: returns "`col1`,`col2`,`col3`"
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = QuoteIdent(n)
+	}
+	return strings.Join(quoted, ",")
+}
+
+// placeholders returns "?,?,?" with n placeholders, for IN(...) and VALUES(...) lists
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?,", n-1) + "?"
+}
+
+// idArgs turns a set of IDs into bound parameters for an IN(...) list
+func idArgs(idSet map[int64]bool) []interface{} {
+	args := make([]interface{}, 0, len(idSet))
+	for id := range idSet {
+		args = append(args, id)
+	}
+	return args
+}
+
+// DeleteRow deletes the row of table (which may be schema-qualified) with the
+// id found in row, a map of column names to values such as a change event's row
+// image; conv turns that value into the one to bind. It reports whether row
+// holds an id at all.
+func DeleteRow(ctx context.Context, db *DB, table string, row map[string]interface{}, conv func(column string, v interface{}) interface{}) (bool, error) {
+	id, ok := row[idColumn]
+	if !ok {
+		return false, nil
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", quoteTable(table), QuoteIdent(idColumn)), conv(idColumn, id))
+	return true, err
+}
+
+// copyBatchSize is how many rows are fetched and inserted per statement when copying a table
+const copyBatchSize = 1000
+
+// maxPlaceholders is the most bound parameters one prepared statement may have
+const maxPlaceholders = 65535
+
+// idBatches splits a set of IDs into ascending batches of at most size IDs.
+// An empty set gives one empty batch.
+func idBatches(idSet map[int64]bool, size int) []map[int64]bool {
+	ids := make([]int64, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	batches := []map[int64]bool{{}}
+	for _, id := range ids {
+		if len(batches[len(batches)-1]) == size {
+			batches = append(batches, map[int64]bool{})
+		}
+		batches[len(batches)-1][id] = true
+	}
+	return batches
+}
+
+// sqlLiteral renders a value scanned from the driver as an SQL literal, for
+// statements written to files where bound parameters are not available.
+func sqlLiteral(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return quoteString(string(val))
+	case string:
+		return quoteString(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32)
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return quoteString(mysqlTime(val, "2006-01-02 15:04:05.999999"))
+	default:
+		return quoteString(fmt.Sprint(val))
+	}
+}
+
+// quoteString quotes s as a MySQL string literal, escaping the characters
+// mysqldump escapes.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '"':
+			b.WriteString(`\"`)
+		case 0x1a:
+			b.WriteString(`\Z`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
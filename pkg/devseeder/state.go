@@ -0,0 +1,172 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StateTable is the dev table that tracks seeded rows (see WithStateTracking).
+const StateTable = "_devseeder_state"
+
+// stateBatchSize is the maximum number of rows per statement on StateTable
+const stateBatchSize = 1000
+
+// WithStateTracking records the id of every row a sync inserts in StateTable on
+// dev, so Clean can later delete exactly those rows from a database that also
+// holds other data.
+func WithStateTracking() Option {
+	return func(s *Seeder) { s.trackState = true }
+}
+
+// recordSeededRows adds the rows in state to StateTable, creating it if needed
+func recordSeededRows(ctx context.Context, devDB *DB, state *UndoLog) error {
+	if len(state.order) == 0 {
+		return nil
+	}
+	_, err := devDB.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	table_name VARCHAR(64) NOT NULL,
+	row_id BIGINT NOT NULL,
+	seeded_at DATETIME NOT NULL,
+	PRIMARY KEY (table_name, row_id)
+)`, QuoteIdent(StateTable)))
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", StateTable, err)
+	}
+
+	now := time.Now().UTC().Format("2006-01-02 15:04:05")
+	total := 0
+	for _, table := range state.order {
+		ids := state.ids[table]
+		for start := 0; start < len(ids); start += stateBatchSize {
+			batch := ids[start:min(start+stateBatchSize, len(ids))]
+			args := make([]interface{}, 0, 3*len(batch))
+			for _, id := range batch {
+				args = append(args, table, id, now)
+			}
+			q := fmt.Sprintf("INSERT INTO %s (table_name, row_id, seeded_at) VALUES %s ON DUPLICATE KEY UPDATE seeded_at = VALUES(seeded_at)",
+				QuoteIdent(StateTable), strings.TrimSuffix(strings.Repeat("(?,?,?),", len(batch)), ","))
+			if _, err := devDB.ExecContext(ctx, q, args...); err != nil {
+				return fmt.Errorf("recording seeded rows of %s: %w", table, err)
+			}
+			total += len(batch)
+		}
+	}
+	logf(ctx, "Recorded %d seeded rows in %s", total, StateTable)
+	return nil
+}
+
+// CleanSeededRows deletes the rows recorded in StateTable from dev, and forgets
+// them. With tables, only the rows of those tables are deleted. StateTable itself
+// is dropped once it is empty. It returns the number of rows deleted.
+func CleanSeededRows(ctx context.Context, devDB *DB, tables []string) (int64, error) {
+	exists, err := listNames(ctx, devDB, `SELECT table_name FROM information_schema.tables
+	WHERE table_schema = DATABASE() AND table_name = ?`, StateTable)
+	if err != nil {
+		return 0, err
+	}
+	if len(exists) == 0 {
+		logf(ctx, "Nothing to clean: dev has no %s table", StateTable)
+		return 0, nil
+	}
+
+	recorded, err := listNames(ctx, devDB, fmt.Sprintf("SELECT DISTINCT table_name FROM %s ORDER BY table_name", QuoteIdent(StateTable)))
+	if err != nil {
+		return 0, err
+	}
+	if len(tables) > 0 {
+		wanted := make(map[string]bool, len(tables))
+		for _, t := range tables {
+			wanted[strings.ToLower(t)] = true
+		}
+		kept := recorded[:0]
+		for _, t := range recorded {
+			if wanted[strings.ToLower(t)] {
+				kept = append(kept, t)
+			}
+		}
+		recorded = kept
+	}
+	filter, args := devDB.schemaFilter("table_schema")
+	present, err := listNames(ctx, devDB, "SELECT "+qualifiedName("table_schema", "table_name")+" FROM information_schema.tables WHERE "+filter, args...)
+	if err != nil {
+		return 0, err
+	}
+	existing := make(map[string]bool, len(present))
+	for _, t := range present {
+		existing[t] = true
+	}
+
+	var deleted int64
+	for _, table := range recorded {
+		if existing[table] {
+			n, err := cleanTable(ctx, devDB, table)
+			if err != nil {
+				return deleted, fmt.Errorf("cleaning %s: %w", table, err)
+			}
+			deleted += n
+			logEvent(ctx, fmt.Sprintf("Cleaned table %s: %d rows", table, n), "table", table, "rows", n)
+		} else {
+			logf(ctx, "Warning: table %s no longer exists; forgetting its seeded rows", table)
+		}
+		if _, err := devDB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE table_name = ?", QuoteIdent(StateTable)), table); err != nil {
+			return deleted, fmt.Errorf("forgetting seeded rows of %s: %w", table, err)
+		}
+	}
+
+	var left int
+	if err := devDB.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", QuoteIdent(StateTable))).Scan(&left); err != nil {
+		return deleted, err
+	}
+	if left == 0 {
+		if _, err := devDB.ExecContext(ctx, "DROP TABLE "+QuoteIdent(StateTable)); err != nil {
+			return deleted, err
+		}
+	}
+	logf(ctx, "Clean complete: deleted %d rows", deleted)
+	return deleted, nil
+}
+
+// cleanTable deletes the recorded rows of one table
+func cleanTable(ctx context.Context, devDB *DB, table string) (int64, error) {
+	rows, err := devDB.QueryContext(ctx, fmt.Sprintf("SELECT row_id FROM %s WHERE table_name = ?", QuoteIdent(StateTable)), table)
+	if err != nil {
+		return 0, err
+	}
+	idSet := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		idSet[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, batch := range idBatches(idSet, stateBatchSize) {
+		res, err := devDB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)",
+			quoteTable(table), QuoteIdent(idColumn), placeholders(len(batch))), idArgs(batch)...)
+		if err != nil {
+			return deleted, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			deleted += n
+		}
+	}
+	return deleted, nil
+}
+
+// Clean deletes the rows recorded by syncs run WithStateTracking from dev (see
+// CleanSeededRows).
+func (s *Seeder) Clean(ctx context.Context, tables []string) error {
+	return s.withDevSession(ctx, func(ctx context.Context) error {
+		_, err := CleanSeededRows(ctx, s.dev, tables)
+		return err
+	})
+}
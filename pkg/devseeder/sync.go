@@ -0,0 +1,910 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SyncOptions controls what SyncPartialData copies and how it writes to dev
+type SyncOptions struct {
+	Tables       map[string]int // { tableName : rowLimit }
+	ResetTables  bool           // whether to truncate dev tables first
+	BackupSuffix string         // if set, dev tables are copied to <table><suffix> before truncating
+	Undo         *UndoLog       // if set, records the IDs of every inserted row
+	// insert only the columns both prod and dev have, instead of failing on schema drift
+	ColumnIntersection bool
+	// drop dev triggers on the copied tables during the load and recreate them afterwards;
+	// their definitions are also saved to a file in TriggerBackupDir
+	DisableTriggers  bool
+	TriggerBackupDir string
+	// { tableName : partitions } restricts seed selection to these prod partitions
+	Partitions map[string][]string
+	// { prodTable : devTable } for tables renamed in dev, e.g. with a prefix
+	TableMap map[string]string
+	// { prodTable : { prodColumn : devColumn } } for columns renamed in dev
+	ColumnMap map[string]map[string]string
+	// { prodTable : { devColumn : SQL expression } } written instead of the prod
+	// value, or for columns prod does not have
+	ColumnValues map[string]map[string]string
+	// { prodTable : devTable } for tables spelled differently in dev (letter case or
+	// TableMap); filled in by the Seeder
+	DevTableNames map[string]string
+	// if set, called as each table is started and finished
+	Progress ProgressFunc
+	// only copy planned rows whose IDs are not in dev yet
+	Incremental bool
+	// delete dev rows of the copied tables that were deleted in prod since
+	Prune bool
+	// how rows are written to dev: WriteInsert, WriteIgnore or WriteAppend
+	WriteStrategy string
+	// how 0000-00-00 dates are written (ZeroDatesKeep, ...) and the date used for ZeroDatesSentinel
+	ZeroDates        string
+	ZeroDateSentinel time.Time
+	// SQL run on dev around the whole copy and each table
+	Hooks SQLHooks
+	// rewrite rows in transit, e.g. masking policies
+	Transforms []RowTransform
+	// copies of the copied rows to add afterwards, for load testing
+	Multiply Multiplication
+	// { prodTable : ConflictSkip | ConflictOverwrite | ConflictRemap | ConflictFail }
+	Conflicts map[string]string
+	// if set, referenced tables with at most this many rows are copied in full
+	ReferenceTableRows int
+	// called with every copied row after it is read and before it is inserted
+	OnRowFetched, BeforeInsert []RowFunc
+	// directory of static seed files applied after the copy (see ApplyFixtures)
+	FixturesDir string
+	// if set, a plan writing more rows fails the sync before dev is touched
+	MaxRows int
+	// { childTable : { fkColumn : most parent IDs the FK may pull in } }
+	EdgeLimits map[string]map[string]int
+	// { table : SQL ORDER BY list choosing which rows are seeded }
+	OrderBy map[string]string
+	// how dev's foreign key checks are handled: FKChecksDeferred (when empty), FKChecksOn or FKChecksOff
+	FKChecks string
+}
+
+// devTable returns the name of the dev table that receives prod table's rows
+func (o SyncOptions) devTable(table string) string {
+	if name, ok := o.DevTableNames[table]; ok {
+		return name
+	}
+	return table
+}
+
+// CopyPlan is the computed subset: which rows of which tables to copy, and in what order
+type CopyPlan struct {
+	Order   []string                  // tables with rows to copy, parents before children
+	RowSets map[string]map[int64]bool // table -> set of "id" values
+
+	Full    map[string]FullTable         // requested tables copied whole (limit 0)
+	Seeds   map[string]int               // requested tables and their row limits
+	Reasons map[string][]InclusionReason // table -> the FKs that pulled its rows in
+}
+
+// InclusionReason records that rows of a child table referenced rows of the table
+// it is filed under, which therefore had to be copied as well.
+type InclusionReason struct {
+	ChildTable  string
+	ChildColumn string
+	Rows        int // parent IDs first discovered through this FK
+}
+
+// -----------------------------------------------------------------------------
+// Example: the BFS-based partial data copy
+// -----------------------------------------------------------------------------
+func SyncPartialData(
+	ctx context.Context,
+	prodDB, devDB *DB,
+	allFks []ForeignKey, // all known FKs
+	opts SyncOptions,
+) error {
+	plan, err := BuildCopyPlan(ctx, prodDB, allFks, opts)
+	if err != nil {
+		return err
+	}
+	return copyPlan(ctx, prodDB, devDB, allFks, plan, opts)
+}
+
+// BuildCopyPlan seeds the requested tables and follows FKs (BFS) to collect every
+// parent row they need, without writing anything.
+func BuildCopyPlan(
+	ctx context.Context,
+	prodDB *DB,
+	allFks []ForeignKey, // all known FKs
+	opts SyncOptions,
+) (*CopyPlan, error) {
+	requestedTables := opts.Tables
+
+	//----------------------------------------------------------------
+	// 1) Build adjacency: child -> slice of (ParentTable, ParentColumn, ChildColumn)
+	// child:[{parentTable: string, parentColumn: string, childColumn: string}]
+	//----------------------------------------------------------------
+	// 1) Build adjacency: child -> slice of (ParentTable, ParentColumn, ChildColumn)
+	childToParents := make(map[string][]FkEdge)
+	for _, fk := range allFks {
+		// If you want to skip self-referencing
+		if fk.FromTable == fk.ToTable {
+			continue
+		}
+
+		// IMPORTANT: skip if the child column is nullable
+		if fk.IsNullable {
+			// This means the child -> parent is optional,
+			// so we don't treat it as a "hard" dependency for topological ordering
+			continue
+		}
+
+		childToParents[fk.FromTable] = append(childToParents[fk.FromTable], FkEdge{
+			ParentTable:  fk.ToTable,
+			ParentColumn: fk.ToColumn,
+			ChildColumn:  fk.FromColumn,
+		})
+	}
+
+	//----------------------------------------------------------------
+	// 2) Maintain sets of row IDs we need to copy for each table
+	//----------------------------------------------------------------
+	//     table -> set of "id" values
+	rowSets := make(map[string]map[int64]bool)
+
+	// Initialize sets (for all tables we see in FKs, plus requested tables)
+	for _, fk := range allFks {
+		if _, ok := rowSets[fk.FromTable]; !ok {
+			rowSets[fk.FromTable] = make(map[int64]bool)
+		}
+		if _, ok := rowSets[fk.ToTable]; !ok {
+			rowSets[fk.ToTable] = make(map[int64]bool)
+		}
+	}
+
+	// Initialize sets for requested tables in case they're not referenced by FKs
+	for tbl := range requestedTables {
+		if _, ok := rowSets[tbl]; !ok {
+			rowSets[tbl] = make(map[int64]bool)
+		}
+	}
+
+	if err := checkPartitions(ctx, prodDB, opts.Partitions); err != nil {
+		return nil, fmt.Errorf("partition check error: %w", err)
+	}
+
+	requested := make([]string, 0, len(requestedTables))
+	for table := range requestedTables {
+		requested = append(requested, table)
+	}
+	orderBy := normalizeTableKeys(opts.OrderBy, requested)
+
+	//----------------------------------------------------------------
+	// 3) Seed the sets with user-requested tables’ limited rowIDs
+	// Example:
+	// 	If user requested table "products" with limit 2
+	// 	rowSets["products"] = map[int64]bool{3: true, 4: true}
+	// Tables requested with limit 0 are copied whole and only counted.
+	//----------------------------------------------------------------
+	full := make(map[string]FullTable)
+	for table, limit := range requestedTables {
+		if limit == 0 {
+			n, err := countRows(ctx, prodDB, table, opts.Partitions[table])
+			if err != nil {
+				return nil, fmt.Errorf("counting rows of %s: %w", table, err)
+			}
+			full[table] = FullTable{Rows: n, Partitions: opts.Partitions[table]}
+			continue
+		}
+		ids, err := fetchSomeIDs(ctx, prodDB, table, opts.Partitions[table], orderBy[table], limit)
+		if err != nil {
+			return nil, fmt.Errorf("fetchSomeIDs error for table %s: %w", table, err)
+		}
+		for _, id := range ids {
+			rowSets[table][id] = true
+		}
+	}
+
+	//----------------------------------------------------------------
+	// 4) BFS queue approach to add all *parent* IDs needed
+	//----------------------------------------------------------------
+	//    If we discover new child->parent references, add them to the parent's set,
+	//    re-queue that parent to find *its* parents, etc.
+
+	bfsCtx, bfsSpan := startSpan(ctx, "devseeder.bfs")
+	queue := make([]string, 0)
+	enqueued := make(map[string]bool)
+	reasons := make(map[string][]InclusionReason)
+
+	var refs *referenceTables
+	if opts.ReferenceTableRows > 0 {
+		refs = newReferenceTables(allFks, opts.ReferenceTableRows)
+	}
+	edgeCaps := newEdgeLimits(opts.EdgeLimits, allFks)
+
+	// Start BFS with each requested table
+	for t := range requestedTables {
+		queue = append(queue, t)
+		enqueued[t] = true
+	}
+
+	// Process the queue until there’s nothing left to explore.
+	for len(queue) > 0 {
+		childTable := queue[0]
+		queue = queue[1:]
+		enqueued[childTable] = false
+
+		// If we have no row-IDs in this child, skip
+		childIDs := rowSets[childTable]
+		childFull, isFull := full[childTable]
+		if len(childIDs) == 0 && !isFull {
+			continue
+		}
+
+		// For each parent relationship child -> parent
+		// An edge here represents a parent-child relationship
+		// Ex. { suppliers id supplier_id}
+		edges := childToParents[childTable]
+		for _, edge := range edges {
+			// Every row of a table copied whole is copied anyway
+			if _, ok := full[edge.ParentTable]; ok {
+				continue
+			}
+			var newParentIDs map[int64]bool
+			var err error
+			if isFull {
+				newParentIDs, err = fetchAllReferencedParentIDs(bfsCtx, prodDB, childTable, childFull.Partitions, edge)
+			} else {
+				newParentIDs, err = fetchReferencedParentIDs(bfsCtx, prodDB, childTable, edge, childIDs)
+			}
+			if err != nil {
+				return nil, finishSpan(bfsSpan, fmt.Errorf("fetchReferencedParentIDs error: %w", err))
+			}
+			// Insert discovered IDs into parent's rowSets, up to the edge's limit
+			added := edgeCaps.add(childTable, edge, newParentIDs, rowSets[edge.ParentTable])
+			changed := added > 0
+			if changed {
+				reasons[edge.ParentTable] = addReason(reasons[edge.ParentTable], childTable, edge.ChildColumn, added)
+			}
+			// If parent's set grew, re-queue the parent table unless it's already enqueued
+			if changed && !enqueued[edge.ParentTable] {
+				queue = append(queue, edge.ParentTable)
+				enqueued[edge.ParentTable] = true
+			}
+		}
+
+		// Small tables this one references are copied whole, whatever rows point at them
+		if refs != nil {
+			for _, fk := range refs.parentsOf[childTable] {
+				if _, ok := full[fk.ToTable]; ok {
+					continue
+				}
+				added, err := refs.include(bfsCtx, prodDB, fk.ToTable, rowSets[fk.ToTable])
+				if err != nil {
+					return nil, finishSpan(bfsSpan, err)
+				}
+				if added > 0 {
+					reasons[fk.ToTable] = addReason(reasons[fk.ToTable], childTable, fk.FromColumn, added)
+					if !enqueued[fk.ToTable] {
+						queue = append(queue, fk.ToTable)
+						enqueued[fk.ToTable] = true
+					}
+				}
+			}
+		}
+	}
+	finishSpan(bfsSpan, nil)
+	edgeCaps.warn(ctx, rowSets)
+
+	//----------------------------------------------------------------
+	// 5) Build final list of tables that actually have rowIDs
+	//----------------------------------------------------------------
+	var tablesNeedingCopy []string
+	for tableName, idSet := range rowSets {
+		if len(idSet) > 0 || full[tableName].Rows > 0 {
+			tablesNeedingCopy = append(tablesNeedingCopy, tableName)
+		}
+	}
+
+	//----------------------------------------------------------------
+	// 6) Topologically sort them so parents come before children
+	//----------------------------------------------------------------
+	sorted, err := partialTopoSort(allFks, tablesNeedingCopy)
+	if err != nil {
+		return nil, fmt.Errorf("topoSort error: %w", err)
+	}
+
+	return &CopyPlan{Order: sorted, RowSets: rowSets, Full: full, Seeds: requestedTables, Reasons: reasons}, nil
+}
+
+// addReason counts added IDs against the child table's FK, recording it on first use
+func addReason(reasons []InclusionReason, childTable, childColumn string, added int) []InclusionReason {
+	for i, r := range reasons {
+		if r.ChildTable == childTable && r.ChildColumn == childColumn {
+			reasons[i].Rows += added
+			return reasons
+		}
+	}
+	return append(reasons, InclusionReason{ChildTable: childTable, ChildColumn: childColumn, Rows: added})
+}
+
+// copyPlan writes the rows of a plan from prod into dev
+func copyPlan(ctx context.Context, prodDB, devDB *DB, allFks []ForeignKey, plan *CopyPlan, opts SyncOptions) (err error) {
+	sorted, rowSets := plan.Order, plan.RowSets
+
+	// On failure, report what dev was left with
+	incomplete := &IncompleteCopyError{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		incomplete.Err = err
+		for _, table := range sorted {
+			if plan.Rows(table) > 0 && table != incomplete.Partial && !slices.Contains(incomplete.Copied, table) {
+				incomplete.NotCopied = append(incomplete.NotCopied, table)
+			}
+		}
+		logf(ctx, "Warning: %s\n", incomplete.Summary())
+		err = incomplete
+	}()
+
+	//----------------------------------------------------------------
+	// 7) Copy data in topological order
+	//----------------------------------------------------------------
+	//    Once a table has been truncated its writes must not be interrupted,
+	//    otherwise dev is left with a half-filled table. Cancellation is only
+	//    honoured between tables and while reading a table's first batch from prod.
+	writeCtx := context.WithoutCancel(ctx)
+
+	// Keep dev triggers from firing on seeded rows
+	if opts.DisableTriggers {
+		devTables := make([]string, len(sorted))
+		for i, t := range sorted {
+			devTables[i] = opts.devTable(t)
+		}
+		enableTriggers, err := DisableDevTriggers(writeCtx, devDB, devTables, opts.TriggerBackupDir)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := enableTriggers(); err != nil {
+				logf(ctx, "Warning: %v\n", err)
+			}
+		}()
+	}
+	var queue []string
+	totalRows := 0
+	for _, table := range sorted {
+		if rows := plan.Rows(table); rows > 0 {
+			queue = append(queue, table)
+			totalRows += rows
+		}
+	}
+	total := len(queue)
+	opts.report(ProgressEvent{Stage: StagePlanned, TablesTotal: total, TotalRows: totalRows, Queue: queue})
+
+	done, totalRowsDone := 0, 0
+	started := time.Now()
+	remapped := make(map[string]map[int64]int64) // table -> prod id -> dev id
+	copiedTables := make(map[string]string)      // dev table -> prod table, once copied
+	copied := func(devTable string, id int64) bool {
+		table, ok := copiedTables[devTable]
+		return ok && plan.copies(table, id)
+	}
+	var deferred []*deferredColumn
+	for _, table := range sorted {
+		idSet := rowSets[table]
+		_, isFull := plan.Full[table]
+		if plan.Rows(table) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("stopped before copying table %s: %w", table, err)
+		}
+		devTable := opts.devTable(table)
+
+		// Incremental runs keep the dev rows of a table copied whole as they insert
+		mode := opts.insertMode(table)
+		if isFull && opts.Incremental && mode == "" {
+			mode = ConflictSkip
+		}
+		// Settle rows dev already has before writing any of the table
+		if !opts.ResetTables && !isFull {
+			var remap map[int64]int64
+			var err error
+			if idSet, remap, err = resolveConflicts(ctx, devDB, table, devTable, idSet, opts.Conflicts[table]); err != nil {
+				return err
+			}
+			if len(remap) > 0 {
+				remapped[table] = remap
+			}
+		}
+		next := setBatches(idSet)
+		if isFull {
+			next = plan.batches(prodDB, table)
+		}
+		batch, err := next(ctx)
+		if err != nil {
+			return fmt.Errorf("reading the IDs of %s: %w", table, err)
+		}
+		if len(batch) == 0 {
+			done++
+			incomplete.Copied = append(incomplete.Copied, table)
+			opts.report(ProgressEvent{Stage: StageTableCopied, Table: table,
+				TablesDone: done, TablesTotal: total, TotalRowsDone: totalRowsDone, TotalRows: totalRows})
+			continue
+		}
+		rows := len(idSet)
+		if isFull {
+			rows = plan.Rows(table)
+		}
+		parents := fkParents(allFks, table, opts)
+		logEvent(ctx, fmt.Sprintf("Copying %d rows from table %s", rows, table), "table", table, "rows", rows)
+		tableStarted := time.Now()
+		event := ProgressEvent{Stage: StageTableStarted, Table: table, Rows: rows,
+			TablesDone: done, TablesTotal: total, TotalRowsDone: totalRowsDone, TotalRows: totalRows}
+		opts.report(event)
+
+		// 7a. Fetch the first batch from prod (before touching dev)
+		rowsData, columns, err := fetchRowsByIDs(ctx, prodDB, table, batch)
+		if err != nil {
+			return fmt.Errorf("fetchRowsByIDs error: %w", err)
+		}
+		// From here on columns carry their dev names
+		columns = renameColumns(columns, opts.ColumnMap[table])
+		prodColumns := columns
+
+		// Generated columns cannot be inserted; dev recomputes them
+		generated, err := fetchGeneratedColumns(ctx, devDB, devTable)
+		if err != nil {
+			return fmt.Errorf("fetchGeneratedColumns error on dev %s: %w", table, err)
+		}
+		columns, rowsData, _ = projectColumns(columns, rowsData, func(c string) bool { return !generated[c] })
+
+		// Tolerate schema drift by only inserting columns dev also has
+		if opts.ColumnIntersection {
+			devColumns, err := fetchColumns(ctx, devDB, devTable)
+			if err != nil {
+				return fmt.Errorf("fetchColumns error on dev %s: %w", table, err)
+			}
+			columns, rowsData = intersectColumns(ctx, table, columns, rowsData, devColumns)
+		}
+		// Configured values replace the prod ones
+		values := columnValueList(opts.ColumnValues[table])
+		columns, rowsData, _ = projectColumns(columns, rowsData, func(c string) bool {
+			_, set := opts.ColumnValues[table][c]
+			return !set
+		})
+		insertable := make(map[string]bool, len(columns))
+		for _, c := range columns {
+			insertable[c] = true
+		}
+		devTypes, err := fetchColumnTypes(ctx, devDB, devTable)
+		if err != nil {
+			return fmt.Errorf("fetchColumnTypes error on dev %s: %w", table, err)
+		}
+		// With checks on, nullable FKs may point at rows copied later
+		held := deferredColumns(allFks, table, columns, opts)
+		deferred = append(deferred, held...)
+
+		// From here on dev's table is being written
+		incomplete.Partial, incomplete.PartialRows, incomplete.PartialTotal = table, 0, rows
+		if err := runSQLHooks(writeCtx, devDB, "before_table "+table, tableHooks(opts.Hooks.BeforeTable, table, devTable)); err != nil {
+			return err
+		}
+
+		// Optionally truncate dev table, keeping a backup copy first
+		if opts.ResetTables {
+			if opts.BackupSuffix != "" {
+				if err := backupTable(writeCtx, devDB, devTable, opts.BackupSuffix); err != nil {
+					return fmt.Errorf("backup error on %s: %w", table, err)
+				}
+				incomplete.PartialBackup = devTable + opts.BackupSuffix
+			}
+			if err := truncateChecked(writeCtx, devDB, devTable, opts); err != nil {
+				return fmt.Errorf("truncate error on %s: %w", table, err)
+			}
+			incomplete.PartialReset = true
+		}
+
+		// 7b. Insert them into dev batch by batch, fetching the rest as we go
+		for i := 0; len(batch) > 0; i++ {
+			if i > 0 {
+				if rowsData, _, err = fetchRowsByIDs(writeCtx, prodDB, table, batch); err != nil {
+					return fmt.Errorf("fetchRowsByIDs error: %w", err)
+				}
+				_, rowsData, _ = projectColumns(prodColumns, rowsData, func(c string) bool { return insertable[c] })
+			}
+			var fetchedFiltered, insertFiltered bool
+			if rowsData, fetchedFiltered, err = filterRows(writeCtx, opts.OnRowFetched, table, columns, rowsData); err != nil {
+				return err
+			}
+			remapRows(columns, rowsData, table, parents, remapped)
+			if err := applyTransforms(writeCtx, opts.Transforms, table, columns, rowsData); err != nil {
+				return err
+			}
+			if err := prepareRows(table, columns, rowsData, devTypes, opts); err != nil {
+				return err
+			}
+			if rowsData, insertFiltered, err = filterRows(writeCtx, opts.BeforeInsert, devTable, columns, rowsData); err != nil {
+				return err
+			}
+			for _, d := range held {
+				d.hold(columns, rowsData, copied)
+			}
+			if err := insertRows(writeCtx, devDB, devTable, columns, rowsData, values, mode); err != nil {
+				return fmt.Errorf("insertRows error: %w", explainZeroDateError(devTable, explainPartitionError(devTable, err)))
+			}
+			if fetchedFiltered || insertFiltered {
+				opts.Undo.Record(devTable, rowIDs(columns, rowsData))
+			} else {
+				opts.Undo.Record(devTable, copiedIDs(batch, remapped[table]))
+			}
+
+			incomplete.PartialRows += len(rowsData)
+			event.Stage = StageRowsCopied
+			event.RowsDone += len(batch)
+			event.TotalRowsDone += len(batch)
+			opts.report(event)
+
+			if batch, err = next(writeCtx); err != nil {
+				return fmt.Errorf("reading the IDs of %s: %w", table, err)
+			}
+		}
+
+		// 7c. Move the AUTO_INCREMENT counter past the seeded IDs
+		if err := syncAutoIncrement(writeCtx, devDB, devTable); err != nil {
+			return fmt.Errorf("syncAutoIncrement error on %s: %w", table, err)
+		}
+		if err := runSQLHooks(writeCtx, devDB, "after_table "+table, tableHooks(opts.Hooks.AfterTable, table, devTable)); err != nil {
+			return err
+		}
+		elapsed := time.Since(tableStarted)
+		logEvent(ctx, fmt.Sprintf("Copied %d rows into table %s in %s", event.RowsDone, table, elapsed.Round(time.Millisecond)),
+			"table", table, "rows", event.RowsDone, "duration", elapsed)
+		done++
+		incomplete.Copied = append(incomplete.Copied, table)
+		copiedTables[devTable] = table
+		incomplete.Partial, incomplete.PartialReset, incomplete.PartialBackup = "", false, ""
+		totalRowsDone = event.TotalRowsDone
+		event.Stage = StageTableCopied
+		event.TablesDone = done
+		opts.report(event)
+	}
+
+	// 7d. Fill in the FKs held back while their parents were being copied
+	if err := applyDeferredColumns(writeCtx, devDB, deferred); err != nil {
+		return fmt.Errorf("setting deferred foreign keys: %w", err)
+	}
+
+	elapsed := time.Since(started)
+	logEvent(ctx, fmt.Sprintf("Copied %d rows into %d tables in %s", totalRowsDone, done, elapsed.Round(time.Millisecond)),
+		"rows", totalRowsDone, "tables", done, "duration", elapsed)
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// HELPER TYPES AND FUNCTIONS
+// -----------------------------------------------------------------------------
+
+// FkEdge is a small struct describing child->parent columns
+type FkEdge struct {
+	ParentTable  string
+	ParentColumn string
+	ChildColumn  string
+}
+
+// truncateTable optionally wipes the dev table
+func truncateTable(ctx context.Context, db *DB, table string) error {
+	sqlStr := "TRUNCATE TABLE " + quoteTable(table)
+	_, err := db.ExecContext(ctx, sqlStr)
+	return err
+}
+
+// fetchSomeIDs: fetch up to "limit" IDs from `table`, the first ones by orderBy
+// (an SQL ORDER BY list, ties broken by `id`) or else by `id`, optionally only
+// from the given partitions
+func fetchSomeIDs(ctx context.Context, db *DB, table string, partitions []string, orderBy string, limit int) ([]int64, error) {
+	id := QuoteIdent(idColumn)
+	order := id
+	if orderBy != "" {
+		order = orderBy + ", " + id
+	}
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT ?", id, quoteTable(table), partitionClause(partitions), order)
+	rows, err := db.QueryContext(ctx, sqlStr, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		results = append(results, id)
+	}
+	return results, rows.Err()
+}
+
+// fetchReferencedParentIDs: given a child's rowIDs, figure out the parent's IDs they reference.
+// For example, if the child FK column is childCol=parent_id, we do:
+//
+//	SELECT DISTINCT parent_id FROM child WHERE id IN (childIDs) AND parent_id IS NOT NULL
+func fetchReferencedParentIDs(
+	ctx context.Context,
+	db *DB,
+	childTable string,
+	edge FkEdge,
+	childIDs map[int64]bool,
+) (map[int64]bool, error) {
+
+	if len(childIDs) == 0 {
+		return nil, nil
+	}
+
+	// One IN(...) query per batch, keeping each under the placeholder limit
+	col := QuoteIdent(edge.ChildColumn)
+	parentIDs := make(map[int64]bool)
+	for _, batch := range idBatches(childIDs, copyBatchSize) {
+		args := idArgs(batch)
+		query := fmt.Sprintf(
+			"SELECT DISTINCT %s FROM %s WHERE %s IN (%s) AND %s IS NOT NULL",
+			col, quoteTable(childTable), QuoteIdent(idColumn), placeholders(len(args)), col,
+		)
+		if err := scanParentIDs(ctx, db, query, args, parentIDs); err != nil {
+			return nil, err
+		}
+	}
+	return parentIDs, nil
+}
+
+// scanParentIDs adds the IDs query returns to parentIDs
+func scanParentIDs(ctx context.Context, db *DB, query string, args []interface{}, parentIDs map[int64]bool) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pid int64
+		if err := rows.Scan(&pid); err != nil {
+			return err
+		}
+		parentIDs[pid] = true
+	}
+	return rows.Err()
+}
+
+// fetchRowsByIDs: SELECT <all columns> FROM `table` WHERE id IN (...)
+func fetchRowsByIDs(ctx context.Context, db *DB, table string, idSet map[int64]bool) (_ [][]interface{}, _ []string, err error) {
+	if len(idSet) == 0 {
+		return nil, nil, nil
+	}
+	ctx, span := startSpan(ctx, "devseeder.fetch_rows",
+		attribute.String("db.sql.table", table), attribute.Int("devseeder.rows", len(idSet)))
+	defer func() { finishSpan(span, err) }()
+
+	// List the columns explicitly: SELECT * leaves out MySQL 8 invisible columns
+	tableColumns, err := fetchColumns(ctx, db, table)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Build IN(...) list, or a primary key range when the IDs are consecutive
+	args := idArgs(idSet)
+	where := fmt.Sprintf("IN (%s)", placeholders(len(args)))
+	if lo, hi := idRange(idSet); hi-lo+1 == int64(len(idSet)) {
+		where, args = "BETWEEN ? AND ?", []interface{}{lo, hi}
+	}
+
+	// Ordered by id so dumps and exports of the same plan are byte-identical
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s WHERE %s %s ORDER BY %[3]s",
+		quoteIdents(tableColumns), quoteTable(table), QuoteIdent(idColumn), where)
+
+	// A connection dropping while the rows stream in fetches the batch again
+	var allData [][]interface{}
+	var columns []string
+	err = db.retry(ctx, func() error {
+		allData, columns, err = queryRows(ctx, db, sqlStr, args)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return allData, columns, nil
+}
+
+// idRange returns the lowest and highest id of a non-empty set
+func idRange(idSet map[int64]bool) (lo, hi int64) {
+	first := true
+	for id := range idSet {
+		if first || id < lo {
+			lo = id
+		}
+		if first || id > hi {
+			hi = id
+		}
+		first = false
+	}
+	return lo, hi
+}
+
+// queryRows runs query and scans every row of its result
+func queryRows(ctx context.Context, db *DB, query string, args []interface{}) ([][]interface{}, []string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	// Column names
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var allData [][]interface{}
+	for rows.Next() {
+		rowVals := make([]interface{}, len(columns))
+		rowPtrs := make([]interface{}, len(columns))
+		for i := range rowVals {
+			rowPtrs[i] = &rowVals[i]
+		}
+		if err := rows.Scan(rowPtrs...); err != nil {
+			return nil, nil, err
+		}
+		allData = append(allData, rowVals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return allData, columns, nil
+}
+
+// insertRows does a multi-row INSERT to dev table; values add columns set to an SQL
+// expression in every row. With mode ConflictSkip, rows clashing with existing
+// ones on a unique key are skipped (INSERT IGNORE); with ConflictOverwrite they
+// are updated to the new values (ON DUPLICATE KEY UPDATE).
+func insertRows(ctx context.Context, db *DB, table string, columns []string, rowsData [][]interface{}, values []columnValue, mode string) error {
+	// Wide tables take fewer rows per statement, to stay under the placeholder limit
+	size := min(copyBatchSize, maxPlaceholders/max(len(columns), 1))
+	for start := 0; start < len(rowsData); start += size {
+		batch := rowsData[start:min(start+size, len(rowsData))]
+		if err := insertRowBatch(ctx, db, table, columns, batch, values, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertRowBatch is insertRows for rows that fit in one statement
+func insertRowBatch(ctx context.Context, db *DB, table string, columns []string, rowsData [][]interface{}, values []columnValue, mode string) (err error) {
+	ctx, span := startSpan(ctx, "devseeder.insert_rows",
+		attribute.String("db.sql.table", table), attribute.Int("devseeder.rows", len(rowsData)))
+	defer func() { finishSpan(span, err) }()
+
+	colList := quoteIdents(columns)
+	rowPlaceholders := placeholders(len(columns))
+	for _, v := range values {
+		colList += "," + QuoteIdent(v.column)
+		rowPlaceholders += "," + v.expr
+	}
+	rowPlaceholders = "(" + rowPlaceholders + ")"
+
+	var valueBlocks []string
+	var allArgs []interface{}
+
+	for _, row := range rowsData {
+		valueBlocks = append(valueBlocks, rowPlaceholders)
+		allArgs = append(allArgs, row...)
+	}
+
+	verb := "INSERT"
+	if mode == ConflictSkip {
+		verb = "INSERT IGNORE"
+	}
+	sqlStr := fmt.Sprintf("%s INTO %s (%s) VALUES %s",
+		verb,
+		quoteTable(table),
+		colList,
+		strings.Join(valueBlocks, ","),
+	)
+	updates := make([]string, 0, len(columns)+len(values))
+	for _, c := range columns {
+		updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", QuoteIdent(c), QuoteIdent(c)))
+	}
+	for _, v := range values {
+		updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", QuoteIdent(v.column), QuoteIdent(v.column)))
+	}
+	upsert := sqlStr + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	if mode == ConflictOverwrite {
+		sqlStr = upsert
+	}
+
+	if err := db.checkSession(ctx); err != nil {
+		return err
+	}
+	// If the connection drops, the batch may already be in: writing it again
+	// overwrites those rows with the same values
+	resume := sqlStr
+	if mode == "" {
+		resume = upsert
+	}
+	_, err = db.execResuming(ctx, sqlStr, resume, allArgs...)
+	return err
+}
+
+// -----------------------------------------------------------------------------
+// partialTopoSort is a simpler topological sort that only sorts the subset
+// -----------------------------------------------------------------------------
+func partialTopoSort(allFks []ForeignKey, neededTables []string) ([]string, error) {
+	// Work on the names in sorted order, so the same tables always sort the same way
+	neededTables = slices.Clone(neededTables)
+	slices.Sort(neededTables)
+
+	neededSet := make(map[string]bool)
+	for _, t := range neededTables {
+		neededSet[t] = true
+	}
+
+	depMap := make(map[string][]string)
+	inDegree := make(map[string]int)
+
+	// Initialize
+	for _, table := range neededTables {
+		depMap[table] = nil
+		inDegree[table] = 0
+	}
+
+	// For each FK in your subset:
+	// If child & parent are in neededSet AND it's NOT nullable
+	for _, fk := range allFks {
+		if fk.FromTable == fk.ToTable {
+			continue
+		}
+		if neededSet[fk.FromTable] && neededSet[fk.ToTable] && !fk.IsNullable {
+			depMap[fk.FromTable] = append(depMap[fk.FromTable], fk.ToTable)
+		}
+	}
+
+	// Calculate in-degree
+	for table, parents := range depMap {
+		for range parents {
+			inDegree[table] = inDegree[table] + 1
+		}
+	}
+
+	// Start with all tables that have in-degree = 0
+	var queue []string
+	for _, t := range neededTables {
+		if inDegree[t] == 0 {
+			queue = append(queue, t)
+		}
+	}
+
+	var sorted []string
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		sorted = append(sorted, cur)
+
+		// Decrease in-degree for each child that depends on `cur` in depMap
+		for _, child := range neededTables {
+			if slices.Contains(depMap[child], cur) {
+				inDegree[child]--
+				if inDegree[child] == 0 {
+					queue = append(queue, child)
+				}
+			}
+		}
+	}
+
+	// If sorted < neededTables, there's a cycle or missing dependency
+	if len(sorted) != len(neededTables) {
+		return nil, fmt.Errorf("topological sort: cycle detected or missing table references")
+	}
+	return sorted, nil
+}
@@ -0,0 +1,29 @@
+package devseeder
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Spans go to the global TracerProvider, so nothing is recorded unless the
+// application installs one (see otel.SetTracerProvider).
+const tracerName = "github.com/milanarif/devseeder/pkg/devseeder"
+
+// startSpan starts a child span of the one in ctx, if any.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// finishSpan ends span, marking it failed when err is set, and returns err.
+func finishSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	return err
+}
@@ -0,0 +1,43 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+)
+
+// RowTransform rewrites rows in transit, e.g. to mask personal data, before they
+// are written to dev or to an export. It may replace values in place but must
+// keep the columns and the id.
+type RowTransform interface {
+	TransformRows(ctx context.Context, table string, columns []string, rows [][]interface{}) error
+}
+
+// WithRowTransforms applies ts, in order, to every copied or exported row.
+func WithRowTransforms(ts ...RowTransform) Option {
+	return func(s *Seeder) { s.opts.Transforms = append(s.opts.Transforms, ts...) }
+}
+
+type transformsKey struct{}
+
+// contextWithTransforms carries ts to the exporters, which only get a plan
+func contextWithTransforms(ctx context.Context, ts []RowTransform) context.Context {
+	if len(ts) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, transformsKey{}, ts)
+}
+
+func transformsFrom(ctx context.Context) []RowTransform {
+	ts, _ := ctx.Value(transformsKey{}).([]RowTransform)
+	return ts
+}
+
+// applyTransforms runs every transform over a batch of table's rows
+func applyTransforms(ctx context.Context, ts []RowTransform, table string, columns []string, rowsData [][]interface{}) error {
+	for _, t := range ts {
+		if err := t.TransformRows(ctx, table, columns, rowsData); err != nil {
+			return fmt.Errorf("transforming rows of %s: %w", table, err)
+		}
+	}
+	return nil
+}
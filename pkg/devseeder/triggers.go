@@ -0,0 +1,199 @@
+package devseeder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// listNames runs a query returning one name per row
+func listNames(ctx context.Context, db *DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+// showCreateTrigger returns the CREATE TRIGGER statement for trigger
+func showCreateTrigger(ctx context.Context, db *DB, trigger string) (string, error) {
+	// Columns: Trigger, sql_mode, SQL Original Statement, character_set_client,
+	// collation_connection, Database Collation, Created
+	var name, sqlMode, ddl, charset, collation, dbCollation string
+	var created interface{}
+	err := db.QueryRowContext(ctx, "SHOW CREATE TRIGGER "+QuoteIdent(trigger)).
+		Scan(&name, &sqlMode, &ddl, &charset, &collation, &dbCollation, &created)
+	return ddl, err
+}
+
+// DisableDevTriggers drops every trigger on the given dev tables so it cannot fire
+// on seeded rows. MySQL has no way to switch a trigger off, so the definitions are
+// saved first (also to a file in dir, in case the run dies) and the returned func
+// recreates them.
+func DisableDevTriggers(ctx context.Context, devDB *DB, tables []string, dir string) (func() error, error) {
+	noop := func() error { return nil }
+	if len(tables) == 0 {
+		return noop, nil
+	}
+
+	args := make([]interface{}, len(tables))
+	for i, t := range tables {
+		args[i] = t
+	}
+	triggers, err := listNames(ctx, devDB, fmt.Sprintf(`
+	SELECT trigger_name
+	FROM information_schema.triggers
+	WHERE trigger_schema = DATABASE() AND event_object_table IN (%s)
+	ORDER BY event_object_table, action_timing, event_manipulation, action_order`, placeholders(len(args))), args...)
+	if err != nil {
+		return noop, fmt.Errorf("cannot list dev triggers: %w", err)
+	}
+	if len(triggers) == 0 {
+		return noop, nil
+	}
+
+	ddls := make([]string, len(triggers))
+	for i, t := range triggers {
+		if ddls[i], err = showCreateTrigger(ctx, devDB, t); err != nil {
+			return noop, fmt.Errorf("SHOW CREATE TRIGGER %s: %w", t, err)
+		}
+	}
+
+	// Keep a copy on disk so the triggers can be recreated by hand if the run is killed
+	path := filepath.Join(dir, fmt.Sprintf("devseeder-triggers-%s.sql", time.Now().Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return noop, fmt.Errorf("cannot save trigger definitions: %w", err)
+	}
+	fmt.Fprintln(f, "DELIMITER $$")
+	for _, ddl := range ddls {
+		fmt.Fprintf(f, "%s$$\n", ddl)
+	}
+	fmt.Fprintln(f, "DELIMITER ;")
+	if err := f.Close(); err != nil {
+		return noop, fmt.Errorf("cannot save trigger definitions: %w", err)
+	}
+
+	for _, t := range triggers {
+		if _, err := devDB.ExecContext(ctx, "DROP TRIGGER "+QuoteIdent(t)); err != nil {
+			return noop, fmt.Errorf("cannot drop trigger %s (definitions saved to %s): %w", t, path, err)
+		}
+	}
+	logf(ctx, "Disabled %d dev triggers during load (definitions saved to %s)", len(triggers), path)
+
+	// Every trigger is recreated that can be, so one failure loses no others
+	return func() error {
+		var errs []error
+		for i, ddl := range ddls {
+			if _, err := devDB.ExecContext(context.Background(), ddl); err != nil {
+				logf(ctx, "Warning: cannot recreate trigger %s; its definition was:\n%s", triggers[i], ddl)
+				errs = append(errs, fmt.Errorf("cannot recreate trigger %s: %w", triggers[i], err))
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%d of %d dev triggers not recreated (definitions saved to %s): %w",
+				len(errs), len(triggers), path, errors.Join(errs...))
+		}
+		logf(ctx, "Re-enabled %d dev triggers", len(triggers))
+		return nil
+	}, nil
+}
+
+// CopyMissingTriggers creates the prod triggers that don't exist in dev.
+// It runs after the data copy so the new triggers don't fire on seeded rows.
+func CopyMissingTriggers(ctx context.Context, prodDB, devDB *DB) error {
+	const q = `SELECT trigger_name FROM information_schema.triggers WHERE trigger_schema = DATABASE() ORDER BY action_order`
+	prodTriggers, err := listNames(ctx, prodDB, q)
+	if err != nil {
+		return err
+	}
+	devTriggers, err := listNames(ctx, devDB, q)
+	if err != nil {
+		return err
+	}
+	prodSchema, err := currentSchema(ctx, prodDB)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(devTriggers))
+	for _, t := range devTriggers {
+		existing[t] = true
+	}
+	for _, t := range prodTriggers {
+		if existing[t] {
+			continue
+		}
+		ddl, err := showCreateTrigger(ctx, prodDB, t)
+		if err != nil {
+			return fmt.Errorf("SHOW CREATE TRIGGER %s: %w", t, err)
+		}
+		if _, err := devDB.ExecContext(ctx, portableDDL(ddl, prodSchema)); err != nil {
+			return fmt.Errorf("cannot create trigger %s: %w", t, err)
+		}
+		logf(ctx, "Created trigger %s", t)
+	}
+	return nil
+}
+
+// CopyMissingRoutines creates the prod stored procedures and functions that don't exist in dev.
+func CopyMissingRoutines(ctx context.Context, prodDB, devDB *DB) error {
+	const q = `SELECT CONCAT(routine_type, ' ', routine_name) FROM information_schema.routines WHERE routine_schema = DATABASE()`
+	prodRoutines, err := listNames(ctx, prodDB, q)
+	if err != nil {
+		return err
+	}
+	devRoutines, err := listNames(ctx, devDB, q)
+	if err != nil {
+		return err
+	}
+	prodSchema, err := currentSchema(ctx, prodDB)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(devRoutines))
+	for _, r := range devRoutines {
+		existing[r] = true
+	}
+	for _, r := range prodRoutines {
+		if existing[r] {
+			continue
+		}
+		kind, name, _ := strings.Cut(r, " ")
+		if kind != "PROCEDURE" && kind != "FUNCTION" {
+			return fmt.Errorf("unexpected routine type %q for %s", kind, name)
+		}
+
+		// Columns: Procedure|Function, sql_mode, Create Procedure|Function,
+		// character_set_client, collation_connection, Database Collation
+		var n, sqlMode, charset, collation, dbCollation string
+		var ddl *string
+		if err := prodDB.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE %s %s", kind, QuoteIdent(name))).
+			Scan(&n, &sqlMode, &ddl, &charset, &collation, &dbCollation); err != nil {
+			return fmt.Errorf("SHOW CREATE %s %s: %w", kind, name, err)
+		}
+		if ddl == nil {
+			return fmt.Errorf("no definition visible for %s %s (the prod account needs SHOW_ROUTINE or to own it)", kind, name)
+		}
+		if _, err := devDB.ExecContext(ctx, portableDDL(*ddl, prodSchema)); err != nil {
+			return fmt.Errorf("cannot create %s %s: %w", kind, name, err)
+		}
+		logf(ctx, "Created %s %s", kind, name)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package devseeder
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// undoBatchSize is the maximum number of IDs per DELETE statement in an undo script
+const undoBatchSize = 1000
+
+// UndoLog records the primary keys of every row DevSeeder inserts so that
+// exactly those rows can be deleted again later.
+type UndoLog struct {
+	order []string           // tables in the order they were written (parents first)
+	ids   map[string][]int64 // table -> inserted IDs
+	next  *UndoLog           // also records everything, if set
+}
+
+// NewUndoLog returns an empty UndoLog
+func NewUndoLog() *UndoLog {
+	return &UndoLog{ids: make(map[string][]int64)}
+}
+
+// Record remembers the IDs inserted into table.
+// A nil *UndoLog is valid and records nothing.
+func (u *UndoLog) Record(table string, idSet map[int64]bool) {
+	if u == nil || len(idSet) == 0 {
+		return
+	}
+	u.next.Record(table, idSet)
+	if _, seen := u.ids[table]; !seen {
+		u.order = append(u.order, table)
+	}
+	for id := range idSet {
+		u.ids[table] = append(u.ids[table], id)
+	}
+}
+
+// WriteScript writes an SQL script into dir that deletes every recorded row,
+// children before parents. It returns the path of the script.
+func (u *UndoLog) WriteScript(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("cannot create undo directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("devseeder-undo-%s.sql", time.Now().Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "-- DevSeeder undo script, generated %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintln(w, "-- Deletes exactly the rows inserted by the run. Apply with: devseeder undo <this file>")
+	fmt.Fprintln(w, "SET foreign_key_checks = 0;")
+
+	// Walk tables in reverse write order so children are removed before parents
+	for i := len(u.order) - 1; i >= 0; i-- {
+		table := u.order[i]
+		ids := slices.Clone(u.ids[table])
+		slices.Sort(ids)
+		for start := 0; start < len(ids); start += undoBatchSize {
+			end := min(start+undoBatchSize, len(ids))
+			idList := make([]string, 0, end-start)
+			for _, id := range ids[start:end] {
+				idList = append(idList, fmt.Sprintf("%d", id))
+			}
+			fmt.Fprintf(w, "DELETE FROM %s WHERE %s IN (%s);\n", quoteTable(table), QuoteIdent(idColumn), strings.Join(idList, ","))
+		}
+	}
+
+	fmt.Fprintln(w, "SET foreign_key_checks = 1;")
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// ApplyUndoScript executes an undo script written by WriteScript against dev.
+// Each statement in the script is on its own line; comment lines are skipped.
+func ApplyUndoScript(ctx context.Context, db *DB, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	deleted := int64(0)
+	for scanner.Scan() {
+		stmt := strings.TrimSpace(scanner.Text())
+		if stmt == "" || strings.HasPrefix(stmt, "--") {
+			continue
+		}
+		res, err := db.ExecContext(ctx, strings.TrimSuffix(stmt, ";"))
+		if err != nil {
+			return fmt.Errorf("undo statement failed: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			deleted += n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	logf(ctx, "Undo complete: deleted %d rows", deleted)
+	return nil
+}
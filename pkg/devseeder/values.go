@@ -0,0 +1,214 @@
+package devseeder
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// fetchColumnTypes returns { column : columnInfo } for table
+func fetchColumnTypes(ctx context.Context, db *DB, table string) (map[string]columnInfo, error) {
+	infos, err := db.tableColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]columnInfo, len(infos))
+	for _, c := range infos {
+		types[c.Name] = c
+	}
+	return types, nil
+}
+
+// prepareRows converts rowsData in place for insertion into the dev columns
+// described by types.
+//
+// JSON values arrive from the driver as []byte, which MySQL treats as a binary
+// string and refuses for a JSON column; they are validated and sent as text.
+// DECIMAL values stay the driver's exact text and are checked to fit the dev
+// column, as MySQL rounds away extra decimals even in strict mode. BIT values,
+// scanned as big-endian bytes, are sent as integers, which MySQL stores the
+// same for any BIT(n) width. Zero dates are replaced as opts.ZeroDates asks.
+// Binary values are always sent as []byte so no charset conversion applies,
+// and are checked against the dev column size rather than silently truncated
+// under a relaxed sql_mode.
+func prepareRows(table string, columns []string, rowsData [][]interface{}, types map[string]columnInfo, opts SyncOptions) error {
+	for i, c := range columns {
+		t := types[c]
+		switch {
+		case t.DataType == "json":
+			for _, row := range rowsData {
+				b, ok := row[i].([]byte)
+				if !ok {
+					continue
+				}
+				if !json.Valid(b) {
+					return fmt.Errorf("table %s, row %s: column %s holds invalid JSON", table, rowID(columns, row), c)
+				}
+				row[i] = string(b)
+			}
+		case t.DataType == "decimal":
+			if err := checkDecimals(table, columns, i, rowsData, t); err != nil {
+				return err
+			}
+		case t.DataType == "bit":
+			for _, row := range rowsData {
+				if b, ok := row[i].([]byte); ok {
+					row[i] = bitValue(b)
+				}
+			}
+		case isDateType(t.DataType):
+			replaceZeroDates(i, rowsData, opts)
+		case t.DataType == "enum" || t.DataType == "set":
+			if err := checkEnumValues(table, columns, i, rowsData, t); err != nil {
+				return err
+			}
+		case isBinaryType(t.DataType) && t.MaxBytes > 0:
+			for _, row := range rowsData {
+				if s, ok := row[i].(string); ok {
+					row[i] = []byte(s)
+				}
+				if b, ok := row[i].([]byte); ok && int64(len(b)) > t.MaxBytes {
+					return fmt.Errorf("table %s, row %s: %d bytes do not fit dev column %s %s",
+						table, rowID(columns, row), len(b), c, t.ColumnType)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkEnumValues reports the values of an ENUM or SET column that the dev
+// definition does not list, which would fail or, under a relaxed sql_mode, be
+// stored as the empty string. Matching is case-insensitive like MySQL's.
+func checkEnumValues(table string, columns []string, i int, rowsData [][]interface{}, t columnInfo) error {
+	allowed := make(map[string]bool)
+	for _, v := range enumValues(t.ColumnType) {
+		allowed[strings.ToLower(v)] = true
+	}
+	var drifted []string
+	seen := make(map[string]bool)
+	for _, row := range rowsData {
+		var v string
+		switch val := row[i].(type) {
+		case []byte:
+			v = string(val)
+		case string:
+			v = val
+		default:
+			continue
+		}
+		members := []string{v}
+		if t.DataType == "set" {
+			if v == "" {
+				continue
+			}
+			members = strings.Split(v, ",")
+		}
+		for _, m := range members {
+			if !allowed[strings.ToLower(m)] && !seen[m] {
+				seen[m] = true
+				drifted = append(drifted, fmt.Sprintf("%q (row %s)", m, rowID(columns, row)))
+			}
+		}
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("table %s: column %s is %s in dev, which lacks %s; add the values to the dev schema",
+			table, columns[i], t.ColumnType, strings.Join(drifted, ", "))
+	}
+	return nil
+}
+
+// enumValues parses the values out of an ENUM or SET column_type such as
+// enum('a','it”s')
+func enumValues(colType string) []string {
+	open, end := strings.IndexByte(colType, '('), strings.LastIndexByte(colType, ')')
+	if open < 0 || end < open {
+		return nil
+	}
+	var values []string
+	var cur strings.Builder
+	inQuote := false
+	body := colType[open+1 : end]
+	for j := 0; j < len(body); j++ {
+		c := body[j]
+		switch {
+		case c == '\'' && inQuote && j+1 < len(body) && body[j+1] == '\'':
+			cur.WriteByte('\'')
+			j++
+		case c == '\'':
+			if inQuote {
+				values = append(values, cur.String())
+				cur.Reset()
+			}
+			inQuote = !inQuote
+		case inQuote:
+			cur.WriteByte(c)
+		}
+	}
+	return values
+}
+
+// checkDecimals makes sure the values of DECIMAL column i are exact text that
+// fits the dev precision and scale. Floats are refused: any value that went
+// through float64 (e.g. in a transform) may already have lost digits.
+func checkDecimals(table string, columns []string, i int, rowsData [][]interface{}, t columnInfo) error {
+	for _, row := range rowsData {
+		var v string
+		switch val := row[i].(type) {
+		case nil:
+			continue
+		case []byte:
+			v = string(val)
+		case string:
+			v = val
+		case int64, uint64:
+			continue
+		default:
+			return fmt.Errorf("table %s, row %s: column %s is DECIMAL but holds %T %v; pass decimals as strings",
+				table, rowID(columns, row), columns[i], val, val)
+		}
+		intPart, frac, _ := strings.Cut(strings.TrimLeft(v, "+-"), ".")
+		intPart = strings.TrimLeft(intPart, "0")
+		frac = strings.TrimRight(frac, "0")
+		if int64(len(frac)) > t.Scale || int64(len(intPart)) > t.Precision-t.Scale {
+			return fmt.Errorf("table %s, row %s: value %s of column %s does not fit dev %s exactly",
+				table, rowID(columns, row), v, columns[i], t.ColumnType)
+		}
+	}
+	return nil
+}
+
+// bitValue returns the number a BIT(n) column holds, given the big-endian bytes
+// the driver scans it as (n is at most 64)
+func bitValue(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+// valueLiteral renders a value of a column of the given data type as an SQL
+// literal. Binary data is written as a hex literal, like mysqldump --hex-blob,
+// since a quoted string would be read in the script's utf8mb4 charset.
+func valueLiteral(v interface{}, dataType string) string {
+	if b, ok := v.([]byte); ok && len(b) > 0 && isBinaryType(dataType) {
+		return "0x" + hex.EncodeToString(b)
+	}
+	return sqlLiteral(v)
+}
+
+// rowID returns the row's id column for error messages
+func rowID(columns []string, row []interface{}) string {
+	i := slices.Index(columns, idColumn)
+	if i < 0 {
+		return "?"
+	}
+	if b, ok := row[i].([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(row[i])
+}
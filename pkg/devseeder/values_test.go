@@ -0,0 +1,198 @@
+package devseeder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Binary payloads such as images and protobuf messages, mostly not valid UTF-8
+var binaryValues = []struct {
+	name string
+	b    []byte
+}{
+	{"png header", []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0x00, 0x00, 0x0d}},
+	{"protobuf", []byte{0x08, 0x96, 0x01, 0x12, 0x04, 0xff, 0xfe, 0x00, 0x80}},
+	{"nul bytes", []byte{0x00, 'a', 0x00, 0x00, 'b', 0x00}},
+	{"invalid utf-8", []byte{0xc3, 0x28, 0xa0, 0xa1, 0xe2, 0x28, 0xa1}},
+	{"quote and backslash", []byte{'\'', '\\', 0x00, '"', 0x1a}},
+}
+
+func TestPrepareRowsBinary(t *testing.T) {
+	types := map[string]columnInfo{
+		"id":   {Name: "id", DataType: "bigint"},
+		"data": {Name: "data", DataType: "varbinary", ColumnType: "varbinary(64)", MaxBytes: 64},
+	}
+	columns := []string{"id", "data"}
+	for _, tc := range binaryValues {
+		t.Run(tc.name, func(t *testing.T) {
+			// Transforms may hand the value back as a string; it must still go out as bytes
+			for _, v := range []interface{}{bytes.Clone(tc.b), string(tc.b)} {
+				rows := [][]interface{}{{int64(1), v}}
+				if err := prepareRows("files", columns, rows, types, SyncOptions{}); err != nil {
+					t.Fatal(err)
+				}
+				got, ok := rows[0][1].([]byte)
+				if !ok {
+					t.Fatalf("prepareRows left %T, want []byte", rows[0][1])
+				}
+				if !bytes.Equal(got, tc.b) {
+					t.Errorf("prepareRows changed %x to %x", tc.b, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPrepareRowsBinaryTooLong(t *testing.T) {
+	types := map[string]columnInfo{
+		"data": {Name: "data", DataType: "varbinary", ColumnType: "varbinary(4)", MaxBytes: 4},
+	}
+	rows := [][]interface{}{{[]byte{0x00, 0xff, 0x00, 0xff, 0x00}}}
+	if err := prepareRows("files", []string{"data"}, rows, types, SyncOptions{}); err == nil {
+		t.Fatal("prepareRows accepted 5 bytes for varbinary(4)")
+	}
+}
+
+func TestValueLiteralBinary(t *testing.T) {
+	for _, dataType := range []string{"binary", "varbinary", "blob", "longblob"} {
+		for _, tc := range binaryValues {
+			t.Run(dataType+"/"+tc.name, func(t *testing.T) {
+				lit := valueLiteral(tc.b, dataType)
+				digits, ok := strings.CutPrefix(lit, "0x")
+				if !ok {
+					t.Fatalf("valueLiteral = %q, want a 0x hex literal", lit)
+				}
+				got, err := hex.DecodeString(digits)
+				if err != nil {
+					t.Fatalf("valueLiteral = %q: %v", lit, err)
+				}
+				if !bytes.Equal(got, tc.b) {
+					t.Errorf("0x literal decodes to %x, want %x", got, tc.b)
+				}
+			})
+		}
+	}
+}
+
+func TestValueLiteralText(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{[]byte{}, "''"},
+		{[]byte("a\x00b"), `'a\0b'`},
+		{"it's", `'it\'s'`},
+		{nil, "NULL"},
+	}
+	for _, tc := range tests {
+		if got := valueLiteral(tc.v, "varchar"); got != tc.want {
+			t.Errorf("valueLiteral(%q) = %s, want %s", tc.v, got, tc.want)
+		}
+	}
+	if got := valueLiteral([]byte{}, "blob"); got != "''" {
+		t.Errorf("valueLiteral of an empty blob = %s, want ''", got)
+	}
+}
+
+func TestJSONValueBinary(t *testing.T) {
+	for _, tc := range binaryValues {
+		t.Run(tc.name, func(t *testing.T) {
+			// Binary columns and any bytes that are not UTF-8 are base64-encoded
+			for _, dataType := range []string{"blob", "varchar"} {
+				if dataType == "varchar" && utf8.Valid(tc.b) {
+					if got := jsonValue(tc.b, dataType); got != string(tc.b) {
+						t.Errorf("jsonValue(varchar) = %q, want the text unchanged", got)
+					}
+					continue
+				}
+				v, ok := jsonValue(tc.b, dataType).(string)
+				if !ok {
+					t.Fatalf("jsonValue(%s) = %T, want a base64 string", dataType, jsonValue(tc.b, dataType))
+				}
+				got, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, tc.b) {
+					t.Errorf("jsonValue(%s) decodes to %x, want %x", dataType, got, tc.b)
+				}
+			}
+		})
+	}
+}
+
+// A DECIMAL(65,30) money column
+var moneyColumn = columnInfo{Name: "amount", DataType: "decimal", ColumnType: "decimal(65,30)", Precision: 65, Scale: 30}
+
+func TestPrepareRowsDecimalExact(t *testing.T) {
+	tests := []string{
+		"12345678901234567890123456789012345.123456789012345678901234567890", // every digit of DECIMAL(65,30)
+		"-99999999999999999999999999999999999.999999999999999999999999999999",
+		"9007199254740993.000000000000000000000000000001", // past float64's 2^53
+		"0.100000000000000000000000000000",
+		"0.000000000000000000000000000001",
+		"-0.5",
+		"0",
+	}
+	types := map[string]columnInfo{"id": {Name: "id", DataType: "bigint"}, "amount": moneyColumn}
+	columns := []string{"id", "amount"}
+	for _, v := range tests {
+		rows := [][]interface{}{{int64(1), []byte(v)}, {int64(2), v}}
+		if err := prepareRows("payments", columns, rows, types, SyncOptions{}); err != nil {
+			t.Errorf("prepareRows(%s): %v", v, err)
+			continue
+		}
+		if got, ok := rows[0][1].([]byte); !ok || string(got) != v {
+			t.Errorf("prepareRows changed []byte %s to %T %v", v, rows[0][1], rows[0][1])
+		}
+		if got, ok := rows[1][1].(string); !ok || got != v {
+			t.Errorf("prepareRows changed string %s to %T %v", v, rows[1][1], rows[1][1])
+		}
+		if got, want := valueLiteral([]byte(v), "decimal"), "'"+v+"'"; got != want {
+			t.Errorf("valueLiteral = %s, want %s", got, want)
+		}
+		if got := jsonValue([]byte(v), "decimal"); got != json.Number(v) {
+			t.Errorf("jsonValue = %v, want json.Number %s", got, v)
+		}
+	}
+}
+
+func TestCheckDecimalsRejectsLossy(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		col  columnInfo
+	}{
+		{"float64", 0.1, moneyColumn},
+		{"float64 past 2^53", float64(9007199254740993), moneyColumn},
+		{"float32", float32(19.99), moneyColumn},
+		{"too many decimals", "1.1234567890123456789012345678901", moneyColumn},
+		{"too many integer digits", "123456789012345678901234567890123456", moneyColumn},
+		{"rounded by narrower dev scale", "19.995", columnInfo{DataType: "decimal", ColumnType: "decimal(10,2)", Precision: 10, Scale: 2}},
+		{"overflows narrower dev precision", "123456789.00", columnInfo{DataType: "decimal", ColumnType: "decimal(10,2)", Precision: 10, Scale: 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rows := [][]interface{}{{int64(7), tc.v}}
+			if err := checkDecimals("payments", []string{"id", "amount"}, 1, rows, tc.col); err == nil {
+				t.Errorf("checkDecimals accepted %v for %s", tc.v, tc.col.ColumnType)
+			}
+		})
+	}
+}
+
+func TestCheckDecimalsAcceptsPadding(t *testing.T) {
+	col := columnInfo{DataType: "decimal", ColumnType: "decimal(10,2)", Precision: 10, Scale: 2}
+	// Leading and trailing zeros carry no digits that could be lost
+	for _, v := range []interface{}{"00012345678.90000", "-0.10", []byte("99999999.99"), int64(42), nil} {
+		rows := [][]interface{}{{int64(1), v}}
+		if err := checkDecimals("payments", []string{"id", "amount"}, 1, rows, col); err != nil {
+			t.Errorf("checkDecimals(%v): %v", v, err)
+		}
+	}
+}
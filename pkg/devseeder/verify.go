@@ -0,0 +1,329 @@
+package devseeder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// What a failed post-sync check does
+const (
+	VerifyOff  = ""     // don't run the check
+	VerifyWarn = "warn" // log every problem and carry on
+	VerifyFail = "fail" // log every problem and fail the sync
+)
+
+// Verification selects the checks run on dev after a sync, each VerifyOff,
+// VerifyWarn or VerifyFail.
+type Verification struct {
+	// Every planned row is present in dev
+	RowCounts string
+	// No copied row references a parent row missing from dev
+	ForeignKeys string
+	// Every copied row is identical in prod and dev
+	Checksums string
+	// Every character column of the copied rows is byte-identical in prod and dev
+	Text string
+}
+
+// VerifyRowCounts counts the planned IDs of every table in dev and returns one
+// problem per table that is missing some of them, e.g. because part of a batch
+// was rejected.
+func VerifyRowCounts(ctx context.Context, devDB *DB, plan *CopyPlan, opts SyncOptions) ([]string, error) {
+	var problems []string
+	for _, table := range plan.Order {
+		if f, ok := plan.Full[table]; ok {
+			// Rows dev had before may count too; only a shortfall is certain
+			devTable := opts.devTable(table)
+			n, err := countRows(ctx, devDB, devTable, nil)
+			if err != nil {
+				return nil, fmt.Errorf("counting rows in dev %s: %w", devTable, err)
+			}
+			if n < f.Rows {
+				problems = append(problems, fmt.Sprintf("table %s: %d rows in dev, prod had %d when planned", table, n, f.Rows))
+			}
+			continue
+		}
+		idSet := plan.RowSets[table]
+		if len(idSet) == 0 {
+			continue
+		}
+		devTable := opts.devTable(table)
+		found := 0
+		for _, batch := range idBatches(idSet, copyBatchSize) {
+			args := idArgs(batch)
+			var n int
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IN (%s)",
+				quoteTable(devTable), QuoteIdent(idColumn), placeholders(len(args)))
+			if err := devDB.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+				return nil, fmt.Errorf("counting rows in dev %s: %w", devTable, err)
+			}
+			found += n
+		}
+		if found != len(idSet) {
+			problems = append(problems, fmt.Sprintf("table %s: %d of %d planned rows are in dev", table, found, len(idSet)))
+		}
+	}
+	return problems, nil
+}
+
+// VerifyForeignKeys looks for copied dev rows whose FK values point at parent rows
+// missing from dev, which foreign_key_checks=0 lets through. It returns one problem
+// per FK with orphans. FKs the copy deliberately does not follow (nullable and
+// self-references) are only logged.
+func VerifyForeignKeys(ctx context.Context, devDB *DB, allFks []ForeignKey, plan *CopyPlan, opts SyncOptions) ([]string, error) {
+	var problems []string
+	for _, fk := range allFks {
+		if plan.Rows(fk.FromTable) == 0 {
+			continue
+		}
+		child, parent := opts.devTable(fk.FromTable), opts.devTable(fk.ToTable)
+		childColumn, parentColumn := fk.FromColumn, fk.ToColumn
+		if dev, ok := opts.ColumnMap[fk.FromTable][childColumn]; ok {
+			childColumn = dev
+		}
+		if dev, ok := opts.ColumnMap[fk.ToTable][parentColumn]; ok {
+			parentColumn = dev
+		}
+		next := setBatches(plan.RowSets[fk.FromTable])
+		if _, ok := plan.Full[fk.FromTable]; ok {
+			next = tableBatches(devDB, child, nil)
+		}
+		orphans := 0
+		for {
+			batch, err := next(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("reading the IDs of dev %s: %w", child, err)
+			}
+			if len(batch) == 0 {
+				break
+			}
+			args := idArgs(batch)
+			query := fmt.Sprintf(`SELECT COUNT(*) FROM %s c LEFT JOIN %s p ON p.%s = c.%s
+				WHERE c.%s IN (%s) AND c.%s IS NOT NULL AND p.%s IS NULL`,
+				quoteTable(child), quoteTable(parent), QuoteIdent(parentColumn), QuoteIdent(childColumn),
+				QuoteIdent(idColumn), placeholders(len(args)), QuoteIdent(childColumn), QuoteIdent(parentColumn))
+			var n int
+			if err := devDB.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+				return nil, fmt.Errorf("checking %s.%s -> %s.%s in dev: %w", child, childColumn, parent, parentColumn, err)
+			}
+			orphans += n
+		}
+		if orphans == 0 {
+			continue
+		}
+		problem := fmt.Sprintf("%d rows of %s reference missing %s rows through %s", orphans, fk.FromTable, fk.ToTable, fk.FromColumn)
+		if fk.IsNullable || fk.FromTable == fk.ToTable {
+			logf(ctx, "Warning: %s (FK not followed by the copy)", problem)
+			continue
+		}
+		problems = append(problems, problem)
+	}
+	return problems, nil
+}
+
+// VerifyChecksums hashes the copied rows of every table on both prod and dev and
+// returns one problem per table whose hashes differ. Each side hashes its rows
+// server-side (SHA-256 over the hex of every column both sides insert), and the
+// row hashes are combined in ID order. Tables changed on purpose in transit,
+// e.g. anonymized ones, are expected to differ.
+func VerifyChecksums(ctx context.Context, prodDB, devDB *DB, plan *CopyPlan, opts SyncOptions) ([]string, error) {
+	var problems []string
+	for _, table := range plan.Order {
+		if plan.Rows(table) == 0 {
+			continue
+		}
+		devTable := opts.devTable(table)
+		columns, devColumns, err := comparableColumns(ctx, prodDB, devDB, table, devTable, opts.ColumnMap[table])
+		if err != nil {
+			return nil, err
+		}
+		prodSum, err := batchesChecksum(ctx, prodDB, table, columns, plan.batches(prodDB, table))
+		if err != nil {
+			return nil, fmt.Errorf("checksumming prod %s: %w", table, err)
+		}
+		devSum, err := batchesChecksum(ctx, devDB, devTable, devColumns, plan.batches(prodDB, table))
+		if err != nil {
+			return nil, fmt.Errorf("checksumming dev %s: %w", devTable, err)
+		}
+		if prodSum != devSum {
+			problems = append(problems, fmt.Sprintf("table %s: checksums differ (prod %s, dev %s)", table, prodSum[:12], devSum[:12]))
+		}
+	}
+	return problems, nil
+}
+
+// VerifyText hashes each character column of the copied rows on both prod and
+// dev and returns one problem per column whose bytes differ, e.g. emoji turned
+// into '?' by a utf8mb3 column or connection.
+func VerifyText(ctx context.Context, prodDB, devDB *DB, plan *CopyPlan, opts SyncOptions) ([]string, error) {
+	charsets, err := columnCharsets(ctx, prodDB, plan.Order)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, table := range plan.Order {
+		if plan.Rows(table) == 0 {
+			continue
+		}
+		devTable := opts.devTable(table)
+		columns, devColumns, err := comparableColumns(ctx, prodDB, devDB, table, devTable, opts.ColumnMap[table])
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range columns {
+			if charsets[table+"."+c] == "" {
+				continue
+			}
+			prodSum, err := batchesChecksum(ctx, prodDB, table, []string{c}, plan.batches(prodDB, table))
+			if err != nil {
+				return nil, fmt.Errorf("checksumming prod %s.%s: %w", table, c, err)
+			}
+			devSum, err := batchesChecksum(ctx, devDB, devTable, []string{devColumns[i]}, plan.batches(prodDB, table))
+			if err != nil {
+				return nil, fmt.Errorf("checksumming dev %s.%s: %w", devTable, devColumns[i], err)
+			}
+			if prodSum != devSum {
+				problems = append(problems, fmt.Sprintf("column %s.%s (%s in prod) differs in dev", table, c, charsets[table+"."+c]))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// comparableColumns returns the prod columns that dev has and stores rather than
+// computes, and the dev name of each (renames maps prod names to dev ones)
+func comparableColumns(ctx context.Context, prodDB, devDB *DB, table, devTable string, renames map[string]string) ([]string, []string, error) {
+	prodColumns, err := fetchColumns(ctx, prodDB, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	devColumns, err := fetchColumns(ctx, devDB, devTable)
+	if err != nil {
+		return nil, nil, err
+	}
+	generated, err := fetchGeneratedColumns(ctx, devDB, devTable)
+	if err != nil {
+		return nil, nil, err
+	}
+	inDev := make(map[string]bool, len(devColumns))
+	for _, c := range devColumns {
+		inDev[c] = !generated[c]
+	}
+	var columns, devNames []string
+	for i, dev := range renameColumns(prodColumns, renames) {
+		if inDev[dev] {
+			columns = append(columns, prodColumns[i])
+			devNames = append(devNames, dev)
+		}
+	}
+	return columns, devNames, nil
+}
+
+// tableChecksum returns the hex SHA-256 of "<id>:<row hash>" lines for the given
+// rows of table, in ID order. Missing rows change the result too.
+func tableChecksum(ctx context.Context, db *DB, table string, columns []string, idSet map[int64]bool) (string, error) {
+	return batchesChecksum(ctx, db, table, columns, setBatches(idSet))
+}
+
+// batchesChecksum is tableChecksum over the IDs next hands out
+func batchesChecksum(ctx context.Context, db *DB, table string, columns []string, next idBatchIter) (string, error) {
+	hexed := make([]string, len(columns))
+	for i, c := range columns {
+		// HEX never yields 'NULL', so NULL and the string 'NULL' stay distinct
+		hexed[i] = fmt.Sprintf("IFNULL(HEX(%s), 'NULL')", QuoteIdent(c))
+	}
+	rowHash := fmt.Sprintf("SHA2(CONCAT_WS(',', %s), 256)", strings.Join(hexed, ", "))
+
+	sum := sha256.New()
+	for {
+		batch, err := next(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		args := idArgs(batch)
+		query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s) ORDER BY %s",
+			QuoteIdent(idColumn), rowHash, quoteTable(table), QuoteIdent(idColumn), placeholders(len(args)), QuoteIdent(idColumn))
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return "", err
+		}
+		for rows.Next() {
+			var id int64
+			var h string
+			if err := rows.Scan(&id, &h); err != nil {
+				rows.Close()
+				return "", err
+			}
+			fmt.Fprintf(sum, "%d:%s\n", id, h)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// applyVerifyMode logs the problems a check found and, in VerifyFail mode,
+// turns them into an error.
+func applyVerifyMode(ctx context.Context, check, mode string, problems []string) error {
+	if len(problems) == 0 {
+		logf(ctx, "Verified %s: OK", check)
+		return nil
+	}
+	for _, p := range problems {
+		logf(ctx, "Warning: %s verification: %s", check, p)
+	}
+	if mode == VerifyFail {
+		return fmt.Errorf("%s verification failed: %s", check, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// verify runs the configured checks on a finished copy
+func (s *Seeder) verify(ctx context.Context, allFks []ForeignKey, plan *CopyPlan, opts SyncOptions) error {
+	if s.verification.RowCounts != VerifyOff {
+		problems, err := VerifyRowCounts(ctx, s.dev, plan, opts)
+		if err != nil {
+			return err
+		}
+		if err := applyVerifyMode(ctx, "row count", s.verification.RowCounts, problems); err != nil {
+			return err
+		}
+	}
+	if s.verification.ForeignKeys != VerifyOff {
+		problems, err := VerifyForeignKeys(ctx, s.dev, allFks, plan, opts)
+		if err != nil {
+			return err
+		}
+		if err := applyVerifyMode(ctx, "foreign key", s.verification.ForeignKeys, problems); err != nil {
+			return err
+		}
+	}
+	if s.verification.Checksums != VerifyOff {
+		problems, err := VerifyChecksums(ctx, s.prod, s.dev, plan, opts)
+		if err != nil {
+			return err
+		}
+		if err := applyVerifyMode(ctx, "checksum", s.verification.Checksums, problems); err != nil {
+			return err
+		}
+	}
+	if s.verification.Text != VerifyOff {
+		problems, err := VerifyText(ctx, s.prod, s.dev, plan, opts)
+		if err != nil {
+			return err
+		}
+		if err := applyVerifyMode(ctx, "text", s.verification.Text, problems); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package devseeder
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// definerClause matches the DEFINER=`user`@`host` part of SHOW CREATE output.
+// The prod account usually doesn't exist on dev, so it is dropped and the
+// object is owned by the dev account instead.
+var definerClause = regexp.MustCompile("DEFINER=`(?:[^`]|``)*`@`(?:[^`]|``)*`\\s*")
+
+// portableDDL rewrites a prod CREATE statement so it can run in the dev schema:
+// the definer is dropped and references qualified with the prod schema name are unqualified.
+func portableDDL(ddl, prodSchema string) string {
+	ddl = definerClause.ReplaceAllString(ddl, "")
+	return strings.ReplaceAll(ddl, QuoteIdent(prodSchema)+".", "")
+}
+
+// currentSchema returns the name of the database the connection uses
+func currentSchema(ctx context.Context, db *DB) (string, error) {
+	var name string
+	err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&name)
+	return name, err
+}
+
+// CopyViews recreates every prod view in dev (CREATE OR REPLACE).
+// Views can depend on other views, so creation is retried until no more progress is made.
+func CopyViews(ctx context.Context, prodDB, devDB *DB) error {
+	prodSchema, err := currentSchema(ctx, prodDB)
+	if err != nil {
+		return err
+	}
+
+	rows, err := prodDB.QueryContext(ctx, `
+	SELECT table_name
+	FROM information_schema.views
+	WHERE table_schema = DATABASE()
+	ORDER BY table_name`)
+	if err != nil {
+		return err
+	}
+	var views []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		views = append(views, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ddls := make(map[string]string, len(views))
+	for _, v := range views {
+		var name, ddl, charset, collation string
+		if err := prodDB.QueryRowContext(ctx, "SHOW CREATE VIEW "+QuoteIdent(v)).Scan(&name, &ddl, &charset, &collation); err != nil {
+			return fmt.Errorf("SHOW CREATE VIEW %s: %w", v, err)
+		}
+		ddl = portableDDL(ddl, prodSchema)
+		ddls[v] = strings.Replace(ddl, "CREATE ", "CREATE OR REPLACE ", 1)
+	}
+
+	pending := views
+	for len(pending) > 0 {
+		var failed []string
+		var lastErr error
+		for _, v := range pending {
+			if _, err := devDB.ExecContext(ctx, ddls[v]); err != nil {
+				failed = append(failed, v)
+				lastErr = err
+				continue
+			}
+			logf(ctx, "Created view %s", v)
+		}
+		if len(failed) == len(pending) {
+			return fmt.Errorf("cannot create views %v: %w", failed, lastErr)
+		}
+		pending = failed
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package devseeder
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// WASMABIVersion is the version of the row transform ABI this build implements.
+//
+// A policy module (any language compiling to WebAssembly, WASI allowed) exports
+// its memory and:
+//
+//	devseeder_abi_version() -> i32               must return WASMABIVersion
+//	devseeder_alloc(size i32) -> i32             a buffer of size bytes for the input
+//	devseeder_transform(ptr i32, len i32) -> i64 transform one row
+//	devseeder_free(ptr i32, len i32)             optional; called on both buffers
+//
+// The input is the JSON object {"table": "users", "row": {"id": 1, "email": "..."}}.
+// The result packs the output's pointer in the high 32 bits and its length in the
+// low ones; the output is {"row": {...}} or {"error": "..."}. Columns left out of
+// the output row keep their value. Values are null, numbers, strings, or
+// {"$base64": "..."} for bytes that are not valid UTF-8; dates and times are
+// strings as MySQL writes them.
+const WASMABIVersion = 1
+
+// WASMTransform is a RowTransform running a WebAssembly policy module. Its
+// functions are not safe for concurrent use.
+type WASMTransform struct {
+	path    string
+	tables  []string // empty: every table
+	runtime wazero.Runtime
+	mod     api.Module
+
+	alloc, free, transform api.Function
+}
+
+// LoadWASMTransform compiles and instantiates the module at path, which then
+// transforms the rows of tables (every table when empty). Close releases it.
+func LoadWASMTransform(ctx context.Context, path string, tables []string) (_ *WASMTransform, err error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := wazero.NewRuntime(ctx)
+	defer func() {
+		if err != nil {
+			r.Close(ctx)
+		}
+	}()
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+	// Reactor modules initialize through _initialize; _start would run a command's main
+	cfg := wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize").
+		WithStdout(os.Stderr).WithStderr(os.Stderr).WithSysWalltime()
+	mod, err := r.InstantiateWithConfig(ctx, code, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating %s: %w", path, err)
+	}
+	t := &WASMTransform{path: path, tables: tables, runtime: r, mod: mod,
+		alloc: mod.ExportedFunction("devseeder_alloc"), free: mod.ExportedFunction("devseeder_free"),
+		transform: mod.ExportedFunction("devseeder_transform")}
+
+	version := mod.ExportedFunction("devseeder_abi_version")
+	if version == nil || t.alloc == nil || t.transform == nil || mod.Memory() == nil {
+		return nil, fmt.Errorf("%s does not export memory, devseeder_abi_version, devseeder_alloc and devseeder_transform", path)
+	}
+	res, err := version.Call(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: devseeder_abi_version: %w", path, err)
+	}
+	if v := api.DecodeI32(res[0]); v != WASMABIVersion {
+		return nil, fmt.Errorf("%s implements ABI version %d, this devseeder version implements %d", path, v, WASMABIVersion)
+	}
+	return t, nil
+}
+
+// Close releases the module.
+func (t *WASMTransform) Close(ctx context.Context) error {
+	return t.runtime.Close(ctx)
+}
+
+// TransformRows passes each row through the module's devseeder_transform.
+func (t *WASMTransform) TransformRows(ctx context.Context, table string, columns []string, rows [][]interface{}) error {
+	if len(t.tables) > 0 && !slices.Contains(t.tables, table) {
+		return nil
+	}
+	for _, row := range rows {
+		if err := t.transformRow(ctx, table, columns, row); err != nil {
+			return fmt.Errorf("%s, row %s: %w", t.path, rowID(columns, row), err)
+		}
+	}
+	return nil
+}
+
+func (t *WASMTransform) transformRow(ctx context.Context, table string, columns []string, row []interface{}) error {
+	in := make(map[string]interface{}, len(columns))
+	for i, c := range columns {
+		in[c] = wasmValue(row[i])
+	}
+	input, err := json.Marshal(map[string]interface{}{"table": table, "row": in})
+	if err != nil {
+		return err
+	}
+
+	res, err := t.alloc.Call(ctx, uint64(len(input)))
+	if err != nil {
+		return fmt.Errorf("devseeder_alloc: %w", err)
+	}
+	ptr := api.DecodeU32(res[0])
+	if !t.mod.Memory().Write(ptr, input) {
+		return fmt.Errorf("devseeder_alloc returned a buffer outside memory")
+	}
+	res, err = t.transform.Call(ctx, uint64(ptr), uint64(len(input)))
+	t.release(ctx, ptr, uint32(len(input)))
+	if err != nil {
+		return fmt.Errorf("devseeder_transform: %w", err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	view, ok := t.mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return fmt.Errorf("devseeder_transform returned a result outside memory")
+	}
+	output := bytes.Clone(view)
+	t.release(ctx, outPtr, outLen)
+
+	var out struct {
+		Row   map[string]json.RawMessage `json:"row"`
+		Error string                     `json:"error"`
+	}
+	if err := json.Unmarshal(output, &out); err != nil {
+		return fmt.Errorf("decoding result: %w", err)
+	}
+	if out.Error != "" {
+		return fmt.Errorf("policy error: %s", out.Error)
+	}
+	for c, raw := range out.Row {
+		i := slices.Index(columns, c)
+		if i < 0 {
+			return fmt.Errorf("result has unknown column %s", c)
+		}
+		// Unchanged values keep their driver type (e.g. BIT bytes)
+		if orig, err := json.Marshal(in[c]); err == nil && bytes.Equal(orig, compactJSON(raw)) {
+			continue
+		}
+		if c == idColumn {
+			return fmt.Errorf("policy changed the id")
+		}
+		if row[i], err = wasmResultValue(raw); err != nil {
+			return fmt.Errorf("column %s: %w", c, err)
+		}
+	}
+	return nil
+}
+
+func (t *WASMTransform) release(ctx context.Context, ptr, size uint32) {
+	if t.free != nil {
+		t.free.Call(ctx, uint64(ptr), uint64(size))
+	}
+}
+
+// wasmValue converts a value scanned from the driver to its ABI JSON form
+func wasmValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []byte:
+		if utf8.Valid(val) {
+			return string(val)
+		}
+		return map[string]string{"$base64": base64.StdEncoding.EncodeToString(val)}
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05.999999")
+	}
+	return v
+}
+
+// wasmResultValue converts a value of the module's output to what the driver sends
+func wasmResultValue(raw json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch val := v.(type) {
+	case nil, string:
+		return val, nil
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n, nil
+		}
+		// decimals stay text so no digits are lost
+		return val.String(), nil
+	case bool:
+		if val {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case map[string]interface{}:
+		if s, ok := val["$base64"].(string); ok && len(val) == 1 {
+			return base64.StdEncoding.DecodeString(s)
+		}
+	}
+	// other objects and arrays are JSON column values
+	return string(compactJSON(raw)), nil
+}
+
+func compactJSON(raw json.RawMessage) []byte {
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		return raw
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,118 @@
+package devseeder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// How 0000-00-00 dates from prod are written to dev (see WithZeroDates)
+const (
+	ZeroDatesKeep     = ""         // copied as they are; a strict dev sql_mode fails the insert
+	ZeroDatesRelax    = "relax"    // copied as they are, with NO_ZERO_DATE/NO_ZERO_IN_DATE dropped from the dev session
+	ZeroDatesNull     = "null"     // written as NULL
+	ZeroDatesSentinel = "sentinel" // replaced by a fixed date
+)
+
+// MySQL error "Incorrect datetime value"
+const errTruncatedWrongValue = 1292
+
+// WithZeroDates selects how 0000-00-00 dates are handled (see ZeroDatesKeep and
+// friends); sentinel is the date written in their place with ZeroDatesSentinel.
+func WithZeroDates(mode string, sentinel time.Time) Option {
+	return func(s *Seeder) {
+		s.opts.ZeroDates = mode
+		s.opts.ZeroDateSentinel = sentinel
+	}
+}
+
+// mysqlTime formats t as MySQL datetime text in layout. The zero time.Time is
+// how the driver scans a 0000-00-00 date, so it is written back as one rather
+// than as the real date 0001-01-01.
+func mysqlTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return "0000-00-00 00:00:00"
+	}
+	return t.Format(layout)
+}
+
+// isDateType reports whether a MySQL data_type can hold a zero date
+func isDateType(dataType string) bool {
+	return dataType == "date" || dataType == "datetime" || dataType == "timestamp"
+}
+
+// isZeroDate reports whether v, scanned from a date column, is 0000-00-00 (with
+// or without a time part). With parseTime the driver scans it as the zero time.Time.
+func isZeroDate(v interface{}) bool {
+	switch val := v.(type) {
+	case time.Time:
+		return val.IsZero()
+	case []byte:
+		return bytes.HasPrefix(val, []byte("0000-00-00"))
+	case string:
+		return strings.HasPrefix(val, "0000-00-00")
+	}
+	return false
+}
+
+// replaceZeroDates rewrites the zero dates of column i according to opts.ZeroDates
+func replaceZeroDates(i int, rowsData [][]interface{}, opts SyncOptions) {
+	if opts.ZeroDates != ZeroDatesNull && opts.ZeroDates != ZeroDatesSentinel {
+		return
+	}
+	for _, row := range rowsData {
+		if !isZeroDate(row[i]) {
+			continue
+		}
+		if opts.ZeroDates == ZeroDatesNull {
+			row[i] = nil
+		} else {
+			row[i] = opts.ZeroDateSentinel
+		}
+	}
+}
+
+// relaxZeroDates drops NO_ZERO_DATE and NO_ZERO_IN_DATE from the dev session's
+// sql_mode and returns a func that restores it. The returned func ignores
+// cancellation so it always runs.
+func relaxZeroDates(ctx context.Context, devDB *DB) (func(), error) {
+	var mode string
+	if err := devDB.QueryRowContext(ctx, "SELECT @@SESSION.sql_mode").Scan(&mode); err != nil {
+		return nil, fmt.Errorf("cannot read dev sql_mode: %w", err)
+	}
+	var relaxed []string
+	for _, m := range strings.Split(mode, ",") {
+		if m != "NO_ZERO_DATE" && m != "NO_ZERO_IN_DATE" && m != "" {
+			relaxed = append(relaxed, m)
+		}
+	}
+	if _, err := devDB.ExecContext(ctx, "SET SESSION sql_mode = ?", strings.Join(relaxed, ",")); err != nil {
+		return nil, fmt.Errorf("cannot relax dev sql_mode: %w", err)
+	}
+	devDB.keepSession(ctx, "sql_mode", func(ctx context.Context) error {
+		_, err := devDB.DB.ExecContext(ctx, "SET SESSION sql_mode = ?", strings.Join(relaxed, ","))
+		return err
+	})
+	return func() {
+		devDB.forgetSession("sql_mode")
+		if _, err := devDB.ExecContext(context.WithoutCancel(ctx), "SET SESSION sql_mode = ?", mode); err != nil {
+			logf(ctx, "Warning: cannot restore dev sql_mode: %v\n", err)
+		}
+	}, nil
+}
+
+// explainZeroDateError turns MySQL's rejection of a zero date into one that says
+// which dev table refused it and how to get past it.
+func explainZeroDateError(table string, err error) error {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == errTruncatedWrongValue && strings.Contains(myErr.Message, "'0000-00-00") {
+		return fmt.Errorf("dev table %s rejects the 0000-00-00 dates copied from prod (strict sql_mode with NO_ZERO_DATE); "+
+			"set zero_dates to relax, null or sentinel: %w", table, err)
+	}
+	return err
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// planCommand computes the subset without writing anything and prints it, with
+// the FK path that pulled each table in, so it can be reviewed before a sync.
+// With -simulate it only approximates the table sizes from statistics, which is
+// fast enough to iterate on limits against a huge prod.
+// With -out the plan is also written to a file that devseeder apply runs later.
+// Usage: devseeder plan [flags] [-format tree|table] [-simulate] [-out plan.json]
+func planCommand(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	format := fs.String("format", "tree", "output format: tree (seed tables down to the parents they pull in) or table (one line per table)")
+	simulate := fs.Bool("simulate", false, "approximate each table's share from table and index statistics instead of reading any IDs")
+	out := fs.String("out", "", "also write the plan (tables, IDs, order and FKs) to this file, to run later with devseeder apply")
+	fs.Parse(args)
+	if *simulate && *out != "" {
+		fatalf(exitConfig, "-simulate reads no IDs, so there is no plan to write with -out\n")
+	}
+	if *format != "tree" && *format != "table" {
+		fatalf(exitFailure, "Unknown plan format %q (expected tree or table)\n", *format)
+	}
+
+	cfg := flags.load()
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	closeSource := openSource(ctx, cfg, audit)
+	defer closeSource()
+
+	prodDB, err := OpenProdDatabase(cfg)
+	if err != nil {
+		fatalf(exitConnection, "Error opening prod database: %v\n", err)
+	}
+	defer prodDB.Close()
+
+	seeder := devseeder.New(prodDB, nil, seederOptions(cfg, audit)...)
+	if *simulate {
+		est, err := seeder.Simulate(ctx)
+		if err != nil {
+			fatalf(exitFailure, "Error: %v\n", err)
+		}
+		fmt.Println("Approximated from table and index statistics; run without -simulate for exact counts")
+		printEstimate(os.Stdout, est)
+		return
+	}
+	plan, err := seeder.Plan(ctx)
+	if err != nil {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+	if *format == "table" {
+		printPlanTable(os.Stdout, plan)
+	} else {
+		printPlanTree(os.Stdout, plan)
+	}
+	if *out != "" {
+		if err := writePlanFile(ctx, seeder, plan, *out); err != nil {
+			fatalf(exitFailure, "Error writing the plan: %v\n", err)
+		}
+		log.Printf("Plan written to %s; run it with: devseeder apply %s", *out, *out)
+	}
+
+	est, err := seeder.Estimate(ctx, plan)
+	if err != nil {
+		fatalf(exitFailure, "Error estimating the run: %v\n", err)
+	}
+	fmt.Println()
+	printEstimate(os.Stdout, est)
+}
+
+// printPlanTable prints one line per table in copy order with the FKs that pulled it in.
+func printPlanTable(w io.Writer, plan *devseeder.CopyPlan) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tROWS\tINCLUDED BECAUSE")
+	for _, table := range plan.Order {
+		var why []string
+		if limit, ok := plan.Seeds[table]; ok {
+			why = append(why, fmt.Sprintf("seed (limit %s)", limitLabel(limit)))
+		}
+		for _, r := range plan.Reasons[table] {
+			why = append(why, fmt.Sprintf("%s.%s (%d rows)", r.ChildTable, r.ChildColumn, r.Rows))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", table, plan.Rows(table), strings.Join(why, ", "))
+	}
+	tw.Flush()
+	printPlanTotals(w, plan)
+}
+
+// printPlanTree prints each seed table with the parent tables it pulls in below
+// it, labelled with the FK column that references them. A table reached again
+// is not expanded twice.
+func printPlanTree(w io.Writer, plan *devseeder.CopyPlan) {
+	// child table -> tables it pulled in, with the FK used
+	type edge struct {
+		parent string
+		reason devseeder.InclusionReason
+	}
+	pulled := make(map[string][]edge)
+	for parent, reasons := range plan.Reasons {
+		for _, r := range reasons {
+			pulled[r.ChildTable] = append(pulled[r.ChildTable], edge{parent, r})
+		}
+	}
+	for _, edges := range pulled {
+		sort.Slice(edges, func(i, j int) bool { return edges[i].parent < edges[j].parent })
+	}
+
+	shown := make(map[string]bool)
+	var walk func(table, indent string)
+	walk = func(table, indent string) {
+		edges := pulled[table]
+		for i, e := range edges {
+			branch, next := "├── ", "│   "
+			if i == len(edges)-1 {
+				branch, next = "└── ", "    "
+			}
+			label := fmt.Sprintf("%s (%d rows) via %s.%s", e.parent, e.reason.Rows, table, e.reason.ChildColumn)
+			if shown[e.parent] {
+				fmt.Fprintf(w, "%s%s%s, see above\n", indent, branch, label)
+				continue
+			}
+			shown[e.parent] = true
+			fmt.Fprintf(w, "%s%s%s\n", indent, branch, label)
+			walk(e.parent, indent+next)
+		}
+	}
+
+	seeds := make([]string, 0, len(plan.Seeds))
+	for table := range plan.Seeds {
+		seeds = append(seeds, table)
+	}
+	sort.Strings(seeds)
+	for _, table := range seeds {
+		fmt.Fprintf(w, "%s (%d rows, seed limit %s)\n", table, plan.Rows(table), limitLabel(plan.Seeds[table]))
+		shown[table] = true
+		walk(table, "")
+	}
+	printPlanTotals(w, plan)
+}
+
+// writePlanFile writes plan to path for devseeder apply
+func writePlanFile(ctx context.Context, seeder *devseeder.Seeder, plan *devseeder.CopyPlan, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := seeder.WritePlan(ctx, plan, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func printPlanTotals(w io.Writer, plan *devseeder.CopyPlan) {
+	fmt.Fprintf(w, "\n%d rows in %d tables\n", plan.TotalRows(), len(plan.Order))
+}
+
+// limitLabel shows a seed limit, 0 being the whole table
+func limitLabel(limit int) string {
+	if limit == 0 {
+		return "all"
+	}
+	return strconv.Itoa(limit)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/zalando/go-keyring"
+	"gopkg.in/yaml.v3"
+)
+
+// keyringService is the service name passwords are stored under in the OS keychain
+const keyringService = "devseeder"
+
+// profilePath returns where a named profile is stored,
+// e.g. ~/.config/devseeder/profiles/<name>.yaml on Linux
+func profilePath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "devseeder", "profiles", name+".yaml"), nil
+}
+
+// SaveProfile writes cfg as a named profile. DSN passwords are moved into the
+// OS keychain (macOS Keychain, Windows Credential Manager, Secret Service) so
+// the YAML file never contains them.
+func SaveProfile(name string, cfg *Config) error {
+	path, err := profilePath(name)
+	if err != nil {
+		return err
+	}
+
+	saved := *cfg
+	if saved.ProdDSN, saved.ProdPasswordKeychain, err = moveDSNPasswordToKeychain(saved.ProdDSN, name+"/prod"); err != nil {
+		return fmt.Errorf("prod password: %w", err)
+	}
+	if saved.DevDSN, saved.DevPasswordKeychain, err = moveDSNPasswordToKeychain(saved.DevDSN, name+"/dev"); err != nil {
+		return fmt.Errorf("dev password: %w", err)
+	}
+
+	data, err := yaml.Marshal(&saved)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// LoadProfile reads a profile saved with SaveProfile.
+// Keychain passwords are restored by resolveKeychainPasswords.
+func LoadProfile(name string) (*Config, error) {
+	path, err := profilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	return LoadConfig(path)
+}
+
+// moveDSNPasswordToKeychain stores the DSN's password in the keychain under account
+// and returns the DSN without it. DSNs without a password are returned unchanged.
+func moveDSNPasswordToKeychain(dsn, account string) (string, string, error) {
+	dsnCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot parse DSN: %w", err)
+	}
+	if dsnCfg.Passwd == "" {
+		return dsn, "", nil
+	}
+	if err := keyring.Set(keyringService, account, dsnCfg.Passwd); err != nil {
+		return "", "", fmt.Errorf("cannot store password in keychain: %w", err)
+	}
+	dsnCfg.Passwd = ""
+	return dsnCfg.FormatDSN(), account, nil
+}
+
+// resolveKeychainPasswords fills in DSN passwords that were saved to the OS keychain.
+func resolveKeychainPasswords(cfg *Config) error {
+	var err error
+	if cfg.ProdPasswordKeychain != "" {
+		if cfg.ProdDSN, err = dsnWithKeychainPassword(cfg.ProdDSN, cfg.ProdPasswordKeychain); err != nil {
+			return fmt.Errorf("prod password: %w", err)
+		}
+	}
+	if cfg.DevPasswordKeychain != "" {
+		if cfg.DevDSN, err = dsnWithKeychainPassword(cfg.DevDSN, cfg.DevPasswordKeychain); err != nil {
+			return fmt.Errorf("dev password: %w", err)
+		}
+	}
+	return nil
+}
+
+func dsnWithKeychainPassword(dsn, account string) (string, error) {
+	pass, err := keyring.Get(keyringService, account)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %q from keychain: %w", account, err)
+	}
+	dsnCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse DSN: %w", err)
+	}
+	dsnCfg.Passwd = pass
+	return dsnCfg.FormatDSN(), nil
+}
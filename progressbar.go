@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+	"golang.org/x/term"
+)
+
+const progressBarWidth = 30
+
+// progressDisplay draws live progress bars on a terminal: one for the table being
+// copied and one for the whole run, with rows/s and an ETA. Finished tables are
+// printed above them. Log output must go through its Write so lines don't tear
+// the bars apart.
+type progressDisplay struct {
+	out io.Writer
+
+	mu           sync.Mutex
+	drawn        int // bar lines currently on screen
+	ev           devseeder.ProgressEvent
+	started      time.Time
+	tableStarted time.Time
+}
+
+func newProgressDisplay(out io.Writer) *progressDisplay {
+	return &progressDisplay{out: out, started: time.Now()}
+}
+
+// How a sync shows its progress
+const (
+	progressOff  = "off"
+	progressBars = "bars" // live bars above the log (-progress on)
+	progressTUI  = "tui"  // full-screen dashboard (-progress tui)
+)
+
+// progressMode resolves the -progress flag: auto draws bars when stderr is a terminal.
+func progressMode(flag string) (string, error) {
+	switch flag {
+	case "auto":
+		if term.IsTerminal(int(os.Stderr.Fd())) {
+			return progressBars, nil
+		}
+		return progressOff, nil
+	case "on":
+		return progressBars, nil
+	case "tui":
+		return progressTUI, nil
+	case "off":
+		return progressOff, nil
+	}
+	return "", fmt.Errorf("unknown progress mode %q (expected auto, on, tui or off)", flag)
+}
+
+// update is a devseeder.ProgressFunc
+func (d *progressDisplay) update(ev devseeder.ProgressEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clear()
+	switch ev.Stage {
+	case devseeder.StageTableStarted:
+		d.tableStarted = time.Now()
+	case devseeder.StageTableCopied:
+		elapsed := time.Since(d.tableStarted)
+		fmt.Fprintf(d.out, "✓ %-30s %8d rows in %s (%s)\n", ev.Table, ev.Rows, elapsed.Round(100*time.Millisecond), rate(ev.Rows, elapsed))
+	}
+	d.ev = ev
+	d.draw()
+}
+
+// Write prints p above the bars.
+func (d *progressDisplay) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clear()
+	n, err := d.out.Write(p)
+	d.draw()
+	return n, err
+}
+
+func (d *progressDisplay) clear() {
+	if d.drawn > 0 {
+		// Move up over the bars and erase to the end of the screen
+		fmt.Fprintf(d.out, "\033[%dA\033[J", d.drawn)
+		d.drawn = 0
+	}
+}
+
+func (d *progressDisplay) draw() {
+	ev := d.ev
+	if ev.TablesTotal == 0 {
+		return
+	}
+	var lines []string
+	if ev.Table != "" && ev.Stage != devseeder.StageTableCopied {
+		lines = append(lines, progressLine(ev.Table, ev.RowsDone, ev.Rows, time.Since(d.tableStarted)))
+	}
+	lines = append(lines, progressLine(fmt.Sprintf("total (%d/%d tables)", ev.TablesDone, ev.TablesTotal),
+		ev.TotalRowsDone, ev.TotalRows, time.Since(d.started)))
+	for _, l := range lines {
+		fmt.Fprintln(d.out, l)
+	}
+	d.drawn = len(lines)
+}
+
+// progressLine renders "name [#####.....] done/total rows  N rows/s  ETA 5s"
+func progressLine(name string, done, total int, elapsed time.Duration) string {
+	filled := 0
+	if total > 0 {
+		filled = done * progressBarWidth / total
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+
+	eta := "--"
+	if done > 0 && done < total {
+		remaining := time.Duration(float64(elapsed) / float64(done) * float64(total-done))
+		eta = remaining.Round(time.Second).String()
+	} else if done >= total {
+		eta = "0s"
+	}
+	return fmt.Sprintf("%-30s [%s] %d/%d rows  %s  ETA %s", name, bar, done, total, rate(done, elapsed), eta)
+}
+
+func rate(rows int, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "-- rows/s"
+	}
+	return fmt.Sprintf("%.0f rows/s", float64(rows)/elapsed.Seconds())
+}
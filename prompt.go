@@ -2,10 +2,11 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"net"
 	"strconv"
 	"strings"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/manifoldco/promptui"
 )
 
@@ -16,7 +17,7 @@ func promptForValue(label, defaultVal string) string {
 	}
 	result, err := prompt.Run()
 	if err != nil {
-		log.Fatalf("Prompt failed for '%s': %v\n", label, err)
+		fatalf(exitFailure, "Prompt failed for '%s': %v\n", label, err)
 	}
 	return result
 }
@@ -29,7 +30,7 @@ func promptForSecret(label, defaultVal string) string {
 	}
 	result, err := prompt.Run()
 	if err != nil {
-		log.Fatalf("Prompt failed for '%s': %v\n", label, err)
+		fatalf(exitFailure, "Prompt failed for '%s': %v\n", label, err)
 	}
 	return result
 }
@@ -38,7 +39,7 @@ func promptForInt(label, defaultVal string) int {
 	valStr := promptForValue(label, defaultVal)
 	valInt, err := strconv.Atoi(valStr)
 	if err != nil {
-		log.Fatalf("Invalid number for '%s': %v\n", label, err)
+		fatalf(exitFailure, "Invalid number for '%s': %v\n", label, err)
 	}
 	return valInt
 }
@@ -58,26 +59,26 @@ func promptForBool(label string, defaultVal bool) bool {
 
 	index, _, err := prompt.Run()
 	if err != nil {
-		log.Fatalf("Prompt failed for '%s': %v\n", label, err)
+		fatalf(exitFailure, "Prompt failed for '%s': %v\n", label, err)
 	}
 
 	return index == 1
 }
 
 func parseTablesPrompt() map[string]int {
-	tablesInput := promptForValue("Tables (format: table:limit,table:limit)", "events:1000,companies:1000")
+	tablesInput := promptForValue("Tables (format: table:limit,table:limit; limit all copies every row)", "events:1000,companies:1000")
 
 	tables := make(map[string]int)
 	pairs := strings.Split(tablesInput, ",")
 	for _, pair := range pairs {
 		parts := strings.Split(pair, ":")
 		if len(parts) != 2 {
-			log.Fatalf("Invalid table format '%s', expected table:limit", pair)
+			fatalf(exitFailure, "Invalid table format '%s', expected table:limit", pair)
 		}
 		tableName := parts[0]
-		limit, err := strconv.Atoi(parts[1])
+		limit, err := parseTableLimit(parts[1])
 		if err != nil {
-			log.Fatalf("Invalid limit for table '%s': %v", tableName, err)
+			fatalf(exitFailure, "Invalid limit for table '%s': %v", tableName, err)
 		}
 		tables[tableName] = limit
 	}
@@ -85,20 +86,70 @@ func parseTablesPrompt() map[string]int {
 	return tables
 }
 
+func promptForTLS(label string) TLSConfig {
+	options := []string{TLSDisabled, TLSPreferred, TLSRequired}
+	prompt := promptui.Select{
+		Label: label + " TLS Mode",
+		Items: options,
+	}
+	_, mode, err := prompt.Run()
+	if err != nil {
+		fatalf(exitFailure, "Prompt failed for '%s TLS Mode': %v\n", label, err)
+	}
+	if mode == TLSDisabled {
+		return TLSConfig{Mode: mode}
+	}
+
+	return TLSConfig{
+		Mode:       mode,
+		CAFile:     promptForValue(label+" TLS CA File (empty for system roots)", ""),
+		CertFile:   promptForValue(label+" TLS Client Cert File (optional)", ""),
+		KeyFile:    promptForValue(label+" TLS Client Key File (optional)", ""),
+		SkipVerify: promptForBool(label+" TLS Skip Certificate Verification?", false),
+	}
+}
+
+// buildDSN goes through mysql.Config so passwords and names containing
+// characters like '@', '/' or ':' are escaped properly.
 func buildDSN(user, pass, host string, port int, dbName string) string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", user, pass, host, port, dbName)
+	c := mysql.NewConfig()
+	c.User = user
+	c.Passwd = pass
+	c.Net = "tcp"
+	c.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	c.DBName = dbName
+	return c.FormatDSN()
 }
 
 func interactiveConfig() *Config {
 	fmt.Println("Configure Source Database (Prod) Connection:")
 
-	prodUser := promptForValue("Prod DB User", "root")
-	prodPass := promptForSecret("Prod DB Password", "")
-	prodHost := promptForValue("Prod DB Host", "localhost")
-	prodPort := promptForInt("Prod DB Port", "3306")
-	prodDBName := promptForValue("Prod DB Name", "prod_db")
-
-	prodDSN := buildDSN(prodUser, prodPass, prodHost, prodPort, prodDBName)
+	var prodDSN string
+	var prodTLS TLSConfig
+	var prodCloudSQL CloudSQLConfig
+	if promptForBool("Connect Prod via Cloud SQL Connector?", false) {
+		prodCloudSQL.Instance = promptForValue("Prod Instance Connection Name (project:region:instance)", "")
+		prodCloudSQL.IAMAuth = promptForBool("Use IAM Database Authentication?", true)
+		prodCloudSQL.PrivateIP = promptForBool("Use Private IP?", false)
+
+		prodUser := promptForValue("Prod DB User", "root")
+		prodPass := ""
+		if !prodCloudSQL.IAMAuth {
+			prodPass = promptForSecret("Prod DB Password", "")
+		}
+		prodDBName := promptForValue("Prod DB Name", "prod_db")
+
+		prodDSN = buildCloudSQLDSN(prodUser, prodPass, prodCloudSQL.Instance, prodDBName)
+	} else {
+		prodUser := promptForValue("Prod DB User", "root")
+		prodPass := promptForSecret("Prod DB Password", "")
+		prodHost := promptForValue("Prod DB Host", "localhost")
+		prodPort := promptForInt("Prod DB Port", "3306")
+		prodDBName := promptForValue("Prod DB Name", "prod_db")
+
+		prodDSN = buildDSN(prodUser, prodPass, prodHost, prodPort, prodDBName)
+		prodTLS = promptForTLS("Prod DB")
+	}
 
 	fmt.Println("\nConfigure Target Database (Dev) Connection:")
 
@@ -109,18 +160,42 @@ func interactiveConfig() *Config {
 	devDBName := promptForValue("Dev DB Name", "dev_db")
 
 	devDSN := buildDSN(devUser, devPass, devHost, devPort, devDBName)
+	devTLS := promptForTLS("Dev DB")
 
 	fmt.Println("\nTables Configuration:")
 	tables := parseTablesPrompt()
 
 	disableFKChecks := promptForBool("Disable Foreign Key Checks?", false)
 	resetTables := promptForBool("Reset Tables Before Sync?", true)
+	backupTables := false
+	if resetTables {
+		backupTables = promptForBool("Backup Dev Tables Before Truncating?", false)
+	}
+	columnIntersection := promptForBool("Only Insert Columns Present in Both Schemas?", false)
+	copyViews := promptForBool("Recreate Prod Views in Dev?", false)
+	disableTriggers := promptForBool("Disable Dev Triggers During Load?", false)
+	copyTriggers := promptForBool("Copy Triggers Missing From Dev?", false)
+	copyRoutines := promptForBool("Copy Stored Procedures/Functions Missing From Dev?", false)
+	auditDir := promptForValue("Audit Log Directory", ".")
+	undoDir := promptForValue("Undo Script Directory (empty to skip)", ".")
 
 	return &Config{
 		ProdDSN:         prodDSN,
 		DevDSN:          devDSN,
+		ProdTLS:         prodTLS,
+		ProdCloudSQL:    prodCloudSQL,
+		DevTLS:          devTLS,
 		Tables:          tables,
 		DisableFKChecks: disableFKChecks,
 		ResetTables:     resetTables,
+		BackupTables:    backupTables,
+		AuditDir:        auditDir,
+		UndoDir:         undoDir,
+
+		ColumnIntersection: columnIntersection,
+		CopyViews:          copyViews,
+		DisableTriggers:    disableTriggers,
+		CopyTriggers:       copyTriggers,
+		CopyRoutines:       copyRoutines,
 	}
 }
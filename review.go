@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// Choices offered for each planned table by sync -review
+const (
+	reviewApprove    = "Approve"
+	reviewSkip       = "Skip"
+	reviewLimit      = "Change limit"
+	reviewApproveAll = "Approve all remaining"
+	reviewAbort      = "Abort"
+)
+
+// interactiveReview returns a devseeder.PlanReviewFunc that prints the plan and
+// asks about each table in copy order. Tables skipped once stay skipped when a
+// changed limit makes the sync plan again.
+func interactiveReview() devseeder.PlanReviewFunc {
+	skipped := make(map[string]bool)
+	return func(ctx context.Context, plan *devseeder.CopyPlan) (devseeder.PlanReview, error) {
+		var review devseeder.PlanReview
+		printPlanTable(os.Stdout, plan)
+
+		approveAll := false
+		for _, table := range plan.Order {
+			if skipped[table] {
+				fmt.Printf("%s: skipped\n", table)
+				review.Skip = append(review.Skip, table)
+				continue
+			}
+			if approveAll {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return review, err
+			}
+
+			limit, seed := plan.Seeds[table]
+			items := []string{reviewApprove, reviewSkip}
+			if seed {
+				items = append(items, reviewLimit)
+			}
+			items = append(items, reviewApproveAll, reviewAbort)
+			label := fmt.Sprintf("%s: %d rows", table, plan.Rows(table))
+			if seed {
+				label += fmt.Sprintf(" (seed, limit %s)", limitLabel(limit))
+			}
+			_, choice, err := (&promptui.Select{Label: label, Items: items}).Run()
+			if err != nil {
+				return review, fmt.Errorf("review: %w", err)
+			}
+
+			switch choice {
+			case reviewSkip:
+				skipped[table] = true
+				review.Skip = append(review.Skip, table)
+			case reviewLimit:
+				n, err := promptLimit(table, limit)
+				if err != nil {
+					return review, err
+				}
+				if review.Limits == nil {
+					review.Limits = make(map[string]int)
+				}
+				review.Limits[table] = n
+			case reviewApproveAll:
+				approveAll = true
+			case reviewAbort:
+				return review, fmt.Errorf("sync aborted during review")
+			}
+		}
+		if len(review.Limits) > 0 {
+			fmt.Println("Planning again with the new limits...")
+		}
+		return review, nil
+	}
+}
+
+// promptLimit asks for a new row limit of a seed table
+func promptLimit(table string, current int) (int, error) {
+	prompt := promptui.Prompt{
+		Label:   fmt.Sprintf("Row limit of %s", table),
+		Default: strconv.Itoa(current),
+		Validate: func(s string) error {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || n < 0 {
+				return fmt.Errorf("enter a number of rows")
+			}
+			return nil
+		},
+	}
+	s, err := prompt.Run()
+	if err != nil {
+		return 0, fmt.Errorf("review: %w", err)
+	}
+	return strconv.Atoi(strings.TrimSpace(s))
+}
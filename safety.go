@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// productionMarkers are substrings that suggest a database name belongs to production.
+var productionMarkers = []string{"prod", "live"}
+
+// targetWarnings inspects the dev (target) DSN and returns every reason it
+// looks like it might actually be a production database.
+func targetWarnings(prodDSN, devDSN string) ([]string, error) {
+	devCfg, err := mysql.ParseDSN(devDSN)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse dev DSN: %w", err)
+	}
+
+	var warnings []string
+	devName := strings.ToLower(devCfg.DBName)
+	for _, marker := range productionMarkers {
+		if strings.Contains(devName, marker) {
+			warnings = append(warnings, fmt.Sprintf("target database name %q contains %q", devCfg.DBName, marker))
+		}
+	}
+	// Commands such as load run without any prod credentials
+	if prodDSN == "" {
+		return warnings, nil
+	}
+	prodCfg, err := mysql.ParseDSN(prodDSN)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse prod DSN: %w", err)
+	}
+	if devName != "" && devName == strings.ToLower(prodCfg.DBName) {
+		if devCfg.Addr == prodCfg.Addr {
+			warnings = append(warnings, fmt.Sprintf("target is the same database as the source (%s/%s)", devCfg.Addr, devCfg.DBName))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("target database name %q matches the source database name", devCfg.DBName))
+		}
+	}
+	return warnings, nil
+}
+
+// confirmTarget warns loudly when the target looks like production and
+// requires explicit confirmation before anything is written to it.
+func confirmTarget(cfg *Config) {
+	warnings, err := targetWarnings(cfg.ProdDSN, cfg.DevDSN)
+	if err != nil {
+		fatalf(exitFailure, "Error checking target database: %v\n", err)
+	}
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Println("\n!!! WARNING: the TARGET database looks like PRODUCTION !!!")
+	for _, w := range warnings {
+		fmt.Printf("  - %s\n", w)
+	}
+	fmt.Println("DevSeeder writes to (and may truncate) the target. Check that the DSNs were not swapped.")
+	fmt.Println()
+
+	if !promptForBool("Really write to this target database?", false) {
+		fatalf(exitFailure, "Aborted: target database was not confirmed")
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC service is described by hand rather than generated from a .proto file,
+// and its messages travel as JSON. Clients select the codec with the "json" content
+// subtype, e.g. grpc.CallContentSubtype("json") in Go or
+// `grpcurl -H 'content-type: application/grpc+json'`.
+//
+//	service devseeder.Seeder {
+//	  rpc StartSync(StartSyncRequest) returns (StartSyncResponse);
+//	  rpc Progress(ProgressRequest) returns (stream RunEvent);
+//	  rpc Cancel(CancelRequest) returns (CancelResponse);
+//	}
+const seederServiceName = "devseeder.Seeder"
+
+// StartSyncRequest starts a sync with the server's config; Tables, when set,
+// replaces the configured { table : rowLimit } map.
+type StartSyncRequest struct {
+	Tables map[string]int `json:"tables,omitempty"`
+}
+
+type StartSyncResponse struct {
+	RunID string `json:"run_id"`
+}
+
+type ProgressRequest struct {
+	RunID string `json:"run_id"`
+}
+
+// RunEvent is one progress update of a run. The last event of a run has Finished
+// set, with Error holding the failure if there was one.
+type RunEvent struct {
+	devseeder.ProgressEvent
+	Finished bool   `json:"finished,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type CancelRequest struct {
+	RunID string `json:"run_id"`
+}
+
+type CancelResponse struct {
+	Cancelled bool `json:"cancelled"`
+}
+
+// jsonCodec marshals gRPC messages as JSON
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// maxRunEvents is how many progress events a run keeps for clients to replay
+const maxRunEvents = 1000
+
+// syncRun is the state of one sync started over gRPC
+type syncRun struct {
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	events  []RunEvent    // the latest events, after the first dropped
+	dropped int           // events trimmed to keep at most maxRunEvents
+	changed chan struct{} // closed and replaced whenever events grows
+}
+
+func (r *syncRun) publish(ev RunEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == maxRunEvents {
+		// Keep the newer half; a client this far behind skips to it
+		half := maxRunEvents / 2
+		r.events = append([]RunEvent(nil), r.events[half:]...)
+		r.dropped += half
+	}
+	r.events = append(r.events, ev)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// since returns the events after the first n that are still kept, the number of
+// the first of them, and a channel closed when more arrive.
+func (r *syncRun) since(n int) ([]RunEvent, int, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n = max(n, r.dropped)
+	return r.events[n-r.dropped:], n, r.changed
+}
+
+// seederService runs syncs with a fixed config on behalf of gRPC clients
+type seederService struct {
+	cfg    *Config
+	audit  *devseeder.AuditLog
+	ctx    context.Context // cancelled when the server shuts down
+	runTTL time.Duration   // how long finished runs are kept
+
+	mu   sync.Mutex
+	runs map[string]*syncRun
+}
+
+func (s *seederService) StartSync(_ context.Context, req *StartSyncRequest) (*StartSyncResponse, error) {
+	cfg := *s.cfg
+	if len(req.Tables) > 0 {
+		cfg.Tables = req.Tables
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	sr := &syncRun{cancel: cancel, changed: make(chan struct{})}
+	id := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	s.mu.Lock()
+	s.runs[id] = sr
+	s.mu.Unlock()
+
+	log.Printf("Starting sync run %s", id)
+	go func() {
+		defer cancel()
+		err := run(ctx, &cfg, s.audit, devseeder.WithProgress(func(ev devseeder.ProgressEvent) {
+			sr.publish(RunEvent{ProgressEvent: ev})
+		}))
+		final := RunEvent{Finished: true}
+		if err != nil {
+			final.Error = err.Error()
+			log.Printf("Sync run %s failed: %v", id, err)
+		} else {
+			log.Printf("Sync run %s finished", id)
+		}
+		sr.publish(final)
+		time.AfterFunc(s.runTTL, func() {
+			s.mu.Lock()
+			delete(s.runs, id)
+			s.mu.Unlock()
+		})
+	}()
+	return &StartSyncResponse{RunID: id}, nil
+}
+
+func (s *seederService) lookup(id string) (*syncRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sr, ok := s.runs[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no run %q", id)
+	}
+	return sr, nil
+}
+
+// Progress replays the run's events so far, then streams new ones until it finishes.
+func (s *seederService) Progress(req *ProgressRequest, stream grpc.ServerStream) error {
+	sr, err := s.lookup(req.RunID)
+	if err != nil {
+		return err
+	}
+	sent := 0
+	for {
+		events, first, changed := sr.since(sent)
+		sent = first
+		for _, ev := range events {
+			if err := stream.SendMsg(&ev); err != nil {
+				return err
+			}
+			sent++
+			if ev.Finished {
+				return nil
+			}
+		}
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (s *seederService) Cancel(_ context.Context, req *CancelRequest) (*CancelResponse, error) {
+	sr, err := s.lookup(req.RunID)
+	if err != nil {
+		return nil, err
+	}
+	sr.cancel()
+	return &CancelResponse{Cancelled: true}, nil
+}
+
+var seederServiceDesc = grpc.ServiceDesc{
+	ServiceName: seederServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartSync",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(StartSyncRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				// grpc leaves running the interceptor (e.g. the token check) to the handler
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(*seederService).StartSync(ctx, req.(*StartSyncRequest))
+				}
+				if interceptor == nil {
+					return handler(ctx, req)
+				}
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + seederServiceName + "/StartSync"}, handler)
+			},
+		},
+		{
+			MethodName: "Cancel",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(CancelRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				// grpc leaves running the interceptor (e.g. the token check) to the handler
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(*seederService).Cancel(ctx, req.(*CancelRequest))
+				}
+				if interceptor == nil {
+					return handler(ctx, req)
+				}
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + seederServiceName + "/Cancel"}, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Progress",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				req := new(ProgressRequest)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(*seederService).Progress(req, stream)
+			},
+		},
+	},
+}
+
+// ServeConfig secures the gRPC server of devseeder serve, which starts copies
+// with the config's prod credentials on behalf of any client it accepts.
+type ServeConfig struct {
+	// Server certificate and key; TLS is required beyond loopback
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// Require client certificates signed by this CA
+	ClientCAFile string `yaml:"client_ca_file"`
+	// File holding the bearer token clients must send
+	TokenFile string `yaml:"token_file"`
+	// How long finished runs can still be queried (default 60)
+	RunTTLMinutes int `yaml:"run_ttl_minutes"`
+}
+
+// runTTL returns how long finished runs are kept
+func (c ServeConfig) runTTL() time.Duration {
+	ttl := time.Duration(c.RunTTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return ttl
+}
+
+// serverOptions returns the gRPC options enforcing c. Listening on anything but
+// loopback requires TLS and either a token or client certificates.
+func (c ServeConfig) serverOptions(listen string) ([]grpc.ServerOption, error) {
+	if !isLoopback(listen) && (c.CertFile == "" || (c.TokenFile == "" && c.ClientCAFile == "")) {
+		return nil, fmt.Errorf("serving on %s, beyond loopback, needs serve.cert_file and serve.key_file, and serve.token_file or serve.client_ca_file", listen)
+	}
+	var opts []grpc.ServerOption
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("serve: cannot load certificate: %w", err)
+		}
+		tlsCfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		if c.ClientCAFile != "" {
+			pem, err := os.ReadFile(c.ClientCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("serve: cannot read client CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("serve: no certificates found in client CA file %s", c.ClientCAFile)
+			}
+			tlsCfg.ClientCAs = pool
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	} else if c.ClientCAFile != "" {
+		return nil, fmt.Errorf("serve: client_ca_file needs cert_file and key_file")
+	}
+	if c.TokenFile != "" {
+		token, err := readTrimmedFile(c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("serve: reading token: %w", err)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("serve: token file %s is empty", c.TokenFile)
+		}
+		opts = append(opts, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := checkToken(ctx, token); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}), grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := checkToken(ss.Context(), token); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}))
+	}
+	return opts, nil
+}
+
+// checkToken makes sure the call carries "authorization: Bearer <token>"
+func checkToken(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if got, ok := strings.CutPrefix(v, "Bearer "); ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong bearer token")
+}
+
+// isLoopback reports whether listen only accepts connections from this machine
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serveCommand exposes sync over gRPC so orchestration systems can start,
+// monitor and cancel runs. Every run uses the loaded config.
+// Usage: devseeder serve [flags] [-listen 127.0.0.1:9090]
+func serveCommand(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	listen := fs.String("listen", "127.0.0.1:9090", "address to serve gRPC on; other than loopback only with TLS and a token or client certificates (see serve in the config)")
+	fs.Parse(args)
+
+	cfg := flags.load()
+	serverOpts, err := cfg.Serve.serverOptions(*listen)
+	if err != nil {
+		fatalf(exitConfig, "Error: %v\n", err)
+	}
+	confirmTarget(cfg)
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing := startTracing(ctx, cfg)
+	defer shutdownTracing()
+
+	closeSource := openSource(ctx, cfg, audit)
+	defer closeSource()
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fatalf(exitFailure, "Error listening on %s: %v\n", *listen, err)
+	}
+	server := grpc.NewServer(serverOpts...)
+	server.RegisterService(&seederServiceDesc, &seederService{
+		cfg:    cfg,
+		audit:  audit,
+		ctx:    ctx,
+		runTTL: cfg.Serve.runTTL(),
+		runs:   make(map[string]*syncRun),
+	})
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	log.Printf("Serving %s on %s", seederServiceName, lis.Addr())
+	if err := server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		fatalf(exitFailure, "Error serving gRPC: %v\n", err)
+	}
+}
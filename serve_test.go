@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServeRequiresToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := ServeConfig{TokenFile: tokenFile}.serverOptions("127.0.0.1:9090")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&seederServiceDesc, &seederService{
+		cfg:  &Config{},
+		ctx:  context.Background(),
+		runs: make(map[string]*syncRun),
+	})
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype("json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		name, authorization string
+		want                codes.Code
+	}{
+		{"no token", "", codes.Unauthenticated},
+		{"wrong token", "Bearer nope", codes.Unauthenticated},
+		{"token", "Bearer s3cret", codes.NotFound}, // past the check, to the unknown run
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tc.authorization)
+			}
+			for _, method := range []string{"StartSync", "Cancel"} {
+				if method == "StartSync" && tc.want != codes.Unauthenticated {
+					continue // would start a real sync
+				}
+				var req, resp interface{} = &CancelRequest{RunID: "none"}, new(CancelResponse)
+				if method == "StartSync" {
+					req, resp = &StartSyncRequest{}, new(StartSyncResponse)
+				}
+				err := conn.Invoke(ctx, "/"+seederServiceName+"/"+method, req, resp)
+				if got := status.Code(err); got != tc.want {
+					t.Errorf("%s: got %v (%v), want %v", method, got, err, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// snapshotDir returns where the snapshot called name is kept, e.g.
+// ~/.config/devseeder/snapshots/<name> on Linux
+func snapshotDir(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate user config directory: %w", err)
+	}
+	return snapshotPath(filepath.Join(dir, "devseeder", "snapshots"), name)
+}
+
+// snapshotPath returns the directory of the snapshot or dataset called name in root
+func snapshotPath(root, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return filepath.Join(root, name), nil
+}
+
+// snapshotExists reports whether a complete snapshot called name exists
+func snapshotExists(name string) bool {
+	dir, err := snapshotDir(name)
+	if err != nil {
+		return false
+	}
+	_, err = devseeder.ReadSnapshotManifest(dir)
+	return err == nil
+}
+
+// snapshotCommand saves the dev database locally, to be put back later with
+// devseeder restore <name> without copying from prod again.
+// Usage: devseeder snapshot [flags] [-name before-migration] [-force]
+func snapshotCommand(args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	name := fs.String("name", "", "name of the snapshot (default snapshot-<timestamp>)")
+	force := fs.Bool("force", false, "replace an existing snapshot of the same name")
+	fs.Parse(args)
+	if *name == "" {
+		*name = "snapshot-" + time.Now().Format("20060102150405")
+	}
+	dir, err := snapshotDir(*name)
+	if err != nil {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+	if _, err := os.Stat(dir); err == nil && !*force {
+		fatalf(exitFailure, "Snapshot %s already exists; pass -force to replace it\n", *name)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+
+	cfg := flags.load()
+
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		fatalf(exitFailure, "Error opening audit log: %v\n", err)
+	}
+	defer closeAuditLog(audit)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	devDB, err := OpenDevDatabase(cfg)
+	if err != nil {
+		fatalf(exitConnection, "Error opening dev database: %v\n", err)
+	}
+	defer devDB.Close()
+
+	// Start from an empty directory so tables dropped since an earlier snapshot
+	// of the same name do not linger in it
+	if err := os.RemoveAll(dir); err != nil {
+		fatalf(exitFailure, "Error: %v\n", err)
+	}
+	seeder := devseeder.New(nil, devDB, devseeder.WithAuditLog(audit), devseeder.WithLockTimeout(cfg.LockTimeout),
+		devseeder.WithLogger(engineLogger(cfg)))
+	m, err := seeder.Snapshot(ctx, dir, *name)
+	if err != nil {
+		fatalf(exitFailure, "Error taking snapshot: %v\n", err)
+	}
+	rows := 0
+	for _, n := range m.Tables {
+		rows += n
+	}
+	log.Printf("Snapshot %s: %d rows in %d tables saved to %s", *name, rows, len(m.Tables), dir)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// LoadDumpSource loads a mysqldump file into a scratch schema on the dev server so the
+// regular pipeline can read from it as if it were prod: FK metadata is rebuilt by MySQL
+// from the dump's DDL. It returns the DSN of the scratch schema and a func that drops it.
+func LoadDumpSource(ctx context.Context, cfg *Config, audit *devseeder.AuditLog) (string, func(), error) {
+	noop := func() {}
+
+	dsnCfg, err := mysql.ParseDSN(cfg.DevDSN)
+	if err != nil {
+		return "", noop, fmt.Errorf("cannot parse dev DSN: %w", err)
+	}
+	scratch := cfg.SourceScratchDB
+	if scratch == "" {
+		scratch = "devseeder_source"
+	}
+	if scratch == dsnCfg.DBName {
+		return "", noop, fmt.Errorf("source_scratch_db must differ from the dev database")
+	}
+
+	// Connect without a default schema to (re)create the scratch one
+	dsnCfg.DBName = ""
+	server, err := openAuditedDB("source", dsnCfg.FormatDSN(), cfg.DevTLS, cfg.forceUTF8MB4(), audit)
+	if err != nil {
+		return "", noop, err
+	}
+	defer server.Close()
+	if _, err := server.ExecContext(ctx, "DROP DATABASE IF EXISTS "+devseeder.QuoteIdent(scratch)); err != nil {
+		return "", noop, err
+	}
+	if _, err := server.ExecContext(ctx, "CREATE DATABASE "+devseeder.QuoteIdent(scratch)+" CHARACTER SET utf8mb4"); err != nil {
+		return "", noop, fmt.Errorf("cannot create scratch schema %s: %w", scratch, err)
+	}
+	drop := func() {
+		db, err := openAuditedDB("source", dsnCfg.FormatDSN(), cfg.DevTLS, cfg.forceUTF8MB4(), audit)
+		if err != nil {
+			log.Printf("Warning: cannot drop scratch schema %s: %v\n", scratch, err)
+			return
+		}
+		defer db.Close()
+		if _, err := db.ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+devseeder.QuoteIdent(scratch)); err != nil {
+			log.Printf("Warning: cannot drop scratch schema %s: %v\n", scratch, err)
+		}
+	}
+
+	dsnCfg.DBName = scratch
+	scratchDSN := dsnCfg.FormatDSN()
+	db, err := openAuditedDB("source", scratchDSN, cfg.DevTLS, cfg.forceUTF8MB4(), audit)
+	if err != nil {
+		drop()
+		return "", noop, err
+	}
+	defer db.Close()
+	// The dump's SET statements must apply to the session running the inserts
+	db.SetMaxOpenConns(1)
+
+	f, err := os.Open(cfg.SourceDump)
+	if err != nil {
+		drop()
+		return "", noop, err
+	}
+	defer f.Close()
+
+	log.Printf("Loading %s into scratch schema %s", cfg.SourceDump, scratch)
+	count := 0
+	err = devseeder.SplitSQLStatements(f, func(stmt string) error {
+		upper := strings.ToUpper(stmt)
+		// The dump may switch databases; everything must land in the scratch schema
+		if strings.HasPrefix(upper, "USE ") || strings.HasPrefix(upper, "CREATE DATABASE") {
+			return nil
+		}
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("statement %d failed: %w", count+1, err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		drop()
+		return "", noop, fmt.Errorf("loading %s: %w", cfg.SourceDump, err)
+	}
+	log.Printf("Loaded %d statements from %s", count, cfg.SourceDump)
+	return scratchDSN, drop, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// TLS modes accepted in TLSConfig.Mode
+const (
+	TLSDisabled  = "disabled"  // plain TCP (default)
+	TLSPreferred = "preferred" // use TLS if the server supports it, plain TCP otherwise
+	TLSRequired  = "required"  // always use TLS, fail if the server does not support it
+)
+
+// TLSConfig holds the TLS options for one database connection.
+type TLSConfig struct {
+	Mode       string `yaml:"mode"`
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	SkipVerify bool   `yaml:"skip_verify"`
+}
+
+// applyTLS registers the TLS settings with the mysql driver under name and
+// returns the DSN rewritten to use them.
+func applyTLS(dsn, name string, t TLSConfig) (string, error) {
+	tlsCfg, err := t.tlsConfig()
+	if err != nil || tlsCfg == nil {
+		return dsn, err
+	}
+
+	dsnCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse DSN: %w", err)
+	}
+	if err := mysql.RegisterTLSConfig(name, tlsCfg); err != nil {
+		return "", fmt.Errorf("cannot register TLS config: %w", err)
+	}
+	dsnCfg.TLSConfig = name
+	dsnCfg.AllowFallbackToPlaintext = t.Mode == TLSPreferred
+	return dsnCfg.FormatDSN(), nil
+}
+
+// tlsConfig builds the crypto/tls settings, or returns nil when TLS is disabled.
+func (t TLSConfig) tlsConfig() (*tls.Config, error) {
+	switch t.Mode {
+	case "", TLSDisabled:
+		return nil, nil
+	case TLSPreferred, TLSRequired:
+	default:
+		return nil, fmt.Errorf("unknown TLS mode %q (expected %s, %s or %s)", t.Mode, TLSDisabled, TLSPreferred, TLSRequired)
+	}
+
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: t.SkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
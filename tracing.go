@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Trace exporters accepted in TracingConfig.Exporter
+const (
+	TracingOTLP   = "otlp"   // OTLP over gRPC, e.g. to an OpenTelemetry Collector, Jaeger or Tempo
+	TracingStdout = "stdout" // pretty-printed spans on stderr, for debugging
+)
+
+// TracingConfig enables OpenTelemetry tracing of runs.
+type TracingConfig struct {
+	// otlp or stdout; tracing is off when empty
+	Exporter string `yaml:"exporter"`
+	// host:port of the OTLP receiver (default: OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317)
+	Endpoint string `yaml:"endpoint"`
+	// Talk plain gRPC to the OTLP receiver instead of TLS
+	Insecure bool `yaml:"insecure"`
+	// Extra OTLP request headers, e.g. an API key
+	Headers map[string]string `yaml:"headers"`
+	// service.name of the exported spans (default devseeder)
+	ServiceName string `yaml:"service_name"`
+}
+
+// setupTracing installs a global TracerProvider exporting to t and returns a func
+// flushing and stopping it. Both are no-ops when tracing is off.
+func setupTracing(ctx context.Context, t TracingConfig) (func(), error) {
+	var exporter sdktrace.SpanExporter
+	switch t.Exporter {
+	case "":
+		return func() {}, nil
+	case TracingOTLP:
+		opts := []otlptracegrpc.Option{}
+		if t.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(t.Endpoint))
+		}
+		if t.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(t.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(t.Headers))
+		}
+		exp, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+		}
+		exporter = exp
+	case TracingStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("creating stdout exporter: %w", err)
+		}
+		exporter = exp
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q (expected %s or %s)", t.Exporter, TracingOTLP, TracingStdout)
+	}
+
+	serviceName := t.ServiceName
+	if serviceName == "" {
+		serviceName = "devseeder"
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("building trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return func() {
+		// Flush even when the run was cancelled, but don't hang on an unreachable collector
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			log.Printf("Warning: flushing traces: %v\n", err)
+		}
+	}, nil
+}
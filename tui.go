@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// How many finished tables and log lines the dashboard keeps on screen
+const (
+	dashboardDoneLines = 8
+	dashboardLogLines  = 6
+)
+
+// Messages sent into the dashboard from the sync goroutine
+type (
+	runDoneMsg struct{ err error }
+	logLineMsg string
+	tickMsg    time.Time
+)
+
+type finishedTable struct {
+	table   string
+	rows    int
+	elapsed time.Duration
+}
+
+// dashboard is the bubbletea model of -progress tui: the table queue, the table
+// in flight with its progress, overall throughput, and recent warnings.
+type dashboard struct {
+	cancel context.CancelFunc
+
+	started      time.Time
+	tableStarted time.Time
+	ev           devseeder.ProgressEvent
+	queue        []string
+	done         []finishedTable
+	logs         []string
+	warnings     []string
+	cancelling   bool
+	err          error
+	width        int
+}
+
+func (d *dashboard) Init() tea.Cmd {
+	return tick()
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (d *dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			if !d.cancelling {
+				d.cancelling = true
+				d.cancel()
+			}
+		}
+	case tea.WindowSizeMsg:
+		d.width = msg.Width
+	case tickMsg:
+		return d, tick()
+	case logLineMsg:
+		line := string(msg)
+		if strings.HasPrefix(line, "Warning: ") {
+			d.warnings = append(d.warnings, line)
+		}
+		d.logs = append(d.logs, line)
+		if len(d.logs) > dashboardLogLines {
+			d.logs = d.logs[len(d.logs)-dashboardLogLines:]
+		}
+	case devseeder.ProgressEvent:
+		d.progress(msg)
+	case runDoneMsg:
+		d.err = msg.err
+		return d, tea.Quit
+	}
+	return d, nil
+}
+
+func (d *dashboard) progress(ev devseeder.ProgressEvent) {
+	switch ev.Stage {
+	case devseeder.StagePlanned:
+		d.queue = ev.Queue
+	case devseeder.StageTableStarted:
+		d.tableStarted = time.Now()
+		if len(d.queue) > 0 && d.queue[0] == ev.Table {
+			d.queue = d.queue[1:]
+		}
+	case devseeder.StageTableCopied:
+		d.done = append(d.done, finishedTable{ev.Table, ev.Rows, time.Since(d.tableStarted)})
+	}
+	d.ev = ev
+}
+
+func (d *dashboard) View() string {
+	var b strings.Builder
+	ev := d.ev
+	status := "copying"
+	if d.cancelling {
+		status = "cancelling after the current table..."
+	}
+	fmt.Fprintf(&b, "DevSeeder sync: %s  (elapsed %s)\n\n", status, time.Since(d.started).Round(time.Second))
+
+	if ev.TablesTotal > 0 {
+		fmt.Fprintln(&b, progressLine(fmt.Sprintf("total (%d/%d tables)", ev.TablesDone, ev.TablesTotal),
+			ev.TotalRowsDone, ev.TotalRows, time.Since(d.started)))
+	} else {
+		fmt.Fprintln(&b, "planning...")
+	}
+	if ev.Table != "" && ev.Stage != devseeder.StageTableCopied {
+		fmt.Fprintln(&b, progressLine(ev.Table, ev.RowsDone, ev.Rows, time.Since(d.tableStarted)))
+	}
+
+	fmt.Fprintf(&b, "\nQueue (%d): %s\n", len(d.queue), d.truncate(strings.Join(d.queue, ", ")))
+
+	fmt.Fprintf(&b, "\nDone (%d):\n", len(d.done))
+	from := max(0, len(d.done)-dashboardDoneLines)
+	for _, t := range d.done[from:] {
+		fmt.Fprintf(&b, "  ✓ %-30s %8d rows  %8s  %s\n", t.table, t.rows, t.elapsed.Round(100*time.Millisecond), rate(t.rows, t.elapsed))
+	}
+
+	fmt.Fprintf(&b, "\nLog (%d warnings):\n", len(d.warnings))
+	for _, l := range d.logs {
+		fmt.Fprintf(&b, "  %s\n", d.truncate(l))
+	}
+	fmt.Fprintln(&b, "\nq: cancel")
+	return b.String()
+}
+
+// truncate cuts s to the terminal width
+func (d *dashboard) truncate(s string) string {
+	if d.width > 4 && len(s) > d.width-2 {
+		return s[:d.width-5] + "..."
+	}
+	return s
+}
+
+// dashboardLog forwards log lines into the dashboard
+type dashboardLog struct {
+	p *tea.Program
+}
+
+func (w dashboardLog) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.p.Send(logLineMsg(line))
+	}
+	return len(p), nil
+}
+
+// runWithDashboard runs the sync behind a full-screen dashboard on stderr. Log
+// lines are shown in it and the warnings among them printed again on exit, as
+// the screen is cleared. Pressing q cancels the run like Ctrl-C would.
+func runWithDashboard(ctx context.Context, cfg *Config, audit *devseeder.AuditLog, extra ...devseeder.Option) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	model := &dashboard{cancel: cancel, started: time.Now()}
+	p := tea.NewProgram(model, tea.WithOutput(os.Stderr), tea.WithAltScreen())
+
+	log.SetOutput(dashboardLog{p})
+	go func() {
+		err := run(ctx, cfg, audit, append(extra, devseeder.WithProgress(func(ev devseeder.ProgressEvent) { p.Send(ev) }))...)
+		p.Send(runDoneMsg{err})
+	}()
+	_, perr := p.Run()
+	log.SetOutput(os.Stderr)
+
+	for _, w := range model.warnings {
+		log.Print(w)
+	}
+	ev := model.ev
+	log.Printf("Copied %d rows into %d of %d tables in %s", ev.TotalRowsDone, ev.TablesDone, ev.TablesTotal,
+		time.Since(model.started).Round(time.Second))
+	if model.err != nil {
+		return model.err
+	}
+	if perr != nil {
+		return fmt.Errorf("dashboard: %w", perr)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/auth/credentials"
+)
+
+// UploadConfig describes where dump artifacts are uploaded after a dump run.
+type UploadConfig struct {
+	// Destination, e.g. "s3://team-seeds/nightly/" or "gs://team-seeds/nightly/".
+	// A URI ending in "/" is a prefix: the artifact's file name is appended.
+	URI string    `yaml:"uri"`
+	S3  S3Config  `yaml:"s3"`
+	GCS GCSConfig `yaml:"gcs"`
+}
+
+// S3Config holds S3 credentials; empty fields fall back to the usual AWS_* variables.
+type S3Config struct {
+	Region          string `yaml:"region"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+	// Custom endpoint for S3-compatible stores (MinIO, R2, ...), path-style addressing
+	Endpoint string `yaml:"endpoint"`
+}
+
+// GCSConfig holds GCS credentials.
+type GCSConfig struct {
+	// Service account key file; Application Default Credentials are used when empty
+	CredentialsFile string `yaml:"credentials_file"`
+}
+
+// UploadArtifact uploads the file or directory at localPath to the configured URI.
+// Directories (ndjson and fixtures exports) are uploaded file by file under the URI.
+func UploadArtifact(ctx context.Context, u UploadConfig, localPath string) error {
+	dest, err := url.Parse(u.URI)
+	if err != nil {
+		return fmt.Errorf("invalid upload URI: %w", err)
+	}
+	if dest.Host == "" {
+		return fmt.Errorf("upload URI %q has no bucket", u.URI)
+	}
+
+	var put func(ctx context.Context, bucket, key, file string) error
+	switch dest.Scheme {
+	case "s3":
+		put = u.S3.put
+	case "gs":
+		put = u.GCS.put
+	default:
+		return fmt.Errorf("unsupported upload URI scheme %q (expected s3:// or gs://)", dest.Scheme)
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimPrefix(dest.Path, "/")
+
+	if !info.IsDir() {
+		key := prefix
+		if key == "" || strings.HasSuffix(key, "/") {
+			key += filepath.Base(localPath)
+		}
+		log.Printf("Uploading %s to %s://%s/%s", localPath, dest.Scheme, dest.Host, key)
+		return put(ctx, dest.Host, key, localPath)
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	entries, err := os.ReadDir(localPath)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key := prefix + e.Name()
+		log.Printf("Uploading %s to %s://%s/%s", e.Name(), dest.Scheme, dest.Host, key)
+		if err := put(ctx, dest.Host, key, filepath.Join(localPath, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put uploads one file with a single SigV4-signed PUT (objects up to 5 GB).
+func (c S3Config) put(ctx context.Context, bucket, key, file string) error {
+	region := firstNonEmpty(c.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	accessKey := firstNonEmpty(c.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID"))
+	secretKey := firstNonEmpty(c.SecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	sessionToken := firstNonEmpty(c.SessionToken, os.Getenv("AWS_SESSION_TOKEN"))
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("no S3 credentials: set upload.s3 access keys or AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY")
+	}
+
+	var target *url.URL
+	if c.Endpoint != "" {
+		base, err := url.Parse(strings.TrimSuffix(c.Endpoint, "/"))
+		if err != nil {
+			return fmt.Errorf("invalid S3 endpoint: %w", err)
+		}
+		target = base.JoinPath(bucket, key)
+	} else {
+		target = &url.URL{Scheme: "https", Host: bucket + ".s3." + region + ".amazonaws.com", Path: "/" + key}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	signS3Request(req, region, accessKey, secretKey, time.Now().UTC())
+
+	return doUpload(req)
+}
+
+// signS3Request adds an AWS Signature Version 4 Authorization header to req.
+// The payload is left unsigned so large files are streamed without hashing them first.
+func signS3Request(req *http.Request, region, accessKey, secretKey string, now time.Time) {
+	const payloadHash = "UNSIGNED-PAYLOAD"
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	var names []string
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, strings.TrimSpace(req.Header.Get(name)))
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// put uploads one file through the GCS JSON API's simple media upload.
+func (c GCSConfig) put(ctx context.Context, bucket, key, file string) error {
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+		Scopes:          []string{"https://www.googleapis.com/auth/devstorage.read_write"},
+		CredentialsFile: c.CredentialsFile,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot load GCS credentials: %w", err)
+	}
+	token, err := creds.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get GCS access token: %w", err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	target := "https://storage.googleapis.com/upload/storage/v1/b/" + url.PathEscape(bucket) +
+		"/o?uploadType=media&name=" + url.QueryEscape(path.Clean(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+token.Value)
+
+	return doUpload(req)
+}
+
+// doUpload sends an upload request and turns a non-2xx response into an error.
+func doUpload(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("upload to %s failed: %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
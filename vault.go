@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// VaultConfig describes how to reach and authenticate against HashiCorp Vault.
+type VaultConfig struct {
+	// Vault address, defaults to $VAULT_ADDR
+	Address string `yaml:"address"`
+	// Vault Enterprise namespace, defaults to $VAULT_NAMESPACE
+	Namespace string `yaml:"namespace"`
+	// "token" (default) or "approle"
+	AuthMethod string `yaml:"auth_method"`
+	// File containing the token for token auth; $VAULT_TOKEN is used when empty
+	TokenFile string `yaml:"token_file"`
+	// AppRole credentials; the secret ID is read from a file so it never sits in config
+	RoleID       string `yaml:"role_id"`
+	SecretIDFile string `yaml:"secret_id_file"`
+	// Mount path of the AppRole auth method, defaults to "approle"
+	AppRoleMount string `yaml:"approle_mount"`
+}
+
+// VaultSecretRef points at a single value stored in Vault.
+// Path is the full API path, e.g. "secret/data/devseeder/prod" for KV v2.
+type VaultSecretRef struct {
+	Path string `yaml:"path"`
+	Key  string `yaml:"key"`
+}
+
+// vaultClient is a minimal client for Vault's HTTP API
+type vaultClient struct {
+	addr      string
+	namespace string
+	token     string
+	http      *http.Client
+}
+
+// newVaultClient authenticates against Vault and returns a client holding a token.
+func newVaultClient(c VaultConfig) (*vaultClient, error) {
+	vc := &vaultClient{
+		addr:      strings.TrimRight(firstNonEmpty(c.Address, os.Getenv("VAULT_ADDR")), "/"),
+		namespace: firstNonEmpty(c.Namespace, os.Getenv("VAULT_NAMESPACE")),
+		http:      &http.Client{Timeout: 30 * time.Second},
+	}
+	if vc.addr == "" {
+		return nil, fmt.Errorf("no Vault address configured (set vault.address or VAULT_ADDR)")
+	}
+
+	switch c.AuthMethod {
+	case "", "token":
+		if c.TokenFile != "" {
+			token, err := readTrimmedFile(c.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read Vault token file: %w", err)
+			}
+			vc.token = token
+		} else {
+			vc.token = os.Getenv("VAULT_TOKEN")
+		}
+		if vc.token == "" {
+			return nil, fmt.Errorf("no Vault token available (set vault.token_file or VAULT_TOKEN)")
+		}
+	case "approle":
+		secretID, err := readTrimmedFile(c.SecretIDFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read AppRole secret ID file: %w", err)
+		}
+		mount := firstNonEmpty(c.AppRoleMount, "approle")
+		var resp struct {
+			Auth struct {
+				ClientToken string `json:"client_token"`
+			} `json:"auth"`
+		}
+		body := map[string]string{"role_id": c.RoleID, "secret_id": secretID}
+		if err := vc.do(http.MethodPost, "auth/"+mount+"/login", body, &resp); err != nil {
+			return nil, fmt.Errorf("AppRole login failed: %w", err)
+		}
+		vc.token = resp.Auth.ClientToken
+	default:
+		return nil, fmt.Errorf("unknown Vault auth method %q (expected token or approle)", c.AuthMethod)
+	}
+	return vc, nil
+}
+
+// do sends one request to the Vault API and decodes the JSON response into out
+func (vc *vaultClient) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, vc.addr+"/v1/"+strings.TrimLeft(path, "/"), &body)
+	if err != nil {
+		return err
+	}
+	if vc.token != "" {
+		req.Header.Set("X-Vault-Token", vc.token)
+	}
+	if vc.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vc.namespace)
+	}
+
+	resp, err := vc.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// readSecret fetches one value from Vault. Both KV v1 and KV v2 responses are understood.
+func (vc *vaultClient) readSecret(ref VaultSecretRef) (string, error) {
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := vc.do(http.MethodGet, ref.Path, nil, &resp); err != nil {
+		return "", err
+	}
+
+	data := resp.Data
+	// KV v2 nests the secret one level deeper: {"data": {"data": {...}, "metadata": {...}}}
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = inner
+		}
+	}
+
+	key := firstNonEmpty(ref.Key, "password")
+	val, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("key %q not found in Vault secret %s", key, ref.Path)
+	}
+	return val, nil
+}
+
+// resolveVaultPasswords replaces the DSN passwords with values read from Vault,
+// for every connection that has a Vault reference configured.
+func resolveVaultPasswords(cfg *Config) error {
+	if cfg.ProdPasswordVault.Path == "" && cfg.DevPasswordVault.Path == "" {
+		return nil
+	}
+
+	vc, err := newVaultClient(cfg.Vault)
+	if err != nil {
+		return err
+	}
+
+	if cfg.ProdPasswordVault.Path != "" {
+		if cfg.ProdDSN, err = dsnWithVaultPassword(vc, cfg.ProdDSN, cfg.ProdPasswordVault); err != nil {
+			return fmt.Errorf("prod password: %w", err)
+		}
+	}
+	if cfg.DevPasswordVault.Path != "" {
+		if cfg.DevDSN, err = dsnWithVaultPassword(vc, cfg.DevDSN, cfg.DevPasswordVault); err != nil {
+			return fmt.Errorf("dev password: %w", err)
+		}
+	}
+	return nil
+}
+
+func dsnWithVaultPassword(vc *vaultClient, dsn string, ref VaultSecretRef) (string, error) {
+	pass, err := vc.readSecret(ref)
+	if err != nil {
+		return "", err
+	}
+	dsnCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse DSN: %w", err)
+	}
+	dsnCfg.Passwd = pass
+	return dsnCfg.FormatDSN(), nil
+}
+
+func readTrimmedFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
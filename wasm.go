@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// WASMPolicyConfig loads a masking or transform policy compiled to WebAssembly
+// (see devseeder.WASMABIVersion for the interface a module implements).
+type WASMPolicyConfig struct {
+	Module string   `yaml:"module"`
+	Tables []string `yaml:"tables"` // default: every table
+}
+
+// loadWASMPolicies instantiates the configured policy modules and returns the
+// option applying them, and a func releasing them.
+func loadWASMPolicies(ctx context.Context, cfg *Config) (devseeder.Option, func(), error) {
+	var transforms []devseeder.RowTransform
+	release := func() {
+		for _, t := range transforms {
+			t.(*devseeder.WASMTransform).Close(ctx)
+		}
+	}
+	for _, p := range cfg.WASMPolicies {
+		if p.Module == "" {
+			release()
+			return nil, nil, fmt.Errorf("wasm_policies: every policy needs a module")
+		}
+		t, err := devseeder.LoadWASMTransform(ctx, p.Module, p.Tables)
+		if err != nil {
+			release()
+			return nil, nil, fmt.Errorf("loading WASM policy: %w", err)
+		}
+		log.Printf("Loaded WASM policy %s", p.Module)
+		transforms = append(transforms, t)
+	}
+	return devseeder.WithRowTransforms(transforms...), release, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milanarif/devseeder/pkg/devseeder"
+)
+
+// WebhookConfig describes where run results are posted.
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+	// Extra request headers, e.g. an Authorization token
+	Headers map[string]string `yaml:"headers"`
+	// Seconds to wait for the endpoint (default 10)
+	Timeout int `yaml:"timeout"`
+}
+
+// RunReport is the JSON payload posted to the webhook when a run finishes.
+type RunReport struct {
+	Status          string         `json:"status"` // "success", "failed" or "cancelled"
+	Tables          map[string]int `json:"tables"` // rows copied per table
+	StartedAt       time.Time      `json:"started_at"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Error           string         `json:"error,omitempty"`
+
+	TableSeconds map[string]float64 `json:"table_seconds,omitempty"` // time spent copying each table
+	Profile      string             `json:"profile,omitempty"`       // saved profile the run used, if any
+	Warnings     []string           `json:"warnings,omitempty"`      // warnings the engine logged
+	PartialTable string             `json:"partial_table,omitempty"` // table left half-written by a failed copy
+	NotCopied    []string           `json:"not_copied,omitempty"`    // planned tables a failed copy never reached
+	ExitCode     int                `json:"exit_code"`               // status the process exits with, 0 on success
+}
+
+// runRecorder collects per-table counts from progress events for the run report.
+type runRecorder struct {
+	started  time.Time
+	mu       sync.Mutex
+	tables   map[string]int
+	seconds  map[string]float64
+	warnings []string
+
+	tableStarted time.Time
+}
+
+func newRunRecorder() *runRecorder {
+	return &runRecorder{started: time.Now(), tables: make(map[string]int), seconds: make(map[string]float64)}
+}
+
+func (r *runRecorder) progress(ev devseeder.ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch ev.Stage {
+	case devseeder.StageTableStarted:
+		r.tableStarted = time.Now()
+	case devseeder.StageTableCopied:
+		r.tables[ev.Table] = ev.Rows
+		r.seconds[ev.Table] = time.Since(r.tableStarted).Seconds()
+	}
+}
+
+// logger returns a Logger passing messages on to next and keeping its warnings
+// for the report
+func (r *runRecorder) logger(next devseeder.Logger) devseeder.Logger {
+	l := warningRecorder{next, r}
+	if attrs, ok := next.(attrLogger); ok {
+		return attrWarningRecorder{l, attrs}
+	}
+	return l
+}
+
+// attrLogger is a Logger taking structured attributes, like devseeder.SlogLogger
+type attrLogger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+type warningRecorder struct {
+	devseeder.Logger
+	rec *runRecorder
+}
+
+func (l warningRecorder) Printf(format string, v ...interface{}) {
+	if msg, ok := strings.CutPrefix(fmt.Sprintf(format, v...), "Warning: "); ok {
+		l.rec.mu.Lock()
+		l.rec.warnings = append(l.rec.warnings, strings.TrimSpace(msg))
+		l.rec.mu.Unlock()
+	}
+	l.Logger.Printf(format, v...)
+}
+
+type attrWarningRecorder struct {
+	warningRecorder
+	attrs attrLogger
+}
+
+func (l attrWarningRecorder) Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l.attrs.Log(ctx, level, msg, args...)
+}
+
+// writeResult writes report as an indented JSON document to path, or to stdout for "-"
+func writeResult(path string, report RunReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// report builds the payload for a run that ended with err (nil on success).
+func (r *runRecorder) report(ctx context.Context, err error) RunReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep := RunReport{
+		Status:          "success",
+		Tables:          r.tables,
+		StartedAt:       r.started,
+		DurationSeconds: time.Since(r.started).Seconds(),
+		TableSeconds:    r.seconds,
+		Warnings:        r.warnings,
+		ExitCode:        runExitCode(ctx, err),
+	}
+	if err != nil {
+		rep.Status = "failed"
+		if ctx.Err() != nil {
+			rep.Status = "cancelled"
+		}
+		rep.Error = err.Error()
+		var incomplete *devseeder.IncompleteCopyError
+		if errors.As(err, &incomplete) {
+			rep.PartialTable, rep.NotCopied = incomplete.Partial, incomplete.NotCopied
+		}
+	}
+	return rep
+}
+
+// postWebhook sends report to the configured URL; non-2xx responses are errors.
+func postWebhook(w WebhookConfig, report RunReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	timeout := time.Duration(w.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	// The run may have been cancelled; the notification should still go out
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}